@@ -18,18 +18,35 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/kubernetes-sigs/minibroker/pkg/admin"
+	"github.com/kubernetes-sigs/minibroker/pkg/audit"
+	"github.com/kubernetes-sigs/minibroker/pkg/auth"
 	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/cli"
+	"github.com/kubernetes-sigs/minibroker/pkg/health"
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
 	"github.com/kubernetes-sigs/minibroker/pkg/kubernetes"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	"github.com/kubernetes-sigs/minibroker/pkg/operator"
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
 	"github.com/pmorie/osb-broker-lib/pkg/metrics"
 	prom "github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/dynamic"
+	k8s "k8s.io/client-go/kubernetes"
 	klog "k8s.io/klog/v2"
 
 	"github.com/pmorie/osb-broker-lib/pkg/rest"
@@ -47,9 +64,46 @@ var options struct {
 	Port    int
 	TLSCert string
 	TLSKey  string
+
+	TLSCertFile       string
+	TLSKeyFile        string
+	TLSClientCAFile   string
+	TLSClientPolicy   string
+	TLSReloadInterval time.Duration
+
+	GenericSecretKeys string
+
+	Operator        bool
+	OperatorWorkers int
+
+	AdminTokenFile string
+
+	AuthCredentialsFile string
+	AuthReloadInterval  time.Duration
+
+	AuditStdout         bool
+	AuditFile           string
+	AuditFileMaxSize    int
+	AuditFileMaxBackups int
+	AuditWebhook        string
+	SensitiveKeys       string
+
+	TracingEndpoint    string
+	TracingInsecure    bool
+	TracingSampleRatio float64
+
+	LogFormat string
 }
 
 func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		if err := cli.Run(os.Args[1:], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "minibroker %s: %v\n", os.Args[1], err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	flag.BoolVar(&options.ServiceCatalogEnabledOnly, "service-catalog-enabled-only", false,
 		"Only list Service Catalog Enabled services")
 	flag.IntVar(&options.Port, "port", 8005,
@@ -58,16 +112,95 @@ func main() {
 		"base-64 encoded PEM block to use as the certificate for TLS. If '--tlsCert' is used, then '--tlsKey' must also be used. If '--tlsCert' is not used, then TLS will not be used.")
 	flag.StringVar(&options.TLSKey, "tlsKey", "",
 		"base-64 encoded PEM block to use as the private key matching the TLS certificate. If '--tlsKey' is used, then '--tlsCert' must also be used")
+	flag.StringVar(&options.TLSCertFile, "tlsCertFile", "",
+		"The PEM file with the TLS certificate, reloaded when it changes. If '--tlsCertFile' is used, then '--tlsKeyFile' must also be used")
+	flag.StringVar(&options.TLSKeyFile, "tlsKeyFile", "",
+		"The PEM file with the private key matching the '--tlsCertFile' certificate, reloaded when it changes")
+	flag.StringVar(&options.TLSClientCAFile, "tlsClientCA", "",
+		"The PEM file with the CA bundle the client certificates are verified against - if set, the OSB API requires a verified client certificate")
+	flag.StringVar(&options.TLSClientPolicy, "tlsClientPolicy", "",
+		"The YAML file mapping the client certificate subjects to the platforms they are allowed to act for")
+	flag.DurationVar(&options.TLSReloadInterval, "tlsReloadInterval", auth.DefaultReloadInterval,
+		"How often the TLS certificate files are checked for changes")
 	flag.StringVar(&options.CatalogPath, "catalogPath", "",
 		"The path to the catalog")
 	flag.StringVar(&options.HelmRepoURL, "helmUrl", "",
 		"The url to the helm repo")
+	flag.DurationVar(&options.ChartIndexRefreshInterval, "chartIndexRefreshInterval", 0,
+		"How often the chart repository index is reloaded - 0 disables the reload and the index freshness readiness check")
 	flag.StringVar(&options.DefaultNamespace, "defaultNamespace", "",
 		"The default namespace for brokers when the request doesn't specify")
 	flag.StringVar(&options.ProvisioningSettingsPath, "provisioningSettings", "",
 		"The path to the YAML file where the optional provisioning settings are stored")
 	flag.StringVar(&options.ClusterDomain, "clusterDomain", "",
 		"The k8s cluster domain - if not set, Minibroker infers from /etc/resolv.conf")
+	flag.BoolVar(&options.PerBindingUsers, "perBindingUsers", false,
+		"Create a dedicated database user for each binding of the services supporting it")
+	flag.DurationVar(&options.CredentialRotationGracePeriod, "credentialRotationGracePeriod", minibroker.DefaultCredentialRotationGracePeriod,
+		"How long the binding credentials replaced by a rotation remain valid")
+	flag.DurationVar(&options.CredentialRotationMaxAge, "credentialRotationMaxAge", 0,
+		"The age after which binding credentials are rotated automatically - 0 disables the scheduled rotation")
+	flag.BoolVar(&options.LiveCredentials, "liveCredentials", false,
+		"Resolve the binding credentials from the current services and secrets instead of the snapshot stored at bind time")
+	flag.BoolVar(&options.SecretKeysPassthrough, "secretKeysPassthrough", false,
+		"Include all the chart secret keys in the binding credentials instead of only the keys allowed by the service providers")
+	flag.StringVar(&options.GenericSecretKeys, "genericSecretKeys", "",
+		"A comma-separated list of the chart secret keys included in the binding credentials of charts without a service provider")
+	flag.BoolVar(&options.ProjectBindingSecrets, "bindingSecrets", false,
+		"Project the binding credentials into Secrets following the Service Binding for Kubernetes specification")
+	flag.BoolVar(&options.Operator, "operator", false,
+		"Provision and bind through the ManagedService and ServiceClaim custom resources, besides the OSB API")
+	flag.IntVar(&options.OperatorWorkers, "operatorWorkers", 2,
+		"The number of custom resources reconciled concurrently in the operator mode")
+	flag.StringVar(&options.AdminTokenFile, "adminTokenFile", "",
+		"The file holding the bearer tokens accepted by the admin API, one per line - the admin API is disabled if not set")
+	flag.StringVar(&options.AuthCredentialsFile, "authCredentials", "",
+		"The YAML file holding the basic auth credentials and bearer tokens accepted by the OSB API - the OSB API is not authenticated if not set")
+	flag.DurationVar(&options.AuthReloadInterval, "authReloadInterval", auth.DefaultReloadInterval,
+		"How often the '--authCredentials' file is checked for changes")
+	flag.BoolVar(&options.AuditStdout, "auditStdout", false,
+		"Write the audit events of the OSB API requests to stdout as JSON lines")
+	flag.StringVar(&options.AuditFile, "auditFile", "",
+		"The file the audit events of the OSB API requests are appended to as JSON lines")
+	flag.IntVar(&options.AuditFileMaxSize, "auditFileMaxSize", 100,
+		"The size in megabytes after which the '--auditFile' file is rotated")
+	flag.IntVar(&options.AuditFileMaxBackups, "auditFileMaxBackups", 5,
+		"The number of rotated '--auditFile' files kept")
+	flag.StringVar(&options.AuditWebhook, "auditWebhook", "",
+		"The URL the audit events of the OSB API requests are posted to")
+	flag.StringVar(&options.SensitiveKeys, "sensitiveKeys", audit.DefaultSensitiveKeys,
+		"The regular expression of the parameter names whose values are redacted in the audit events and the fetched instances")
+	flag.StringVar(&options.TracingEndpoint, "tracingEndpoint", "",
+		"The host:port of the OTLP/HTTP collector the trace spans are exported to - the spans are not exported if not set")
+	flag.BoolVar(&options.TracingInsecure, "tracingInsecure", false,
+		"Export the trace spans over plain HTTP instead of HTTPS")
+	flag.Float64Var(&options.TracingSampleRatio, "tracingSampleRatio", 1,
+		"The fraction of the traces started by Minibroker which are sampled")
+	flag.StringVar(&options.LogFormat, "logFormat", string(log.FormatText),
+		"The format of the logs: 'text' or 'json'")
+	flag.StringVar(&options.KubeConfig, "kubeconfig", "",
+		"The kubeconfig file used to reach the cluster - if neither '--kubeconfig' nor '--kube-context' are set, the in-cluster configuration is used")
+	flag.StringVar(&options.KubeContext, "kube-context", "",
+		"The kubeconfig context used to reach the cluster - if not set, the current context is used")
+	flag.StringVar(&options.ConfigNamespace, "configNamespace", os.Getenv("CONFIG_NAMESPACE"),
+		"The namespace where Minibroker stores the instance state - defaults to the CONFIG_NAMESPACE environment variable")
+	flag.StringVar(&options.ClustersConfigPath, "clustersConfig", "",
+		"The path to the YAML file describing the target clusters releases can be installed into")
+	flag.BoolVar(&options.NamespacePerInstance, "namespacePerInstance", false,
+		"Install each instance into a generated namespace with a default-deny NetworkPolicy, deleted on deprovision")
+	flag.StringVar(&options.IsolationConfigPath, "isolationConfig", "",
+		"The path to the YAML file with the ResourceQuota and LimitRange templates of the generated instance namespaces")
+	flag.StringVar(&options.NamespacePolicyPath, "namespacePolicy", "",
+		"The path to the YAML file with the namespace allow and deny lists and the instance quotas enforced on provisioning")
+	flag.StringVar(&options.NamespaceTemplate, "namespaceTemplate", "",
+		"A Go template mapping the OSB platform context to the namespace instances are provisioned into, e.g. 'cf-{{ .SpaceGUID }}'")
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "Usage: minibroker [flags]\n       minibroker COMMAND [flags]\n\nBroker flags:\n")
+		flag.PrintDefaults()
+		fmt.Fprintln(out)
+		cli.Usage(out)
+	}
 	flag.Parse()
 
 	klogFlags := flag.NewFlagSet("klog", flag.ExitOnError)
@@ -81,21 +214,21 @@ func main() {
 		}
 	})
 	defer klog.Flush()
+	if err := log.SetFormat(log.Format(options.LogFormat), os.Stderr); err != nil {
+		klog.Fatalln(err)
+	}
 
 	if options.ClusterDomain == "" {
-		resolvConf, err := os.Open("/etc/resolv.conf")
+		clusterDomain, err := inferClusterDomain()
 		if err != nil {
-			klog.Fatalln(err)
-		}
-		// An assurance for the future-proof copy-paste of this block! Yes, this
-		// is not necessary here but shall be if other events happen in the
-		// future.
-		defer resolvConf.Close()
-
-		if options.ClusterDomain, err = kubernetes.ClusterDomain(resolvConf); err != nil {
-			klog.Fatalln(err)
+			if options.KubeConfig == "" && options.KubeContext == "" {
+				klog.Fatalln(err)
+			}
+			// Outside the cluster, the local resolv.conf does not carry the cluster search domains.
+			klog.Warningf("failed to infer the cluster domain, using %q: %v", defaultClusterDomain, err)
+			clusterDomain = defaultClusterDomain
 		}
-		resolvConf.Close()
+		options.ClusterDomain = clusterDomain
 	}
 
 	if err := run(); err != nil && err != context.Canceled && err != context.DeadlineExceeded {
@@ -103,6 +236,21 @@ func main() {
 	}
 }
 
+// defaultClusterDomain is the cluster domain assumed when running outside the cluster and it
+// cannot be inferred.
+const defaultClusterDomain = "cluster.local"
+
+// inferClusterDomain infers the k8s cluster domain from the /etc/resolv.conf.
+func inferClusterDomain() (string, error) {
+	resolvConf, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+	defer resolvConf.Close()
+
+	return kubernetes.ClusterDomain(resolvConf)
+}
+
 func run() error {
 	ctx, cancelFunc := context.WithCancel(context.Background())
 	defer cancelFunc()
@@ -121,20 +269,64 @@ func runWithContext(ctx context.Context) error {
 		err := fmt.Errorf("failed to start Minibroker: to use TLS, both --tlsCert and --tlsKey must be used")
 		return err
 	}
+	if (options.TLSCertFile != "" || options.TLSKeyFile != "") &&
+		(options.TLSCertFile == "" || options.TLSKeyFile == "") {
+		return fmt.Errorf("failed to start Minibroker: to use TLS, both --tlsCertFile and --tlsKeyFile must be used")
+	}
+	if options.TLSCertFile != "" && options.TLSCert != "" {
+		return fmt.Errorf("failed to start Minibroker: --tlsCertFile and --tlsCert are mutually exclusive")
+	}
+	if (options.TLSClientCAFile != "" || options.TLSClientPolicy != "") && options.TLSCertFile == "" {
+		return fmt.Errorf("failed to start Minibroker: --tlsClientCA and --tlsClientPolicy require --tlsCertFile")
+	}
+	if options.TLSClientPolicy != "" && options.TLSClientCAFile == "" {
+		return fmt.Errorf("failed to start Minibroker: --tlsClientPolicy requires --tlsClientCA")
+	}
 
 	addr := ":" + strconv.Itoa(options.Port)
 
-	options.Options.ConfigNamespace = os.Getenv("CONFIG_NAMESPACE")
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Endpoint:    options.TracingEndpoint,
+		Insecure:    options.TracingInsecure,
+		SampleRatio: options.TracingSampleRatio,
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			klog.Warningf("failed to flush the trace spans: %v", err)
+		}
+	}()
+
+	if options.GenericSecretKeys != "" {
+		options.Options.GenericSecretKeys = strings.Split(options.GenericSecretKeys, ",")
+	}
 
-	b, err := broker.NewBrokerFromOptions(options.Options)
+	b, err := broker.NewBrokerFromOptions(ctx, options.Options)
 	if err != nil {
 		return err
 	}
 
+	if options.Operator {
+		if err := startOperator(ctx, b.PolicyClient()); err != nil {
+			return err
+		}
+	}
+
 	// Prometheus metrics
 	reg := prom.NewRegistry()
 	osbMetrics := metrics.New()
 	reg.MustRegister(osbMetrics)
+	reg.MustRegister(minibroker.Collectors()...)
+	reg.MustRegister(helm.Collectors()...)
+	reg.MustRegister(auth.Collectors()...)
+	reg.MustRegister(audit.Collectors()...)
+	if inventory, ok := b.Client().(inventoryReporter); ok {
+		reg.MustRegister(inventory.InventoryCollector())
+	}
 
 	api, err := rest.NewAPISurface(b, osbMetrics)
 	if err != nil {
@@ -143,29 +335,218 @@ func runWithContext(ctx context.Context) error {
 
 	s := server.New(api, reg)
 
+	// The broker library does not serve the instance fetch. The fetched parameters are redacted like
+	// those of the audit events.
+	redactor, err := audit.NewRedactor(options.SensitiveKeys)
+	if err != nil {
+		return err
+	}
+	s.Router.Path(broker.InstancePath).Methods(http.MethodGet).Handler(broker.NewInstanceHandler(b, redactor.Redact))
+
+	// The tracing wraps everything else, so the audit and authentication are part of the request
+	// span.
+	s.Router.Use(tracing.Middleware)
+
+	// Every request gets a correlation ID, returned in the X-Correlation-ID header and carried by
+	// the logs and the audit events of the request.
+	s.Router.Use(log.CorrelationMiddleware)
+
+	// The audit wraps the authentication, so the rejected requests are audited too.
+	auditLogger, err := newAuditLogger(redactor)
+	if err != nil {
+		return err
+	}
+	if auditLogger != nil {
+		defer auditLogger.Close()
+		s.Router.Use(auditLogger.Middleware)
+	}
+
+	if options.AuthCredentialsFile != "" {
+		authenticator, err := auth.NewAuthenticator(options.AuthCredentialsFile)
+		if err != nil {
+			return err
+		}
+		go authenticator.Watch(ctx, options.AuthReloadInterval)
+		s.Router.Use(authenticator.Middleware)
+	} else {
+		klog.Warningf("the OSB API is served without authentication, use --authCredentials to enable it")
+	}
+
+	if options.AdminTokenFile != "" {
+		tokens, err := admin.LoadTokens(options.AdminTokenFile)
+		if err != nil {
+			return err
+		}
+		adminClient, ok := b.Client().(admin.Client)
+		if !ok {
+			return fmt.Errorf("failed to start the admin API: the broker client does not support it")
+		}
+		s.Router.PathPrefix(admin.PathPrefix).Handler(admin.NewHandler(adminClient, tokens))
+	}
+
+	// The liveness endpoint /healthz is served by the broker library, while /readyz checks the
+	// dependencies.
+	readiness, ok := b.Client().(readinessChecker)
+	if !ok {
+		return fmt.Errorf("failed to start Minibroker: the broker client does not support readiness checks")
+	}
+	maxIndexAge := time.Duration(chartIndexStaleIntervals) * options.ChartIndexRefreshInterval
+	s.Router.Path(readyzPath).Handler(health.NewChecker(0, readiness.ReadinessChecks(maxIndexAge)...))
+
+	var reloader *auth.CertificateReloader
+	if options.TLSCertFile != "" {
+		reloader, err = auth.NewCertificateReloader(options.TLSCertFile, options.TLSKeyFile, options.TLSClientCAFile)
+		if err != nil {
+			return err
+		}
+		go reloader.Watch(ctx, options.TLSReloadInterval)
+	}
+	if options.TLSClientCAFile != "" {
+		policy := &auth.ClientCertificatePolicy{}
+		if options.TLSClientPolicy != "" {
+			data, err := ioutil.ReadFile(options.TLSClientPolicy)
+			if err != nil {
+				return fmt.Errorf("failed to load the client certificate policy: %w", err)
+			}
+			if policy, err = auth.LoadClientCertificatePolicy(data); err != nil {
+				return err
+			}
+		}
+		if instances, ok := b.Client().(auth.InstancePlatformGetter); ok {
+			policy.SetInstances(instances)
+		}
+		s.Router.Use(policy.Middleware)
+	}
+
+	// The catalog declares the instance fetch; the middleware is the innermost so it only rewrites
+	// the catalog of the broker library.
+	s.Router.Use(broker.CatalogMiddleware)
+
 	klog.V(1).Infof("starting broker!")
 
-	if options.TLSCert == "" && options.TLSKey == "" {
+	switch {
+	case reloader != nil:
+		err = runTLS(ctx, addr, s.Router, reloader.TLSConfig())
+	case options.TLSCert == "" && options.TLSKey == "":
 		err = s.Run(ctx, addr)
-	} else {
+	default:
 		err = s.RunTLS(ctx, addr, options.TLSCert, options.TLSKey)
 	}
+	// The servers are closed on the cancellation of the context; the deferred calls then flush the
+	// audit events.
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// tracingShutdownTimeout bounds the flush of the pending trace spans on exit.
+const tracingShutdownTimeout = 5 * time.Second
+
+const (
+	readyzPath = "/readyz"
+	// chartIndexStaleIntervals is the number of missed refresh intervals after which the chart index
+	// is reported as stale.
+	chartIndexStaleIntervals = 3
+)
+
+// inventoryReporter is implemented by the broker clients reporting the instance and binding
+// counts as metrics.
+type inventoryReporter interface {
+	InventoryCollector() prom.Collector
+}
+
+// readinessChecker is implemented by the broker clients reporting the state of their dependencies.
+type readinessChecker interface {
+	ReadinessChecks(maxIndexAge time.Duration) []health.Check
+}
+
+// auditWebhookQueueSize is the number of audit events queued for the webhook.
+const auditWebhookQueueSize = 1000
+
+// newAuditLogger creates the audit logger with the sinks enabled by the flags, or nil when none is.
+func newAuditLogger(redactor *audit.Redactor) (*audit.Logger, error) {
+	sinks := make([]audit.Sink, 0)
+	if options.AuditStdout {
+		sinks = append(sinks, audit.NewWriterSink(os.Stdout))
+	}
+	if options.AuditFile != "" {
+		sink, err := audit.NewFileSink(options.AuditFile, int64(options.AuditFileMaxSize)<<20, options.AuditFileMaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if options.AuditWebhook != "" {
+		sinks = append(sinks, audit.NewWebhookSink(options.AuditWebhook, auditWebhookQueueSize))
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	return audit.NewLogger(redactor, sinks...), nil
+}
+
+// runTLS serves the handler with the given TLS configuration until the context is done.
+func runTLS(ctx context.Context, addr string, handler http.Handler, config *tls.Config) error {
+	srv := &http.Server{
+		Addr:      addr,
+		Handler:   handler,
+		TLSConfig: config,
+	}
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		<-ctx.Done()
+		c, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		if srv.Shutdown(c) != nil {
+			srv.Close()
+		}
+	}()
+	err := srv.ListenAndServeTLS("", "")
+	if err == http.ErrServerClosed {
+		// Wait for the requests in flight before returning.
+		<-shutdown
+	}
 	return err
 }
 
+// startOperator starts the controller reconciling the Minibroker custom resources in the
+// background.
+func startOperator(ctx context.Context, client operator.Client) error {
+	config, err := kubernetes.RESTConfig(options.KubeConfig, options.KubeContext)
+	if err != nil {
+		return fmt.Errorf("failed to start the operator: %w", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to start the operator: %w", err)
+	}
+	coreClient, err := k8s.NewForConfig(config)
+	if err != nil {
+		return fmt.Errorf("failed to start the operator: %w", err)
+	}
+
+	controller := operator.NewController(client, dynamicClient, coreClient)
+	go func() {
+		if err := controller.Run(ctx, options.OperatorWorkers); err != nil && err != context.Canceled {
+			klog.Errorf("operator: %v", err)
+		}
+	}()
+	return nil
+}
+
+// cancelOnInterrupt cancels the context on SIGINT or SIGTERM, letting run shut down and return.
 func cancelOnInterrupt(ctx context.Context, f context.CancelFunc) {
 	term := make(chan os.Signal, 1)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(term)
 
-	for {
-		select {
-		case <-term:
-			klog.V(1).Infof("received SIGTERM, exiting gracefully...")
-			f()
-			os.Exit(0)
-		case <-ctx.Done():
-			os.Exit(0)
-		}
+	select {
+	case <-term:
+		klog.V(1).Infof("received SIGTERM, exiting gracefully...")
+		f()
+	case <-ctx.Done():
 	}
 }
 
@@ -0,0 +1,202 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// PathPrefix is the path prefix of the administration API.
+const PathPrefix = "/admin/v1/"
+
+// Client is the state access of the Minibroker client used by the administration API.
+type Client interface {
+	ListInstances(ctx context.Context) ([]minibroker.InstanceSummary, error)
+	GetInstance(ctx context.Context, instanceID string) (*minibroker.InstanceSummary, error)
+	OperationHistory(ctx context.Context, instanceID string) ([]minibroker.OperationRecord, error)
+	FailOperation(ctx context.Context, instanceID, bindingID, reason string) error
+	ForceDeleteInstance(ctx context.Context, instanceID string) error
+	RotateInstanceCredentials(ctx context.Context, instanceID string) ([]string, error)
+	RotateBindingCredentials(ctx context.Context, instanceID, bindingID string) error
+}
+
+// Handler serves the administration API, authenticating the requests with bearer tokens.
+type Handler struct {
+	client Client
+	tokens []string
+}
+
+var _ http.Handler = &Handler{}
+
+// NewHandler creates a new Handler accepting any of the given tokens.
+func NewHandler(client Client, tokens []string) *Handler {
+	return &Handler{client: client, tokens: tokens}
+}
+
+// LoadTokens reads the tokens from a file, one per line. Empty lines and lines starting with # are
+// ignored.
+func LoadTokens(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the admin tokens: %w", err)
+	}
+	defer f.Close()
+
+	tokens := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		token := strings.TrimSpace(scanner.Text())
+		if token == "" || strings.HasPrefix(token, "#") {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load the admin tokens: %w", err)
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("failed to load the admin tokens: no token in %s", path)
+	}
+	return tokens, nil
+}
+
+// ServeHTTP routes the administration API requests:
+//
+//	GET    /admin/v1/instances
+//	GET    /admin/v1/instances/{instance_id}
+//	DELETE /admin/v1/instances/{instance_id}
+//	GET    /admin/v1/instances/{instance_id}/operations
+//	POST   /admin/v1/instances/{instance_id}/operations/fail
+//	POST   /admin/v1/instances/{instance_id}/rotate
+//	POST   /admin/v1/instances/{instance_id}/bindings/{binding_id}/rotate
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authenticated(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="minibroker-admin"`)
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	logger := log.FromContext(r.Context()).WithName("admin").WithValues("method", r.Method, "path", r.URL.Path)
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, PathPrefix), "/"), "/")
+	if parts[0] != "instances" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		instances, err := h.client.ListInstances(r.Context())
+		writeResponse(logger, w, map[string]interface{}{"instances": instances}, err)
+	case len(parts) == 2 && r.Method == http.MethodGet:
+		instance, err := h.client.GetInstance(r.Context(), parts[1])
+		writeResponse(logger, w, instance, err)
+	case len(parts) == 2 && r.Method == http.MethodDelete:
+		logger.V(2).Info("force-deleting instance", "instanceID", parts[1])
+		err := h.client.ForceDeleteInstance(r.Context(), parts[1])
+		writeResponse(logger, w, map[string]interface{}{}, err)
+	case len(parts) == 3 && parts[2] == "operations" && r.Method == http.MethodGet:
+		history, err := h.client.OperationHistory(r.Context(), parts[1])
+		writeResponse(logger, w, map[string]interface{}{"operations": history}, err)
+	case len(parts) == 4 && parts[2] == "operations" && parts[3] == "fail" && r.Method == http.MethodPost:
+		var request struct {
+			BindingID string `json:"bindingID"`
+			Reason    string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+			return
+		}
+		logger.V(2).Info("failing the operation", "instanceID", parts[1], "bindingID", request.BindingID, "reason", request.Reason)
+		err := h.client.FailOperation(r.Context(), parts[1], request.BindingID, request.Reason)
+		writeResponse(logger, w, map[string]interface{}{}, err)
+	case len(parts) == 3 && parts[2] == "rotate" && r.Method == http.MethodPost:
+		logger.V(2).Info("rotating the instance credentials", "instanceID", parts[1])
+		rotated, err := h.client.RotateInstanceCredentials(r.Context(), parts[1])
+		writeResponse(logger, w, map[string]interface{}{"bindings": rotated}, err)
+	case len(parts) == 5 && parts[2] == "bindings" && parts[4] == "rotate" && r.Method == http.MethodPost:
+		logger.V(2).Info("rotating the binding credentials", "instanceID", parts[1], "bindingID", parts[3])
+		err := h.client.RotateBindingCredentials(r.Context(), parts[1], parts[3])
+		writeResponse(logger, w, map[string]interface{}{"bindings": []string{parts[3]}}, err)
+	case len(parts) <= 4, len(parts) == 5 && parts[2] == "bindings" && parts[4] == "rotate":
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	}
+}
+
+// authenticated returns whether the request has a valid bearer token.
+func (h *Handler) authenticated(r *http.Request) bool {
+	const prefix = "Bearer "
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, prefix) {
+		return false
+	}
+	token := []byte(strings.TrimPrefix(authorization, prefix))
+	for _, valid := range h.tokens {
+		if subtle.ConstantTimeCompare(token, []byte(valid)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeResponse writes the response as JSON, or the error with its matching status code.
+func writeResponse(logger logr.Logger, w http.ResponseWriter, response interface{}, err error) {
+	switch {
+	case err == nil:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			logger.V(2).Info("failed to write the response", "error", err)
+		}
+	case apierrors.IsNotFound(err):
+		writeError(w, http.StatusNotFound, fmt.Errorf("instance not found"))
+	case isStatusCode(err, http.StatusNotFound):
+		writeError(w, http.StatusNotFound, fmt.Errorf("not found"))
+	case err == minibroker.ErrNoOperationInProgress, err == minibroker.ErrReleaseExists, err == minibroker.ErrRotationUnsupported:
+		writeError(w, http.StatusConflict, err)
+	default:
+		logger.V(2).Info("request failed", "error", err)
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+// isStatusCode returns whether the error is an OSB error with the given status code.
+func isStatusCode(err error, statusCode int) bool {
+	statusErr, ok := err.(osb.HTTPStatusCodeError)
+	return ok && statusErr.StatusCode == statusCode
+}
+
+func writeError(w http.ResponseWriter, statusCode int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/admin"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeClient struct {
+	instances map[string]minibroker.InstanceSummary
+	failed    map[string]string
+	rotated   []string
+}
+
+func (f *fakeClient) ListInstances(_ context.Context) ([]minibroker.InstanceSummary, error) {
+	instances := make([]minibroker.InstanceSummary, 0)
+	for _, instance := range f.instances {
+		instances = append(instances, instance)
+	}
+	return instances, nil
+}
+
+func (f *fakeClient) GetInstance(_ context.Context, instanceID string) (*minibroker.InstanceSummary, error) {
+	instance, ok := f.instances[instanceID]
+	if !ok {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, instanceID)
+	}
+	return &instance, nil
+}
+
+func (f *fakeClient) OperationHistory(_ context.Context, instanceID string) ([]minibroker.OperationRecord, error) {
+	return []minibroker.OperationRecord{{Operation: "provision-1", State: "succeeded"}}, nil
+}
+
+func (f *fakeClient) FailOperation(_ context.Context, instanceID, bindingID, reason string) error {
+	if bindingID != "" {
+		f.failed[instanceID+"/"+bindingID] = reason
+		return nil
+	}
+	if f.instances[instanceID].State != "in progress" {
+		return minibroker.ErrNoOperationInProgress
+	}
+	f.failed[instanceID] = reason
+	return nil
+}
+
+func (f *fakeClient) ForceDeleteInstance(_ context.Context, instanceID string) error {
+	return minibroker.ErrReleaseExists
+}
+
+func (f *fakeClient) RotateInstanceCredentials(_ context.Context, instanceID string) ([]string, error) {
+	if _, ok := f.instances[instanceID]; !ok {
+		return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	f.rotated = append(f.rotated, "b1", "b2")
+	return []string{"b1", "b2"}, nil
+}
+
+func (f *fakeClient) RotateBindingCredentials(_ context.Context, instanceID, bindingID string) error {
+	if bindingID == "shared" {
+		return minibroker.ErrRotationUnsupported
+	}
+	if _, ok := f.instances[instanceID]; !ok {
+		return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	f.rotated = append(f.rotated, bindingID)
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	client := &fakeClient{
+		instances: map[string]minibroker.InstanceSummary{
+			"stuck": {ID: "stuck", ServiceID: "redis", State: "in progress"},
+		},
+		failed: map[string]string{},
+	}
+	server := httptest.NewServer(admin.NewHandler(client, []string{"secret"}))
+	defer server.Close()
+
+	tests := []struct {
+		method     string
+		path       string
+		token      string
+		body       string
+		statusCode int
+		contains   string
+	}{
+		{http.MethodGet, "/admin/v1/instances", "", "", http.StatusUnauthorized, "unauthorized"},
+		{http.MethodGet, "/admin/v1/instances", "wrong", "", http.StatusUnauthorized, "unauthorized"},
+		{http.MethodGet, "/admin/v1/instances", "secret", "", http.StatusOK, `"serviceID":"redis"`},
+		{http.MethodGet, "/admin/v1/instances/stuck", "secret", "", http.StatusOK, `"state":"in progress"`},
+		{http.MethodGet, "/admin/v1/instances/missing", "secret", "", http.StatusNotFound, "not found"},
+		{http.MethodGet, "/admin/v1/instances/stuck/operations", "secret", "", http.StatusOK, `"operation":"provision-1"`},
+		{http.MethodPost, "/admin/v1/instances/stuck/operations/fail", "secret", `{"reason":"helm hung"}`, http.StatusOK, "{}"},
+		{http.MethodPost, "/admin/v1/instances/stuck/operations/fail", "secret", `{"bindingID":"b1","reason":"job hung"}`, http.StatusOK, "{}"},
+		{http.MethodDelete, "/admin/v1/instances/stuck", "secret", "", http.StatusConflict, "release of the instance still exists"},
+		{http.MethodPut, "/admin/v1/instances", "secret", "", http.StatusMethodNotAllowed, "not allowed"},
+		{http.MethodPost, "/admin/v1/instances/stuck/rotate", "secret", "", http.StatusOK, `{"bindings":["b1","b2"]}`},
+		{http.MethodPost, "/admin/v1/instances/missing/rotate", "secret", "", http.StatusNotFound, "not found"},
+		{http.MethodPost, "/admin/v1/instances/stuck/bindings/b3/rotate", "secret", "", http.StatusOK, `{"bindings":["b3"]}`},
+		{http.MethodPost, "/admin/v1/instances/stuck/bindings/shared/rotate", "secret", "", http.StatusConflict, "per-binding users"},
+		{http.MethodPost, "/admin/v1/instances/missing/bindings/b3/rotate", "secret", "", http.StatusNotFound, "not found"},
+		{http.MethodGet, "/admin/v1/instances/stuck/bindings/b3/rotate", "secret", "", http.StatusMethodNotAllowed, "not allowed"},
+		{http.MethodGet, "/admin/v1/releases", "secret", "", http.StatusNotFound, "not found"},
+	}
+
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: unexpected error %v", tt.method, tt.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.statusCode || !strings.Contains(string(body), tt.contains) {
+			t.Errorf("%s %s: expected %d containing %q, actual %d %q", tt.method, tt.path, tt.statusCode, tt.contains, resp.StatusCode, body)
+		}
+	}
+
+	if !reflect.DeepEqual(client.rotated, []string{"b1", "b2", "b3"}) {
+		t.Errorf("expected the rotated bindings [b1 b2 b3], actual %v", client.rotated)
+	}
+	if client.failed["stuck"] != "helm hung" || client.failed["stuck/b1"] != "job hung" {
+		t.Errorf("expected the operations to be failed with the reasons, actual %v", client.failed)
+	}
+}
+
+func TestLoadTokens(t *testing.T) {
+	f, err := ioutil.TempFile("", "admin-tokens")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("# support\nfirst\n\n  second  \n"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	f.Close()
+
+	tokens, err := admin.LoadTokens(f.Name())
+	if err != nil {
+		t.Fatalf("LoadTokens: unexpected error %v", err)
+	}
+	if len(tokens) != 2 || tokens[0] != "first" || tokens[1] != "second" {
+		t.Errorf("LoadTokens: expected [first second], actual %v", tokens)
+	}
+}
+
+func TestHandlerLogs(t *testing.T) {
+	var out bytes.Buffer
+	client := &fakeClient{instances: map[string]minibroker.InstanceSummary{}, failed: map[string]string{}}
+	handler := admin.NewHandler(client, []string{"secret"})
+	withLogger := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.CorrelationMiddleware(handler).ServeHTTP(w, r.WithContext(log.IntoContext(r.Context(), log.NewJSON(&out))))
+	})
+
+	req := httptest.NewRequest(http.MethodDelete, "/admin/v1/instances/db", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set(log.CorrelationIDHeader, "admin-request")
+	withLogger.ServeHTTP(httptest.NewRecorder(), req)
+
+	logs := out.String()
+	for _, expected := range []string{`"msg":"force-deleting instance"`, `"instanceID":"db"`, `"correlationID":"admin-request"`} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("expected the logs to contain %s, actual %s", expected, logs)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/auth"
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+)
+
+// PathPrefix is the path prefix of the OSB API routes audited.
+const PathPrefix = "/v2/"
+
+// Redacted replaces the values of the sensitive parameters in the events.
+const Redacted = "[REDACTED]"
+
+// The outcomes of the audited requests.
+const (
+	OutcomeSuccess  = "success"
+	OutcomeAccepted = "accepted"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+)
+
+// Event is the audit record of an OSB API request.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	// The correlation ID of the request, also found in the broker logs.
+	CorrelationID string               `json:"correlationID,omitempty"`
+	InstanceID    string               `json:"instanceID,omitempty"`
+	BindingID     string               `json:"bindingID,omitempty"`
+	ServiceID     string               `json:"serviceID,omitempty"`
+	PlanID        string               `json:"planID,omitempty"`
+	Platform      string               `json:"platform,omitempty"`
+	Namespace     string               `json:"namespace,omitempty"`
+	Identity      *minibroker.Identity `json:"identity,omitempty"`
+	Outcome       string               `json:"outcome"`
+	StatusCode    int                  `json:"statusCode"`
+	// The duration of the request in milliseconds.
+	Duration float64 `json:"durationMs"`
+	// The class of the error for the failed requests: the OSB error code when the response has one,
+	// or a class derived from the status code.
+	ErrorClass string                 `json:"errorClass,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Sink writes the audit events somewhere.
+type Sink interface {
+	Write(event *Event) error
+	Close() error
+}
+
+// DefaultSensitiveKeys is the default pattern of the parameter names whose values are redacted.
+const DefaultSensitiveKeys = `(?i)pass|secret|token|key|credential|cert|auth|private`
+
+// Redactor replaces the values of the parameters whose names match its pattern.
+type Redactor struct {
+	sensitiveKeys *regexp.Regexp
+}
+
+var defaultRedactor = &Redactor{sensitiveKeys: regexp.MustCompile(DefaultSensitiveKeys)}
+
+// NewRedactor creates a new Redactor from the pattern of the sensitive parameter names.
+func NewRedactor(sensitiveKeys string) (*Redactor, error) {
+	if sensitiveKeys == "" {
+		return nil, fmt.Errorf("invalid sensitive key pattern: must not be empty")
+	}
+	pattern, err := regexp.Compile(sensitiveKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sensitive key pattern %q: %w", sensitiveKeys, err)
+	}
+	return &Redactor{sensitiveKeys: pattern}, nil
+}
+
+// Logger audits the OSB API requests to its sinks.
+type Logger struct {
+	sinks    []Sink
+	redactor *Redactor
+	now      func() time.Time
+}
+
+// NewLogger creates a new Logger writing to the given sinks and redacting the parameters with the
+// given Redactor, or with DefaultSensitiveKeys when nil.
+func NewLogger(redactor *Redactor, sinks ...Sink) *Logger {
+	if redactor == nil {
+		redactor = defaultRedactor
+	}
+	return &Logger{sinks: sinks, redactor: redactor, now: time.Now}
+}
+
+// Close closes the sinks.
+func (l *Logger) Close() error {
+	var errs []string
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close the audit sinks: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Middleware wraps a handler, auditing the OSB API requests. It should wrap the authentication so
+// the rejected requests are audited too.
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, PathPrefix) || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := l.now()
+		event, err := newEvent(w, r, l.redactor)
+		event.Time = start.UTC()
+		recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		if err != nil {
+			recorder.Header().Set("Content-Type", "application/json")
+			recorder.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(recorder).Encode(map[string]string{"description": err.Error()})
+		} else {
+			next.ServeHTTP(recorder, r)
+		}
+
+		event.Duration = float64(l.now().Sub(start)) / float64(time.Millisecond)
+		event.StatusCode = recorder.statusCode
+		event.Outcome, event.ErrorClass = outcome(recorder.statusCode, recorder.body.Bytes())
+		l.write(r.Context(), event)
+	})
+}
+
+func (l *Logger) write(ctx context.Context, event *Event) {
+	for _, sink := range l.sinks {
+		if err := sink.Write(event); err != nil {
+			log.FromContext(ctx).WithName("audit").Error(err, "failed to write the event", "method", event.Method, "path", event.Path)
+		}
+	}
+}
+
+// newEvent builds the event of a request from its path, query, headers and body. The body is left
+// readable by the next handler. An error is returned, along with the event, when the body cannot be
+// read or exceeds auth.MaxBodySize.
+func newEvent(w http.ResponseWriter, r *http.Request, redactor *Redactor) (*Event, error) {
+	event := &Event{Method: r.Method, Path: r.URL.Path, CorrelationID: log.CorrelationID(r.Context())}
+	event.Operation, event.InstanceID, event.BindingID = operation(r.Method, r.URL.Path)
+	event.ServiceID = r.URL.Query().Get("service_id")
+	event.PlanID = r.URL.Query().Get("plan_id")
+	event.Identity = identity(r.Header.Get(osb.OriginatingIdentityHeader))
+
+	if r.Body == nil || (r.Method != http.MethodPut && r.Method != http.MethodPatch) {
+		return event, nil
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, auth.MaxBodySize))
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return event, fmt.Errorf("failed to read the request body: %w", err)
+	}
+	var request struct {
+		ServiceID  string                 `json:"service_id"`
+		PlanID     string                 `json:"plan_id"`
+		Context    map[string]interface{} `json:"context"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	// Malformed bodies are left to the next handler to reject.
+	if err := json.Unmarshal(body, &request); err != nil {
+		return event, nil
+	}
+	if request.ServiceID != "" {
+		event.ServiceID = request.ServiceID
+	}
+	if request.PlanID != "" {
+		event.PlanID = request.PlanID
+	}
+	if context, err := broker.ResolveContext(request.Context); err == nil {
+		event.Platform = context.Platform
+		event.Namespace = context.Namespace
+	}
+	event.Parameters = redactor.Redact(request.Parameters)
+	return event, nil
+}
+
+// operation returns the operation and the instance and binding IDs of an OSB API request.
+func operation(method, path string) (string, string, string) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, PathPrefix), "/"), "/")
+	if len(parts) == 1 && parts[0] == "catalog" {
+		return "catalog", "", ""
+	}
+	if len(parts) < 2 || parts[0] != "service_instances" {
+		return "unknown", "", ""
+	}
+	instanceID := parts[1]
+	switch {
+	case len(parts) == 2:
+		switch method {
+		case http.MethodPut:
+			return "provision", instanceID, ""
+		case http.MethodPatch:
+			return "update", instanceID, ""
+		case http.MethodDelete:
+			return "deprovision", instanceID, ""
+		case http.MethodGet:
+			return "get_instance", instanceID, ""
+		}
+	case len(parts) == 3 && parts[2] == "last_operation":
+		return "last_operation", instanceID, ""
+	case len(parts) >= 4 && parts[2] == "service_bindings":
+		bindingID := parts[3]
+		if len(parts) == 5 && parts[4] == "last_operation" {
+			return "binding_last_operation", instanceID, bindingID
+		}
+		switch method {
+		case http.MethodPut:
+			return "bind", instanceID, bindingID
+		case http.MethodDelete:
+			return "unbind", instanceID, bindingID
+		case http.MethodGet:
+			return "get_binding", instanceID, bindingID
+		}
+	}
+	return "unknown", instanceID, ""
+}
+
+// identity decodes the X-Broker-API-Originating-Identity header, "<platform> <base64 JSON>".
+func identity(header string) *minibroker.Identity {
+	if header == "" {
+		return nil
+	}
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 {
+		return &minibroker.Identity{Platform: parts[0]}
+	}
+	value, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return &minibroker.Identity{Platform: parts[0]}
+	}
+	id, err := broker.ResolveIdentity(&osb.OriginatingIdentity{Platform: parts[0], Value: string(value)})
+	if err != nil {
+		return &minibroker.Identity{Platform: parts[0]}
+	}
+	// The decoded fields are enough for the known platforms.
+	if id.Platform == broker.PlatformKubernetes || id.Platform == broker.PlatformCloudFoundry {
+		id.Value = nil
+	}
+	return id
+}
+
+// outcome classifies the response of a request.
+func outcome(statusCode int, body []byte) (string, string) {
+	switch {
+	case statusCode == http.StatusAccepted:
+		return OutcomeAccepted, ""
+	case statusCode < 400:
+		return OutcomeSuccess, ""
+	}
+
+	var response struct {
+		Error string `json:"error"`
+	}
+	_ = json.Unmarshal(body, &response)
+	errorClass := response.Error
+	if errorClass == "" {
+		errorClass = strings.ToLower(strings.Replace(http.StatusText(statusCode), " ", "_", -1))
+	}
+	if statusCode >= 500 {
+		return OutcomeError, errorClass
+	}
+	return OutcomeRejected, errorClass
+}
+
+// Redact returns a copy of the parameters with the values of the keys matching
+// DefaultSensitiveKeys replaced, at any depth.
+func Redact(parameters map[string]interface{}) map[string]interface{} {
+	return defaultRedactor.Redact(parameters)
+}
+
+// Redact returns a copy of the parameters with the values of the sensitive keys replaced, at any
+// depth.
+func (r *Redactor) Redact(parameters map[string]interface{}) map[string]interface{} {
+	if parameters == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(parameters))
+	for key, value := range parameters {
+		if r.sensitiveKeys.MatchString(key) {
+			redacted[key] = Redacted
+			continue
+		}
+		redacted[key] = r.redactValue(value)
+	}
+	return redacted
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.Redact(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i := range v {
+			values[i] = r.redactValue(v[i])
+		}
+		return values
+	default:
+		return v
+	}
+}
+
+// maxErrorBody bounds the response body kept for classifying the errors.
+const maxErrorBody = 4096
+
+// responseRecorder records the status code and the beginning of the error responses.
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	if r.statusCode >= 400 && r.body.Len() < maxErrorBody {
+		n := maxErrorBody - r.body.Len()
+		if n > len(data) {
+			n = len(data)
+		}
+		r.body.Write(data[:n])
+	}
+	return r.ResponseWriter.Write(data)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit_test
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/audit"
+	"github.com/kubernetes-sigs/minibroker/pkg/auth"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+)
+
+type fakeSink struct {
+	events []*audit.Event
+}
+
+func (s *fakeSink) Write(event *audit.Event) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	sink := &fakeSink{}
+	logger := audit.NewLogger(nil, sink)
+	handler := log.CorrelationMiddleware(logger.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case strings.Contains(string(body), "conflict"):
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error":"ConcurrencyError","description":"Concurrent modification not supported"}`))
+		case r.Method == http.MethodPut:
+			w.WriteHeader(http.StatusAccepted)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})))
+
+	identity := "kubernetes " + base64.StdEncoding.EncodeToString([]byte(`{"username":"jane","groups":["admins"]}`))
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/v2/service_instances/db", `{"service_id":"mysql","plan_id":"mysql-5-7","context":{"platform":"kubernetes","namespace":"team-a"},"parameters":{"db":{"user":"app","password":"s3cr3t"},"rootPassword":"s3cr3t","replicas":2}}`},
+		{http.MethodPut, "/v2/service_instances/db/service_bindings/app", `{"service_id":"mysql","parameters":{"conflict":true}}`},
+		{http.MethodDelete, "/v2/service_instances/db?service_id=mysql&plan_id=mysql-5-7", ""},
+		{http.MethodGet, "/healthz", ""},
+	}
+	for _, r := range requests {
+		req := httptest.NewRequest(r.method, r.path, strings.NewReader(r.body))
+		req.Header.Set("X-Broker-API-Originating-Identity", identity)
+		req.Header.Set(log.CorrelationIDHeader, "req-"+r.method)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(sink.events) != 3 {
+		t.Fatalf("expected 3 events, actual %d", len(sink.events))
+	}
+
+	provision := sink.events[0]
+	if provision.Operation != "provision" || provision.InstanceID != "db" || provision.ServiceID != "mysql" ||
+		provision.PlanID != "mysql-5-7" || provision.Namespace != "team-a" || provision.Platform != "kubernetes" {
+		t.Errorf("unexpected provision event %+v", provision)
+	}
+	if provision.Outcome != audit.OutcomeAccepted || provision.StatusCode != http.StatusAccepted || provision.ErrorClass != "" {
+		t.Errorf("expected an accepted provision, actual %+v", provision)
+	}
+	if provision.CorrelationID != "req-PUT" {
+		t.Errorf("expected the correlation ID of the request, actual %q", provision.CorrelationID)
+	}
+	if provision.Identity == nil || provision.Identity.Username != "jane" || provision.Identity.Value != nil {
+		t.Errorf("expected the decoded identity of jane, actual %+v", provision.Identity)
+	}
+	expectedParams := map[string]interface{}{
+		"db":           map[string]interface{}{"user": "app", "password": audit.Redacted},
+		"rootPassword": audit.Redacted,
+		"replicas":     float64(2),
+	}
+	if !reflect.DeepEqual(provision.Parameters, expectedParams) {
+		t.Errorf("expected the redacted parameters %v, actual %v", expectedParams, provision.Parameters)
+	}
+
+	bind := sink.events[1]
+	if bind.Operation != "bind" || bind.BindingID != "app" || bind.Outcome != audit.OutcomeRejected || bind.ErrorClass != "ConcurrencyError" {
+		t.Errorf("unexpected bind event %+v", bind)
+	}
+
+	deprovision := sink.events[2]
+	if deprovision.Operation != "deprovision" || deprovision.ServiceID != "mysql" || deprovision.PlanID != "mysql-5-7" ||
+		deprovision.Outcome != audit.OutcomeRejected || deprovision.ErrorClass != "unauthorized" {
+		t.Errorf("unexpected deprovision event %+v", deprovision)
+	}
+}
+
+func TestMiddlewareBodyLimit(t *testing.T) {
+	sink := &fakeSink{}
+	logger := audit.NewLogger(nil, sink)
+	handler := logger.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("expected the oversized request not to reach the handler")
+	}))
+
+	body := `{"parameters":{"data":"` + strings.Repeat("x", auth.MaxBodySize) + `"}}`
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPut, "/v2/service_instances/db", strings.NewReader(body)))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("expected a 400 for an oversized body, actual %d", recorder.Code)
+	}
+	if len(sink.events) != 1 || sink.events[0].StatusCode != http.StatusBadRequest || sink.events[0].Outcome != audit.OutcomeRejected {
+		t.Errorf("expected a rejected provision event, actual %+v", sink.events)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	parameters := map[string]interface{}{
+		"users": []interface{}{map[string]interface{}{"name": "app", "apiKey": "k"}},
+		"tls":   map[string]interface{}{"cert": "c", "enabled": true},
+	}
+	expected := map[string]interface{}{
+		"users": []interface{}{map[string]interface{}{"name": "app", "apiKey": audit.Redacted}},
+		"tls":   map[string]interface{}{"cert": audit.Redacted, "enabled": true},
+	}
+	if actual := audit.Redact(parameters); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+	if parameters["tls"].(map[string]interface{})["cert"] != "c" {
+		t.Errorf("expected the parameters to be left unchanged")
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	redactor, err := audit.NewRedactor(`(?i)^(dsn|apikey)$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parameters := map[string]interface{}{
+		"dsn":      "mysql://app:s3cr3t@db",
+		"password": "p",
+		"nested":   map[string]interface{}{"apiKey": "k"},
+	}
+	expected := map[string]interface{}{
+		"dsn":      audit.Redacted,
+		"password": "p",
+		"nested":   map[string]interface{}{"apiKey": audit.Redacted},
+	}
+	if actual := redactor.Redact(parameters); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, actual %v", expected, actual)
+	}
+
+	for _, pattern := range []string{"", "("} {
+		if _, err := audit.NewRedactor(pattern); err == nil {
+			t.Errorf("expected an error for the pattern %q", pattern)
+		}
+	}
+}
+
+func TestMiddlewareRedactor(t *testing.T) {
+	redactor, err := audit.NewRedactor(`^dsn$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink := &fakeSink{}
+	handler := audit.NewLogger(redactor, sink).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	body := `{"service_id":"mysql","plan_id":"mysql-5-7-28","parameters":{"dsn":"mysql://db","rootPassword":"p"}}`
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPut, "/v2/service_instances/db", strings.NewReader(body)))
+
+	expected := map[string]interface{}{"dsn": audit.Redacted, "rootPassword": "p"}
+	if len(sink.events) != 1 || !reflect.DeepEqual(sink.events[0].Parameters, expected) {
+		t.Errorf("expected the parameters %v, actual %+v", expected, sink.events)
+	}
+}
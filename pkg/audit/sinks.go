@@ -0,0 +1,270 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+)
+
+var droppedEventsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "minibroker",
+		Name:      "audit_events_dropped_total",
+		Help:      "The number of audit events a sink failed to deliver.",
+	},
+	[]string{"sink"},
+)
+
+// Collectors returns the Prometheus collectors of the audit.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		droppedEventsTotal,
+	}
+}
+
+// WriterSink writes the events as JSON lines to a writer, e.g. os.Stdout.
+type WriterSink struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+var _ Sink = &WriterSink{}
+
+// NewWriterSink creates a new WriterSink.
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write writes the event as a JSON line.
+func (s *WriterSink) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close does nothing; the writer is owned by the caller.
+func (s *WriterSink) Close() error {
+	return nil
+}
+
+// FileSink writes the events as JSON lines to a file, rotating it when it reaches its maximum size.
+// The rotated files are suffixed with .1, the most recent, up to .<maxBackups>.
+type FileSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+}
+
+var _ Sink = &FileSink{}
+
+// NewFileSink creates a new FileSink appending to the given file.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	s := &FileSink{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open the audit file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open the audit file: %w", err)
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// Write appends the event as a JSON line, rotating the file first when the line does not fit.
+func (s *FileSink) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.maxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			droppedEventsTotal.WithLabelValues("file").Inc()
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	if err != nil {
+		droppedEventsTotal.WithLabelValues("file").Inc()
+	}
+	return err
+}
+
+// rotate shifts the backups, dropping the oldest one, and reopens the file.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate the audit file: %w", err)
+	}
+	if s.maxBackups > 0 {
+		for i := s.maxBackups - 1; i > 0; i-- {
+			from := fmt.Sprintf("%s.%d", s.path, i)
+			if _, err := os.Stat(from); err == nil {
+				if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+					return fmt.Errorf("failed to rotate the audit file: %w", err)
+				}
+			}
+		}
+		if err := os.Rename(s.path, s.path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate the audit file: %w", err)
+		}
+	} else if err := os.Remove(s.path); err != nil {
+		return fmt.Errorf("failed to rotate the audit file: %w", err)
+	}
+	return s.open()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.file.Close()
+}
+
+// webhookTimeout bounds each webhook delivery.
+const webhookTimeout = 10 * time.Second
+
+// WebhookSink posts the events as JSON to an HTTP endpoint in the background, so a slow endpoint
+// does not delay the requests. The events are dropped, and counted, when the queue is full or the
+// delivery fails.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+	queue  chan *Event
+	done   chan struct{}
+
+	// mutex guards closed, so the events written by the requests still in flight on Close are
+	// dropped instead of sent on the closed queue.
+	mutex  sync.RWMutex
+	closed bool
+}
+
+var _ Sink = &WebhookSink{}
+
+// NewWebhookSink creates a new WebhookSink queueing up to queueSize events.
+func NewWebhookSink(url string, queueSize int) *WebhookSink {
+	s := &WebhookSink{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+		queue:  make(chan *Event, queueSize),
+		done:   make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Write queues the event for delivery.
+func (s *WebhookSink) Write(event *Event) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		droppedEventsTotal.WithLabelValues("webhook").Inc()
+		return fmt.Errorf("failed to queue the audit event: the webhook sink is closed")
+	}
+	select {
+	case s.queue <- event:
+		return nil
+	default:
+		droppedEventsTotal.WithLabelValues("webhook").Inc()
+		return fmt.Errorf("failed to queue the audit event: the webhook queue is full")
+	}
+}
+
+func (s *WebhookSink) run() {
+	defer close(s.done)
+	for event := range s.queue {
+		if err := s.post(event); err != nil {
+			droppedEventsTotal.WithLabelValues("webhook").Inc()
+			// The events are delivered after their request completed, so they are correlated by
+			// their ID rather than by the request context.
+			log.FromContext(context.Background()).WithName("audit").Error(err, "failed to deliver the event",
+				"correlationID", event.CorrelationID, "method", event.Method, "path", event.Path)
+		}
+	}
+}
+
+func (s *WebhookSink) post(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close delivers the queued events and stops the sink. The events written afterwards are dropped.
+func (s *WebhookSink) Close() error {
+	s.mutex.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mutex.Unlock()
+
+	<-s.done
+	return nil
+}
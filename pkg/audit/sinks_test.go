@@ -0,0 +1,133 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/audit"
+)
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := audit.NewWriterSink(&buf)
+	if err := sink.Write(&audit.Event{Operation: "provision", InstanceID: "db"}); err != nil {
+		t.Fatalf("Write: unexpected error %v", err)
+	}
+	if !strings.HasSuffix(buf.String(), "\n") || !strings.Contains(buf.String(), `"instanceID":"db"`) {
+		t.Errorf("expected a JSON line, actual %q", buf.String())
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	line, _ := json.Marshal(&audit.Event{Operation: "provision", InstanceID: "000"})
+	// Room for two events per file.
+	sink, err := audit.NewFileSink(path, int64(2*(len(line)+1)), 2)
+	if err != nil {
+		t.Fatalf("NewFileSink: unexpected error %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := sink.Write(&audit.Event{Operation: "provision", InstanceID: fmt.Sprintf("%03d", i)}); err != nil {
+			t.Fatalf("Write: unexpected error %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: unexpected error %v", err)
+	}
+
+	expected := map[string][]string{
+		path:        {"006"},
+		path + ".1": {"004", "005"},
+		path + ".2": {"002", "003"},
+	}
+	for file, ids := range expected {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if len(lines) != len(ids) {
+			t.Errorf("%s: expected %d events, actual %q", file, len(ids), data)
+			continue
+		}
+		for i, id := range ids {
+			if !strings.Contains(lines[i], fmt.Sprintf(`"instanceID":%q`, id)) {
+				t.Errorf("%s: expected event %s, actual %q", file, id, lines[i])
+			}
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, actual err %v", err)
+	}
+}
+
+func TestWebhookSink(t *testing.T) {
+	var mutex sync.Mutex
+	received := make([]audit.Event, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event audit.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		received = append(received, event)
+	}))
+	defer server.Close()
+
+	sink := audit.NewWebhookSink(server.URL, 10)
+	for _, id := range []string{"a", "b"} {
+		if err := sink.Write(&audit.Event{Operation: "bind", BindingID: id}); err != nil {
+			t.Fatalf("Write: unexpected error %v", err)
+		}
+	}
+	// Closing delivers the queued events.
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: unexpected error %v", err)
+	}
+	// The requests still in flight write after Close.
+	if err := sink.Write(&audit.Event{Operation: "bind", BindingID: "c"}); err == nil {
+		t.Errorf("Write: expected an error after Close")
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Close: unexpected error %v closing twice", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(received) != 2 || received[0].BindingID != "a" || received[1].BindingID != "b" {
+		t.Errorf("expected the events a and b, actual %+v", received)
+	}
+}
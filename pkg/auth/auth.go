@@ -0,0 +1,244 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-logr/logr"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PathPrefix is the path prefix of the OSB API routes requiring authentication. The other routes,
+// e.g. /metrics, /healthz and the administration API, are left to their own authentication.
+const PathPrefix = "/v2/"
+
+// DefaultReloadInterval is how often the credentials file is checked for changes by default.
+const DefaultReloadInterval = 30 * time.Second
+
+// The reasons of the authentication failures counted by the failures metric.
+const (
+	FailureMissing = "missing"
+	FailureInvalid = "invalid"
+)
+
+var failuresTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "minibroker",
+		Name:      "auth_failures_total",
+		Help:      "The number of OSB API requests rejected for missing or invalid credentials.",
+	},
+	[]string{"reason"},
+)
+
+// Collectors returns the Prometheus collectors of the authentication.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		failuresTotal,
+	}
+}
+
+// BasicCredential is a username and password accepted through HTTP basic auth.
+type BasicCredential struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Credentials are the credentials accepted by the OSB API. Several credentials of each kind can be
+// listed for rotating them without downtime.
+type Credentials struct {
+	Basic  []BasicCredential `json:"basic,omitempty"`
+	Tokens []string          `json:"tokens,omitempty"`
+}
+
+// LoadCredentials loads the credentials from their YAML representation.
+func LoadCredentials(data []byte) (*Credentials, error) {
+	credentials := &Credentials{}
+	if err := yaml.UnmarshalStrict(data, credentials, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("failed to load the credentials: %w", err)
+	}
+	for i, basic := range credentials.Basic {
+		if basic.Username == "" || basic.Password == "" {
+			return nil, fmt.Errorf("failed to load the credentials: basic credential %d has no username or password", i)
+		}
+	}
+	for i, token := range credentials.Tokens {
+		if token == "" {
+			return nil, fmt.Errorf("failed to load the credentials: token %d is empty", i)
+		}
+	}
+	if len(credentials.Basic) == 0 && len(credentials.Tokens) == 0 {
+		return nil, fmt.Errorf("failed to load the credentials: no credentials")
+	}
+	return credentials, nil
+}
+
+// Authenticator authenticates the OSB API requests against the credentials read from a file,
+// usually a mounted Secret. The file is reloaded when it changes.
+type Authenticator struct {
+	path string
+
+	mutex       sync.RWMutex
+	data        []byte
+	credentials *Credentials
+}
+
+// NewAuthenticator creates a new Authenticator with the credentials read from the given file.
+func NewAuthenticator(path string) (*Authenticator, error) {
+	a := &Authenticator{path: path}
+	if _, err := a.Reload(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reload reads the credentials file again, returning whether the credentials changed. The
+// current credentials are kept when the file is invalid.
+func (a *Authenticator) Reload() (bool, error) {
+	data, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to load the credentials: %w", err)
+	}
+
+	a.mutex.RLock()
+	unchanged := a.credentials != nil && bytes.Equal(data, a.data)
+	a.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	credentials, err := LoadCredentials(data)
+	if err != nil {
+		return false, err
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.data = data
+	a.credentials = credentials
+	return true, nil
+}
+
+// Watch reloads the credentials file on every interval until the context is done.
+func (a *Authenticator) Watch(ctx context.Context, interval time.Duration) {
+	logger := log.FromContext(ctx).WithName("auth")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := a.Reload()
+			if err != nil {
+				logger.Error(err, "keeping the current credentials", "path", a.path)
+				continue
+			}
+			if changed {
+				logger.V(2).Info("reloaded the credentials", "path", a.path)
+			}
+		}
+	}
+}
+
+// Middleware wraps a handler, rejecting the OSB API requests without valid credentials with a 401.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, PathPrefix) || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if reason := a.authenticate(r); reason != "" {
+			failuresTotal.WithLabelValues(reason).Inc()
+			requestLogger(r).V(3).Info("rejected the request", "reason", reason+" credentials")
+			a.unauthorized(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate returns the reason the request failed the authentication, or an empty string when
+// it succeeded.
+func (a *Authenticator) authenticate(r *http.Request) string {
+	a.mutex.RLock()
+	credentials := a.credentials
+	a.mutex.RUnlock()
+
+	if username, password, ok := r.BasicAuth(); ok {
+		for _, basic := range credentials.Basic {
+			if equal(username, basic.Username) && equal(password, basic.Password) {
+				return ""
+			}
+		}
+		return FailureInvalid
+	}
+
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, prefix) {
+		return FailureMissing
+	}
+	token := strings.TrimPrefix(header, prefix)
+	for _, accepted := range credentials.Tokens {
+		if equal(token, accepted) {
+			return ""
+		}
+	}
+	return FailureInvalid
+}
+
+func (a *Authenticator) unauthorized(w http.ResponseWriter) {
+	a.mutex.RLock()
+	credentials := a.credentials
+	a.mutex.RUnlock()
+
+	if len(credentials.Basic) > 0 {
+		w.Header().Add("WWW-Authenticate", `Basic realm="minibroker"`)
+	}
+	if len(credentials.Tokens) > 0 {
+		w.Header().Add("WWW-Authenticate", `Bearer realm="minibroker"`)
+	}
+	writeError(w, http.StatusUnauthorized, "unauthorized")
+}
+
+// requestLogger returns the structured logger of a request, carrying its method and path.
+func requestLogger(r *http.Request) logr.Logger {
+	return log.FromContext(r.Context()).WithName("auth").WithValues("method", r.Method, "path", r.URL.Path)
+}
+
+// writeError writes an OSB error response.
+func writeError(w http.ResponseWriter, statusCode int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]string{"description": description})
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
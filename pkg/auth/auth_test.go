@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/auth"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func writeCredentials(t *testing.T, path, data string) {
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func failures(t *testing.T, reason string) float64 {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(auth.Collectors()...)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "reason" && label.GetValue() == reason {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestAuthenticator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := dir + "/credentials.yaml"
+	writeCredentials(t, path, `
+basic:
+- username: platform
+  password: old
+- username: platform
+  password: new
+tokens:
+- token
+`)
+
+	authenticator, err := auth.NewAuthenticator(path)
+	if err != nil {
+		t.Fatalf("NewAuthenticator: unexpected error %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := authenticator.Middleware(ok)
+
+	type request struct {
+		path       string
+		username   string
+		password   string
+		token      string
+		statusCode int
+	}
+	check := func(tests []request) {
+		for _, tt := range tests {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.username != "" {
+				req.SetBasicAuth(tt.username, tt.password)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			if w.Code != tt.statusCode {
+				t.Errorf("%+v: expected %d, actual %d", tt, tt.statusCode, w.Code)
+			}
+		}
+	}
+
+	check([]request{
+		{path: "/v2/catalog", statusCode: http.StatusUnauthorized},
+		{path: "/v2/catalog", username: "platform", password: "old", statusCode: http.StatusOK},
+		{path: "/v2/catalog", username: "platform", password: "new", statusCode: http.StatusOK},
+		{path: "/v2/catalog", username: "platform", password: "wrong", statusCode: http.StatusUnauthorized},
+		{path: "/v2/catalog", token: "token", statusCode: http.StatusOK},
+		{path: "/v2/catalog", token: "wrong", statusCode: http.StatusUnauthorized},
+		{path: "/metrics", statusCode: http.StatusOK},
+		{path: "/healthz", statusCode: http.StatusOK},
+		{path: "/admin/v1/instances", statusCode: http.StatusOK},
+	})
+	if missing, invalid := failures(t, auth.FailureMissing), failures(t, auth.FailureInvalid); missing != 1 || invalid != 2 {
+		t.Errorf("expected 1 missing and 2 invalid credentials failures, actual %v and %v", missing, invalid)
+	}
+
+	writeCredentials(t, path, `
+basic:
+- username: platform
+  password: new
+`)
+	changed, err := authenticator.Reload()
+	if err != nil || !changed {
+		t.Fatalf("Reload: expected a change, actual %v, %v", changed, err)
+	}
+	check([]request{
+		{path: "/v2/catalog", username: "platform", password: "old", statusCode: http.StatusUnauthorized},
+		{path: "/v2/catalog", username: "platform", password: "new", statusCode: http.StatusOK},
+		{path: "/v2/catalog", token: "token", statusCode: http.StatusUnauthorized},
+	})
+
+	writeCredentials(t, path, "basic: []\n")
+	if _, err := authenticator.Reload(); err == nil {
+		t.Errorf("Reload: expected an error for a file without credentials")
+	}
+	check([]request{
+		{path: "/v2/catalog", username: "platform", password: "new", statusCode: http.StatusOK},
+	})
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// AnyPlatform allows a client certificate to act for any platform.
+const AnyPlatform = "*"
+
+// FailureForbidden is the reason counted by the failures metric when a client certificate is not
+// allowed to act for the platform of a request.
+const FailureForbidden = "forbidden"
+
+// ClientCertificate maps the client certificates matching its subject fields to the platforms they
+// are allowed to act for.
+type ClientCertificate struct {
+	// The common name of the certificate subject. Any common name matches when empty.
+	CommonName string `json:"commonName,omitempty"`
+	// An organization of the certificate subject. Any organization matches when empty.
+	Organization string `json:"organization,omitempty"`
+	// The platforms of the OSB contexts the client may send, e.g. kubernetes or cloudfoundry.
+	Platforms []string `json:"platforms"`
+}
+
+// ClientCertificatePolicy requires the OSB API requests to present a verified client certificate
+// and, when Clients is not empty, restricts the platforms each client may act for.
+type ClientCertificatePolicy struct {
+	Clients []ClientCertificate `json:"clients,omitempty"`
+
+	instances InstancePlatformGetter
+}
+
+// InstancePlatformGetter gets the platform an instance was provisioned for, and whether the instance
+// exists.
+type InstancePlatformGetter interface {
+	InstancePlatform(ctx context.Context, instanceID string) (string, bool, error)
+}
+
+// SetInstances sets the getter of the instance platforms, restricting the requests on the existing
+// instances and their bindings to the clients allowed for the platform of the instance. Only the
+// platform of the request body is checked otherwise.
+func (p *ClientCertificatePolicy) SetInstances(instances InstancePlatformGetter) {
+	p.instances = instances
+}
+
+// LoadClientCertificatePolicy loads the policy from its YAML representation.
+func LoadClientCertificatePolicy(data []byte) (*ClientCertificatePolicy, error) {
+	policy := &ClientCertificatePolicy{}
+	if err := yaml.UnmarshalStrict(data, policy, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("failed to load the client certificate policy: %w", err)
+	}
+	for i, client := range policy.Clients {
+		if client.CommonName == "" && client.Organization == "" {
+			return nil, fmt.Errorf("failed to load the client certificate policy: client %d has neither commonName nor organization", i)
+		}
+		if len(client.Platforms) == 0 {
+			return nil, fmt.Errorf("failed to load the client certificate policy: client %d has no platforms", i)
+		}
+	}
+	return policy, nil
+}
+
+// Middleware wraps a handler, rejecting the OSB API requests without a verified client certificate
+// with a 401, and the requests for a platform the client is not allowed to act for with a 403.
+func (p *ClientCertificatePolicy) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, PathPrefix) || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			failuresTotal.WithLabelValues(FailureMissing).Inc()
+			requestLogger(r).V(3).Info("rejected the request", "reason", "missing client certificate")
+			writeError(w, http.StatusUnauthorized, "a verified client certificate is required")
+			return
+		}
+		if len(p.Clients) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		leaf := r.TLS.VerifiedChains[0][0]
+		client := p.client(leaf)
+		if client == nil {
+			failuresTotal.WithLabelValues(FailureForbidden).Inc()
+			requestLogger(r).V(3).Info("rejected the request", "reason", "client certificate not allowed", "subject", leaf.Subject.String())
+			writeError(w, http.StatusForbidden, fmt.Sprintf("client certificate %q is not allowed", leaf.Subject))
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, instancesPathPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		platforms, err := p.requestPlatforms(r)
+		if err != nil {
+			requestLogger(r).V(3).Info("rejected the request", "reason", err.Error())
+			writeError(w, err.statusCode, err.Error())
+			return
+		}
+		for _, platform := range platforms {
+			if !client.allows(platform) {
+				failuresTotal.WithLabelValues(FailureForbidden).Inc()
+				requestLogger(r).V(3).Info("rejected the request", "reason", "client certificate not allowed for platform",
+					"subject", leaf.Subject.String(), "platform", platform)
+				writeError(w, http.StatusForbidden, fmt.Sprintf("client certificate %q is not allowed for platform %q", leaf.Subject, platform))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// client returns the first client matching the certificate subject, or nil.
+func (p *ClientCertificatePolicy) client(cert *x509.Certificate) *ClientCertificate {
+	for i := range p.Clients {
+		client := &p.Clients[i]
+		if client.CommonName != "" && client.CommonName != cert.Subject.CommonName {
+			continue
+		}
+		if client.Organization != "" && !contains(cert.Subject.Organization, client.Organization) {
+			continue
+		}
+		return client
+	}
+	return nil
+}
+
+func (c *ClientCertificate) allows(platform string) bool {
+	return contains(c.Platforms, AnyPlatform) || (platform != "" && contains(c.Platforms, platform))
+}
+
+// instancesPathPrefix is the path prefix of the OSB API instance and binding routes.
+const instancesPathPrefix = "/v2/service_instances/"
+
+// MaxBodySize bounds the request bodies read by the middlewares inspecting them, such as for finding
+// the platform.
+const MaxBodySize = 1 << 20
+
+// platformError is an error resolving the platforms of a request, with the status of the response.
+type platformError struct {
+	statusCode int
+	err        error
+}
+
+func (e *platformError) Error() string {
+	return e.err.Error()
+}
+
+// requestPlatforms returns the platforms a request acts for: the platform of the OSB context in the
+// body of the PUT and PATCH requests, and the platform recorded for the existing instance the
+// request targets. A request without either acts for no platform, which only the clients allowed
+// for any platform may do; the bodyless requests are not restricted without an instance getter.
+func (p *ClientCertificatePolicy) requestPlatforms(r *http.Request) ([]string, *platformError) {
+	var platforms []string
+	hasBody := r.Method == http.MethodPut || r.Method == http.MethodPatch
+	if hasBody {
+		platform, err := requestPlatform(r)
+		if err != nil {
+			return nil, &platformError{http.StatusBadRequest, err}
+		}
+		if platform != "" {
+			platforms = append(platforms, platform)
+		}
+	}
+	if p.instances != nil {
+		instanceID := strings.SplitN(strings.TrimPrefix(r.URL.Path, instancesPathPrefix), "/", 2)[0]
+		platform, found, err := p.instances.InstancePlatform(r.Context(), instanceID)
+		if err != nil {
+			return nil, &platformError{http.StatusInternalServerError, err}
+		}
+		if found && !contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	if len(platforms) == 0 && (hasBody || p.instances != nil) {
+		platforms = append(platforms, "")
+	}
+	return platforms, nil
+}
+
+// requestPlatform returns the platform of the OSB context in the request body, leaving the body
+// readable by the next handler. Contexts with a namespace and no platform are Kubernetes contexts,
+// as the broker resolves them.
+func requestPlatform(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return "", nil
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxBodySize))
+	r.Body.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the request body: %w", err)
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	var request struct {
+		Context struct {
+			Platform  string      `json:"platform"`
+			Namespace interface{} `json:"namespace"`
+		} `json:"context"`
+	}
+	if len(body) > 0 {
+		// Malformed bodies are left to the next handler to reject.
+		_ = json.Unmarshal(body, &request)
+	}
+	if request.Context.Platform == "" && request.Context.Namespace != nil {
+		return "kubernetes", nil
+	}
+	return request.Context.Platform, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+)
+
+// CertificateReloader serves the TLS certificate, and the CA bundle client certificates are
+// verified against, from files that are reloaded when they change. The reloaded files only apply
+// to the new connections, so the established ones are not dropped.
+type CertificateReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mutex       sync.RWMutex
+	data        [][]byte
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+// NewCertificateReloader creates a new CertificateReloader for the given certificate and key
+// files. When clientCAFile is not empty, the client certificates are verified against it.
+func NewCertificateReloader(certFile, keyFile, clientCAFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+	}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate files again, returning whether they changed. The current
+// certificates are kept when the files are invalid.
+func (r *CertificateReloader) Reload() (bool, error) {
+	paths := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		paths = append(paths, r.clientCAFile)
+	}
+	data := make([][]byte, len(paths))
+	for i, path := range paths {
+		var err error
+		if data[i], err = ioutil.ReadFile(path); err != nil {
+			return false, fmt.Errorf("failed to load the TLS certificates: %w", err)
+		}
+	}
+
+	r.mutex.RLock()
+	unchanged := r.certificate != nil && equalData(data, r.data)
+	r.mutex.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	certificate, err := tls.X509KeyPair(data[0], data[1])
+	if err != nil {
+		return false, fmt.Errorf("failed to load the TLS certificates: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data[2]) {
+			return false, fmt.Errorf("failed to load the TLS certificates: no certificate in %s", r.clientCAFile)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.data = data
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	return true, nil
+}
+
+// Watch reloads the certificate files on every interval until the context is done.
+func (r *CertificateReloader) Watch(ctx context.Context, interval time.Duration) {
+	logger := log.FromContext(ctx).WithName("auth")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			if err != nil {
+				logger.Error(err, "keeping the current TLS certificates", "certFile", r.certFile)
+				continue
+			}
+			if changed {
+				logger.V(2).Info("reloaded the TLS certificates", "certFile", r.certFile)
+			}
+		}
+	}
+}
+
+// TLSConfig returns the server TLS configuration using the current certificates on every
+// handshake. Client certificates are verified when given, but not required, so the endpoints
+// outside the OSB API stay reachable without one; see ClientCertificatePolicy.
+func (r *CertificateReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mutex.RLock()
+			defer r.mutex.RUnlock()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.certificate},
+			}
+			if r.clientCAs != nil {
+				config.ClientCAs = r.clientCAs
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+			return config, nil
+		},
+	}
+}
+
+func (r *CertificateReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.certificate, nil
+}
+
+func equalData(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package auth_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/auth"
+)
+
+// fakeInstances maps the existing instances to their platforms.
+type fakeInstances map[string]string
+
+func (f fakeInstances) InstancePlatform(_ context.Context, instanceID string) (string, bool, error) {
+	platform, ok := f[instanceID]
+	return platform, ok, nil
+}
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert creates a certificate signed by the parent, or a self-signed CA when parent is nil.
+func newTestCert(t *testing.T, subject pkix.Name, parent *testCert, serial int64) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      subject,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCert) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return certificate
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "auth-tls")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	ca := newTestCert(t, pkix.Name{CommonName: "ca"}, nil, 1)
+	writeCert := func(c *testCert) {
+		writeCredentials(t, certFile, string(c.certPEM))
+		writeCredentials(t, keyFile, string(c.keyPEM))
+	}
+	writeCert(newTestCert(t, pkix.Name{CommonName: "first"}, ca, 2))
+	writeCredentials(t, caFile, string(ca.certPEM))
+
+	reloader, err := auth.NewCertificateReloader(certFile, keyFile, caFile)
+	if err != nil {
+		t.Fatalf("NewCertificateReloader: unexpected error %v", err)
+	}
+	policy, err := auth.LoadClientCertificatePolicy([]byte(`
+clients:
+- commonName: cloud-controller
+  organization: cf
+  platforms: [cloudfoundry]
+- commonName: service-catalog
+  platforms: ["*"]
+`))
+	if err != nil {
+		t.Fatalf("LoadClientCertificatePolicy: unexpected error %v", err)
+	}
+	policy.SetInstances(fakeInstances{"cf": "cloudfoundry", "k8s": "kubernetes"})
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})
+	server := httptest.NewUnstartedServer(policy.Middleware(ok))
+	server.TLS = reloader.TLSConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientFor := func(client *testCert) *http.Client {
+		config := &tls.Config{RootCAs: roots}
+		if client != nil {
+			config.Certificates = []tls.Certificate{client.tlsCertificate(t)}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+	}
+
+	cf := newTestCert(t, pkix.Name{CommonName: "cloud-controller", Organization: []string{"cf"}}, ca, 3)
+	catalog := newTestCert(t, pkix.Name{CommonName: "service-catalog"}, ca, 4)
+	other := newTestCert(t, pkix.Name{CommonName: "other"}, ca, 5)
+	untrusted := newTestCert(t, pkix.Name{CommonName: "cloud-controller", Organization: []string{"cf"}}, nil, 6)
+
+	tests := []struct {
+		client     *testCert
+		method     string
+		path       string
+		body       string
+		statusCode int
+	}{
+		{nil, http.MethodGet, "/healthz", "", http.StatusOK},
+		{nil, http.MethodGet, "/v2/catalog", "", http.StatusUnauthorized},
+		{cf, http.MethodGet, "/v2/catalog", "", http.StatusOK},
+		{cf, http.MethodPut, "/v2/service_instances/1", `{"context":{"platform":"cloudfoundry"}}`, http.StatusOK},
+		{cf, http.MethodPut, "/v2/service_instances/1", `{"context":{"platform":"kubernetes","namespace":"a"}}`, http.StatusForbidden},
+		{cf, http.MethodPut, "/v2/service_instances/1", `{"context":{"namespace":"a"}}`, http.StatusForbidden},
+		{catalog, http.MethodPut, "/v2/service_instances/1", `{"context":{"namespace":"a"}}`, http.StatusOK},
+		{other, http.MethodGet, "/v2/catalog", "", http.StatusForbidden},
+		{cf, http.MethodDelete, "/v2/service_instances/cf", "", http.StatusOK},
+		{cf, http.MethodDelete, "/v2/service_instances/k8s", "", http.StatusForbidden},
+		{cf, http.MethodDelete, "/v2/service_instances/missing", "", http.StatusForbidden},
+		{catalog, http.MethodDelete, "/v2/service_instances/k8s", "", http.StatusOK},
+		{cf, http.MethodGet, "/v2/service_instances/cf/last_operation", "", http.StatusOK},
+		{cf, http.MethodGet, "/v2/service_instances/k8s", "", http.StatusForbidden},
+		{cf, http.MethodGet, "/v2/service_instances/k8s/service_bindings/1", "", http.StatusForbidden},
+		{cf, http.MethodDelete, "/v2/service_instances/k8s/service_bindings/1", "", http.StatusForbidden},
+		{cf, http.MethodPut, "/v2/service_instances/cf/service_bindings/1", `{}`, http.StatusOK},
+		{cf, http.MethodPut, "/v2/service_instances/k8s/service_bindings/1", `{"context":{"platform":"cloudfoundry"}}`, http.StatusForbidden},
+		{cf, http.MethodPatch, "/v2/service_instances/k8s", `{"context":{"platform":"cloudfoundry"}}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		resp, err := clientFor(tt.client).Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: unexpected error %v", tt.method, tt.path, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.statusCode {
+			t.Errorf("%s %s %s: expected %d, actual %d %q", tt.method, tt.path, tt.body, tt.statusCode, resp.StatusCode, body)
+		}
+		if resp.StatusCode == http.StatusOK && string(body) != tt.body {
+			t.Errorf("%s %s: expected the body %q to reach the handler, actual %q", tt.method, tt.path, tt.body, body)
+		}
+	}
+
+	// The client does not present a certificate the server would not accept.
+	resp, err := clientFor(untrusted).Get(server.URL + "/v2/catalog")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("expected an untrusted client certificate to be rejected, actual %d", resp.StatusCode)
+		}
+	}
+
+	// The rotated certificate is served to the new connections.
+	writeCert(newTestCert(t, pkix.Name{CommonName: "second"}, ca, 7))
+	if changed, err := reloader.Reload(); err != nil || !changed {
+		t.Fatalf("Reload: expected a change, actual %v, %v", changed, err)
+	}
+	resp, err = clientFor(cf).Get(server.URL + "/v2/catalog")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	if cn := resp.TLS.PeerCertificates[0].Subject.CommonName; cn != "second" {
+		t.Errorf("expected the reloaded certificate, actual %q", cn)
+	}
+
+	writeCredentials(t, keyFile, "invalid")
+	if _, err := reloader.Reload(); err == nil {
+		t.Errorf("Reload: expected an error for an invalid key")
+	}
+}
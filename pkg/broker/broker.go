@@ -17,16 +17,24 @@ limitations under the License.
 package broker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"sync"
 
 	"github.com/ghodss/yaml"
+	"github.com/go-logr/logr"
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	"github.com/kubernetes-sigs/minibroker/pkg/kubernetes"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
 	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
 	"github.com/pmorie/osb-broker-lib/pkg/broker"
-	klog "k8s.io/klog/v2"
+	"go.opentelemetry.io/otel/attribute"
+	k8s "k8s.io/client-go/kubernetes"
 )
 
 // ProvisioningSettings represents the configuration regarding the provisioning of services.
@@ -76,27 +84,83 @@ func (d *ProvisioningSettings) ForService(service string) (*ServiceProvisioningS
 // MinibrokerClient defines the interface of the client the broker operates on.
 type MinibrokerClient interface {
 	Init(repoURL string) error
-	ListServices() ([]osb.Service, error)
-	Provision(instanceID, serviceID, planID, namespace string, acceptsIncomplete bool, provisionParams *minibroker.ProvisionParams) (string, error)
-	Bind(instanceID, serviceID, bindingID string, acceptsIncomplete bool, bindParams *minibroker.BindParams) (string, error)
-	Unbind(instanceID, bindingID string) error
-	GetBinding(instanceID, bindingID string) (*osb.GetBindingResponse, error)
-	Deprovision(instanceID string, acceptsIncomplete bool) (string, error)
-	LastOperationState(instanceID string, operationKey *osb.OperationKey) (*osb.LastOperationResponse, error)
-	LastBindingOperationState(instanceID, bindingID string) (*osb.LastOperationResponse, error)
+	ListServices(ctx context.Context) ([]osb.Service, error)
+	Provision(ctx context.Context, request *minibroker.ProvisionRequest) (string, error)
+	Bind(ctx context.Context, request *minibroker.BindRequest) (string, error)
+	Unbind(ctx context.Context, instanceID, bindingID string) error
+	GetBinding(ctx context.Context, instanceID, bindingID string) (*osb.GetBindingResponse, error)
+	FetchInstance(ctx context.Context, instanceID string) (*minibroker.FetchedInstance, error)
+	Deprovision(ctx context.Context, instanceID string, acceptsIncomplete bool) (string, error)
+	LastOperationState(ctx context.Context, instanceID string, operationKey *osb.OperationKey) (*osb.LastOperationResponse, error)
+	LastBindingOperationState(ctx context.Context, instanceID, bindingID string) (*osb.LastOperationResponse, error)
+	ListInstances(ctx context.Context) ([]minibroker.InstanceSummary, error)
 }
 
 // NewBrokerFromOptions is a hook that is called with the Options the program is run
 // with. NewBroker is the place where you will initialize your
 // Broker the parameters passed in.
-func NewBrokerFromOptions(o Options) (*Broker, error) {
-	klog.V(5).Infof("broker: creating a new broker with options %+v", o)
-	mb := minibroker.NewClient(o.ConfigNamespace, o.ServiceCatalogEnabledOnly, o.ClusterDomain)
-	err := mb.Init(o.HelmRepoURL)
+// The context bounds the lifetime of the background tasks started for the broker.
+func NewBrokerFromOptions(ctx context.Context, o Options) (*Broker, error) {
+	log.FromContext(ctx).WithName("broker").V(5).Info("creating a new broker", "options", o)
+	config, err := kubernetes.RESTConfig(o.KubeConfig, o.KubeContext)
 	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+	}
+	coreClient, err := k8s.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+	}
+	helmClient := helm.NewClientForConfig(helm.NewKubeConfigProvider(o.KubeConfig, o.KubeContext))
+
+	var clusters *minibroker.ClustersConfig
+	if len(o.ClustersConfigPath) > 0 {
+		data, err := ioutil.ReadFile(o.ClustersConfigPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+		if clusters, err = minibroker.LoadClustersConfig(data); err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+	}
+
+	var isolation *minibroker.IsolationConfig
+	if o.NamespacePerInstance {
+		var data []byte
+		if len(o.IsolationConfigPath) > 0 {
+			if data, err = ioutil.ReadFile(o.IsolationConfigPath); err != nil {
+				return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+			}
+		}
+		if isolation, err = minibroker.LoadIsolationConfig(data); err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+	}
+
+	mb := minibroker.NewClient(ctx, helmClient, coreClient, minibroker.ClientOptions{
+		Namespace:                 o.ConfigNamespace,
+		ServiceCatalogEnabledOnly: o.ServiceCatalogEnabledOnly,
+		ClusterDomain:             o.ClusterDomain,
+		PerBindingUsers:           o.PerBindingUsers,
+		RotationGracePeriod:       o.CredentialRotationGracePeriod,
+		LiveCredentials:           o.LiveCredentials,
+		SecretKeysPassthrough:     o.SecretKeysPassthrough,
+		GenericSecretKeys:         o.GenericSecretKeys,
+		ProjectBindingSecrets:     o.ProjectBindingSecrets,
+		Clusters:                  clusters,
+		Isolation:                 isolation,
+		EventRecorder:             minibroker.NewEventRecorder(ctx, coreClient),
+	})
+	if err := mb.Init(o.HelmRepoURL); err != nil {
 		return nil, err
 	}
 
+	if o.PerBindingUsers {
+		go mb.RunCredentialRotation(ctx, o.CredentialRotationMaxAge)
+	}
+	if o.ChartIndexRefreshInterval > 0 {
+		go helmClient.WatchIndex(ctx, o.ChartIndexRefreshInterval)
+	}
+
 	provisioningSettings := &ProvisioningSettings{}
 	if len(o.ProvisioningSettingsPath) > 0 {
 		data, err := ioutil.ReadFile(o.ProvisioningSettingsPath)
@@ -110,16 +174,49 @@ func NewBrokerFromOptions(o Options) (*Broker, error) {
 		}
 	}
 
-	return NewBroker(mb, o.DefaultNamespace, provisioningSettings), nil
+	var namespacePolicy *NamespacePolicy
+	if len(o.NamespacePolicyPath) > 0 {
+		data, err := ioutil.ReadFile(o.NamespacePolicyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+		settings, err := LoadNamespacePolicySettings(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+		namespacePolicy, err = NewNamespacePolicy(*settings, mb)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+	}
+
+	var namespaceTemplate *NamespaceTemplate
+	if o.NamespaceTemplate != "" {
+		if namespaceTemplate, err = NewNamespaceTemplate(o.NamespaceTemplate); err != nil {
+			return nil, fmt.Errorf("failed to initialize the broker: %w", err)
+		}
+	}
+
+	return NewBroker(mb, o.DefaultNamespace, provisioningSettings, namespacePolicy, namespaceTemplate), nil
 }
 
-// NewBroker creates a Broker instance with the given dependencies.
-func NewBroker(mb MinibrokerClient, defaultNamespace string, provisioningSettings *ProvisioningSettings) *Broker {
+// NewBroker creates a Broker instance with the given dependencies. A nil namespacePolicy allows
+// provisioning into any namespace. A nil namespaceTemplate provisions into the namespace of the
+// Kubernetes contexts, or the default namespace.
+func NewBroker(
+	mb MinibrokerClient,
+	defaultNamespace string,
+	provisioningSettings *ProvisioningSettings,
+	namespacePolicy *NamespacePolicy,
+	namespaceTemplate *NamespaceTemplate,
+) *Broker {
 	return &Broker{
 		client:               mb,
 		async:                true,
 		defaultNamespace:     defaultNamespace,
 		provisioningSettings: provisioningSettings,
+		namespacePolicy:      namespacePolicy,
+		namespaceTemplate:    namespaceTemplate,
 	}
 }
 
@@ -135,13 +232,24 @@ type Broker struct {
 	defaultNamespace string
 	// Provisioning settings.
 	provisioningSettings *ProvisioningSettings
+	// The restrictions on the namespaces and the number of instances, if any.
+	namespacePolicy *NamespacePolicy
+	// The mapping of the request contexts to namespaces, if any.
+	namespaceTemplate *NamespaceTemplate
 }
 
 var _ broker.Interface = &Broker{}
 
+// Client returns the client the broker operates on.
+func (b *Broker) Client() MinibrokerClient {
+	return b.client
+}
+
 func (b *Broker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse, error) {
-	klog.V(4).Infoln("broker: getting catalog")
-	services, err := b.client.ListServices()
+	ctx := requestContext(c)
+	logger := b.logger(ctx)
+	logger.V(4).Info("getting catalog")
+	services, err := b.client.ListServices(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -152,26 +260,74 @@ func (b *Broker) GetCatalog(c *broker.RequestContext) (*broker.CatalogResponse,
 		},
 	}
 
-	klog.V(4).Infoln("broker: got catalog")
+	logger.V(4).Info("got catalog")
 	return response, nil
 }
 
-func (b *Broker) Provision(request *osb.ProvisionRequest, _ *broker.RequestContext) (*broker.ProvisionResponse, error) {
+// namespace returns the namespace to provision into for the request context: the namespace rendered
+// by the template, if any, or the namespace of the Kubernetes contexts, or the default namespace.
+func (b *Broker) namespace(platformContext *PlatformContext) (string, error) {
+	if b.namespaceTemplate != nil {
+		namespace, err := b.namespaceTemplate.Namespace(platformContext)
+		if err != nil || namespace != "" {
+			return namespace, err
+		}
+	}
+	if platformContext.Namespace != "" {
+		return platformContext.Namespace, nil
+	}
+	return b.defaultNamespace, nil
+}
+
+// requestContext returns the context of the OSB request, carrying its span and correlation ID.
+func requestContext(c *broker.RequestContext) context.Context {
+	if c == nil || c.Request == nil {
+		return context.Background()
+	}
+	return c.Request.Context()
+}
+
+// logger returns the structured logger of the request context.
+func (b *Broker) logger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName("broker")
+}
+
+func (b *Broker) Provision(request *osb.ProvisionRequest, c *broker.RequestContext) (_ *broker.ProvisionResponse, err error) {
+	ctx, span := tracing.Start(requestContext(c), "broker.Provision",
+		attribute.String("instance.id", request.InstanceID),
+		attribute.String("service.id", request.ServiceID),
+		attribute.String("plan.id", request.PlanID),
+	)
+	defer func() { tracing.End(span, err) }()
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID)
+
 	b.Lock()
 	defer b.Unlock()
 
-	namespace := b.defaultNamespace
-	if request.Context["namespace"] != nil {
-		namespace = request.Context["namespace"].(string)
+	platformContext, err := ResolveContext(request.Context)
+	if err != nil {
+		logger.V(4).Info("failed to provision", "error", err)
+		return nil, err
+	}
+	identity, err := ResolveIdentity(request.OriginatingIdentity)
+	if err != nil {
+		logger.V(4).Info("failed to provision", "error", err)
+		return nil, err
+	}
+	namespace, err := b.namespace(platformContext)
+	if err != nil {
+		logger.V(4).Info("failed to provision", "error", err)
+		return nil, err
 	}
 
 	if namespace == "" {
-		klog.V(4).Infof("broker: failed to provision %q with empty namespace", request.InstanceID)
-		return nil, errors.New("Cannot provision with empty namespace")
+		logger.V(4).Info("failed to provision with empty namespace")
+		return nil, osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusBadRequest,
+			Description: strPtr("cannot provision with empty namespace"),
+		}
 	}
 
-	klog.V(4).Infof("broker: provisioning request %+v in namespace %q", request, namespace)
-
 	// Check if override parameters are defined for the service to be provisioned.
 	// If defined, those parameters will be used instead of what the user provided.
 	provisioningSettings, found := b.provisioningSettings.ForService(request.ServiceID)
@@ -182,16 +338,28 @@ func (b *Broker) Provision(request *osb.ProvisionRequest, _ *broker.RequestConte
 		params = request.Parameters
 	}
 
-	operationName, err := b.client.Provision(
-		request.InstanceID,
-		request.ServiceID,
-		request.PlanID,
-		namespace,
-		request.AcceptsIncomplete,
-		minibroker.NewProvisionParams(params),
-	)
+	provisionRequest := &minibroker.ProvisionRequest{
+		InstanceID: request.InstanceID,
+		ServiceID:  request.ServiceID,
+		PlanID:     request.PlanID,
+		Namespace:  namespace,
+		// The target cluster is optional; the plan or the broker's own cluster is used otherwise.
+		Cluster:           platformContext.Cluster,
+		Context:           platformContext.Raw,
+		Identity:          identity,
+		AcceptsIncomplete: request.AcceptsIncomplete,
+		Params:            minibroker.NewProvisionParams(params),
+	}
+	if err := b.checkPolicy(ctx, provisionRequest); err != nil {
+		logger.V(4).Info("rejected provisioning", "namespace", namespace, "identity", identity.String(), "error", err)
+		return nil, err
+	}
+
+	logger.V(4).Info("provisioning", "serviceID", request.ServiceID, "planID", request.PlanID, "namespace", namespace, "acceptsIncomplete", request.AcceptsIncomplete)
+
+	operationName, err := b.client.Provision(ctx, provisionRequest)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to provision request %q: %v", request.InstanceID, err)
+		logger.V(4).Info("failed to provision", "error", err)
 		return nil, err
 	}
 
@@ -202,19 +370,25 @@ func (b *Broker) Provision(request *osb.ProvisionRequest, _ *broker.RequestConte
 		response.OperationKey = &operationKey
 	}
 
-	klog.V(4).Infof("broker: provisioned %q in namespace %q", request.InstanceID, namespace)
+	logger.V(4).Info("provisioned", "namespace", namespace, "operation", operationName)
 	return &response, nil
 }
 
-func (b *Broker) Deprovision(request *osb.DeprovisionRequest, _ *broker.RequestContext) (*broker.DeprovisionResponse, error) {
-	klog.V(4).Infof("broker: deprovisioning request %+v", request)
+func (b *Broker) Deprovision(request *osb.DeprovisionRequest, c *broker.RequestContext) (_ *broker.DeprovisionResponse, err error) {
+	ctx, span := tracing.Start(requestContext(c), "broker.Deprovision",
+		attribute.String("instance.id", request.InstanceID),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID)
+	logger.V(4).Info("deprovisioning", "acceptsIncomplete", request.AcceptsIncomplete)
 
 	b.Lock()
 	defer b.Unlock()
 
-	operationName, err := b.client.Deprovision(request.InstanceID, request.AcceptsIncomplete)
+	operationName, err := b.client.Deprovision(ctx, request.InstanceID, request.AcceptsIncomplete)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to deprovision %q: %v", request.InstanceID, err)
+		logger.V(4).Info("failed to deprovision", "error", err)
 		return nil, err
 	}
 
@@ -225,44 +399,69 @@ func (b *Broker) Deprovision(request *osb.DeprovisionRequest, _ *broker.RequestC
 		response.OperationKey = &operationKey
 	}
 
-	klog.V(4).Infof("broker: deprovisioned %q", request.InstanceID)
+	logger.V(4).Info("deprovisioned", "operation", operationName)
 	return &response, nil
 }
 
 // LastOperation provides information on the state of the last asynchronous operation
-func (b *Broker) LastOperation(request *osb.LastOperationRequest, _ *broker.RequestContext) (*broker.LastOperationResponse, error) {
-	klog.V(4).Infof("broker: getting last operation request %+v", request)
+func (b *Broker) LastOperation(request *osb.LastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	ctx := requestContext(c)
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID)
+	logger.V(4).Info("getting last operation")
 
 	b.Lock()
 	defer b.Unlock()
 
-	response, err := b.client.LastOperationState(request.InstanceID, request.OperationKey)
+	response, err := b.client.LastOperationState(ctx, request.InstanceID, request.OperationKey)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to get last operation for instance %q: %v", request.InstanceID, err)
+		logger.V(4).Info("failed to get last operation", "error", err)
 		return nil, err
 	}
 
 	wrappedResponse := broker.LastOperationResponse{LastOperationResponse: *response}
 
-	klog.V(4).Infof("broker: got last operation for %q: %+v", request.InstanceID, response)
+	logger.V(4).Info("got last operation", "state", response.State)
 	return &wrappedResponse, nil
 }
 
-func (b *Broker) Bind(request *osb.BindRequest, _ *broker.RequestContext) (*broker.BindResponse, error) {
-	klog.V(4).Infof("broker: binding request %+v", request)
+func (b *Broker) Bind(request *osb.BindRequest, c *broker.RequestContext) (_ *broker.BindResponse, err error) {
+	ctx, span := tracing.Start(requestContext(c), "broker.Bind",
+		attribute.String("instance.id", request.InstanceID),
+		attribute.String("binding.id", request.BindingID),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID, "bindingID", request.BindingID)
+	logger.V(4).Info("binding", "serviceID", request.ServiceID, "planID", request.PlanID, "acceptsIncomplete", request.AcceptsIncomplete)
+
+	// The namespace of the consumer is only known on Kubernetes platforms; the client falls back to
+	// the release namespace otherwise.
+	platformContext, err := ResolveContext(request.Context)
+	if err != nil {
+		logger.V(4).Info("failed to bind", "error", err)
+		return nil, err
+	}
+	namespace := platformContext.Namespace
+	identity, err := ResolveIdentity(request.OriginatingIdentity)
+	if err != nil {
+		logger.V(4).Info("failed to bind", "error", err)
+		return nil, err
+	}
 
 	b.Lock()
 	defer b.Unlock()
 
-	operationName, err := b.client.Bind(
-		request.InstanceID,
-		request.ServiceID,
-		request.BindingID,
-		request.AcceptsIncomplete,
-		minibroker.NewBindParams(request.Parameters),
-	)
+	operationName, err := b.client.Bind(ctx, &minibroker.BindRequest{
+		InstanceID:        request.InstanceID,
+		ServiceID:         request.ServiceID,
+		BindingID:         request.BindingID,
+		Namespace:         namespace,
+		Identity:          identity,
+		AcceptsIncomplete: request.AcceptsIncomplete,
+		Params:            minibroker.NewBindParams(request.Parameters),
+	})
 	if err != nil {
-		klog.V(4).Infof("broker: failed to bind %q: %v", request.InstanceID, err)
+		logger.V(4).Info("failed to bind", "error", err)
 		return nil, err
 	}
 
@@ -279,18 +478,18 @@ func (b *Broker) Bind(request *osb.BindRequest, _ *broker.RequestContext) (*brok
 	}
 
 	// Get the response back out of the configmaps
-	operationState, err := b.client.LastBindingOperationState(request.InstanceID, request.BindingID)
+	operationState, err := b.client.LastBindingOperationState(ctx, request.InstanceID, request.BindingID)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to bind %q: %v", request.InstanceID, err)
+		logger.V(4).Info("failed to bind", "error", err)
 		return nil, err
 	}
 	if operationState.State != osb.StateSucceeded {
-		klog.V(4).Infof("broker: failed to bind instance %q: state is %q", request.InstanceID, operationState.State)
+		logger.V(4).Info("failed to bind", "state", operationState.State)
 		return nil, errors.New("Failed to bind instance")
 	}
-	binding, err := b.client.GetBinding(request.InstanceID, request.BindingID)
+	binding, err := b.client.GetBinding(ctx, request.InstanceID, request.BindingID)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to bind %q: %v", request.InstanceID, err)
+		logger.V(4).Info("failed to bind", "error", err)
 		return nil, err
 	}
 
@@ -303,56 +502,66 @@ func (b *Broker) Bind(request *osb.BindRequest, _ *broker.RequestContext) (*brok
 		},
 	}
 
-	klog.V(4).Infof("broker: bound %q", request.InstanceID)
+	logger.V(4).Info("bound")
 
 	return &bindResponse, nil
 }
 
-func (b *Broker) GetBinding(request *osb.GetBindingRequest, _ *broker.RequestContext) (*broker.GetBindingResponse, error) {
-	klog.V(4).Infof("broker: getting binding request %+v", request)
+func (b *Broker) GetBinding(request *osb.GetBindingRequest, c *broker.RequestContext) (*broker.GetBindingResponse, error) {
+	ctx := requestContext(c)
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID, "bindingID", request.BindingID)
+	logger.V(4).Info("getting binding")
 
-	binding, err := b.client.GetBinding(request.InstanceID, request.BindingID)
+	binding, err := b.client.GetBinding(ctx, request.InstanceID, request.BindingID)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to get binding %q for instance %q: %v", request.BindingID, request.InstanceID, err)
+		logger.V(4).Info("failed to get binding", "error", err)
 		return nil, err
 	}
 	response := broker.GetBindingResponse{
 		GetBindingResponse: *binding,
 	}
 
-	klog.V(4).Infof("broker: got binding %q", request.BindingID)
+	logger.V(4).Info("got binding")
 
 	return &response, nil
 }
 
-func (b *Broker) BindingLastOperation(request *osb.BindingLastOperationRequest, _ *broker.RequestContext) (*broker.LastOperationResponse, error) {
-	klog.V(4).Infof("broker: getting binding last operation request %+v", request)
+func (b *Broker) BindingLastOperation(request *osb.BindingLastOperationRequest, c *broker.RequestContext) (*broker.LastOperationResponse, error) {
+	ctx := requestContext(c)
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID, "bindingID", request.BindingID)
+	logger.V(4).Info("getting binding last operation")
 
-	state, err := b.client.LastBindingOperationState(request.InstanceID, request.BindingID)
+	state, err := b.client.LastBindingOperationState(ctx, request.InstanceID, request.BindingID)
 	if err != nil {
-		klog.V(4).Infof("broker: failed to get binding %q last operation for instance %q: %v", request.BindingID, request.InstanceID, err)
+		logger.V(4).Info("failed to get binding last operation", "error", err)
 		return nil, err
 	}
 
 	response := broker.LastOperationResponse{LastOperationResponse: *state}
 
-	klog.V(4).Infof("broker: got last binding operation for %q: %+v", request.InstanceID, *state)
+	logger.V(4).Info("got binding last operation", "state", state.State)
 
 	return &response, nil
 }
 
-func (b *Broker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (*broker.UnbindResponse, error) {
-	klog.V(4).Infof("broker: unbinding request %+v", request)
+func (b *Broker) Unbind(request *osb.UnbindRequest, c *broker.RequestContext) (_ *broker.UnbindResponse, err error) {
+	ctx, span := tracing.Start(requestContext(c), "broker.Unbind",
+		attribute.String("instance.id", request.InstanceID),
+		attribute.String("binding.id", request.BindingID),
+	)
+	defer func() { tracing.End(span, err) }()
+	logger := b.logger(ctx).WithValues("instanceID", request.InstanceID, "bindingID", request.BindingID)
+	logger.V(4).Info("unbinding")
 
-	if err := b.client.Unbind(request.InstanceID, request.BindingID); err != nil {
-		klog.V(4).Infof("broker: failed to unbind instance %q: %v", request.InstanceID, err)
+	if err := b.client.Unbind(ctx, request.InstanceID, request.BindingID); err != nil {
+		logger.V(4).Info("failed to unbind", "error", err)
 		return nil, err
 	}
 
 	// The unbind is always synchronous
 	response := broker.UnbindResponse{}
 
-	klog.V(4).Infof("broker: unbound %q", request.InstanceID)
+	logger.V(4).Info("unbound")
 
 	return &response, nil
 }
@@ -367,7 +576,3 @@ func (b *Broker) Update(request *osb.UpdateInstanceRequest, _ *broker.RequestCon
 
 	return &response, nil
 }
-
-func (b *Broker) ValidateBrokerAPIVersion(version string) error {
-	return nil
-}
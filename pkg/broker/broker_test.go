@@ -72,7 +72,7 @@ var _ = Describe("Broker", func() {
 	})
 
 	JustBeforeEach(func() {
-		b = broker.NewBroker(mbclient, namespace, provisioningSettings)
+		b = broker.NewBroker(mbclient, namespace, provisioningSettings, nil, nil)
 	})
 
 	AfterEach(func() {
@@ -94,12 +94,33 @@ var _ = Describe("Broker", func() {
 		Context("without default chart values", func() {
 			It("passes on unaltered provision params", func() {
 				mbclient.EXPECT().
-					Provision(gomock.Any(), gomock.Eq("redis"), gomock.Any(), gomock.Eq(namespace), gomock.Any(), gomock.Eq(provisionParams))
+					Provision(gomock.Any(), gomock.Eq(&minibroker.ProvisionRequest{
+						ServiceID: "redis",
+						Namespace: namespace,
+						Params:    provisionParams,
+					}))
 
 				b.Provision(provisionRequest, requestContext)
 			})
 		})
 
+		Context("with a target cluster", func() {
+			It("passes on the cluster from the request context", func() {
+				request := *provisionRequest
+				request.Context = map[string]interface{}{"cluster": "east"}
+				mbclient.EXPECT().
+					Provision(gomock.Any(), gomock.Eq(&minibroker.ProvisionRequest{
+						ServiceID: "redis",
+						Namespace: namespace,
+						Cluster:   "east",
+						Context:   request.Context,
+						Params:    provisionParams,
+					}))
+
+				b.Provision(&request, requestContext)
+			})
+		})
+
 		Context("with default chart values", func() {
 			BeforeEach(func() {
 				provisioningSettings = &broker.ProvisioningSettings{}
@@ -117,7 +138,11 @@ var _ = Describe("Broker", func() {
 					params := minibroker.NewProvisionParams(provisioningSettings.OverrideParams)
 
 					mbclient.EXPECT().
-						Provision(gomock.Any(), gomock.Eq(service), gomock.Any(), gomock.Eq(namespace), gomock.Any(), gomock.Eq(params))
+						Provision(gomock.Any(), gomock.Eq(&minibroker.ProvisionRequest{
+							ServiceID: service,
+							Namespace: namespace,
+							Params:    params,
+						}))
 
 					b.Provision(provisionRequest, requestContext)
 				}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// The platforms with a well-known OSB context profile.
+const (
+	PlatformKubernetes   = "kubernetes"
+	PlatformCloudFoundry = "cloudfoundry"
+)
+
+// PlatformContext is the OSB context of a request, resolved following the profile of its platform.
+// See https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md#context-object.
+type PlatformContext struct {
+	// The platform sending the request, e.g. kubernetes or cloudfoundry.
+	Platform string
+	// The namespace of the request on Kubernetes.
+	Namespace string
+	// The ID of the Kubernetes cluster sending the request.
+	ClusterID string
+	// The organization and space of the request on Cloud Foundry.
+	OrganizationGUID string
+	OrganizationName string
+	SpaceGUID        string
+	SpaceName        string
+	// The name of the service instance on the platform.
+	InstanceName string
+	// The target cluster requested for the instance, see minibroker.ClusterConfig.
+	Cluster string
+	// Raw is the complete context, including the fields not known by the profiles.
+	Raw map[string]interface{}
+}
+
+// ResolveContext resolves the OSB context of a request. A context without a platform but with a
+// namespace, as sent by older platforms, is resolved as a Kubernetes context. An OSB 400 error is
+// returned for fields of unexpected types.
+func ResolveContext(context map[string]interface{}) (*PlatformContext, error) {
+	pc := &PlatformContext{Raw: context}
+	fields := []struct {
+		key   string
+		value *string
+	}{
+		{"platform", &pc.Platform},
+		{"namespace", &pc.Namespace},
+		{"clusterid", &pc.ClusterID},
+		{"organization_guid", &pc.OrganizationGUID},
+		{"organization_name", &pc.OrganizationName},
+		{"space_guid", &pc.SpaceGUID},
+		{"space_name", &pc.SpaceName},
+		{"instance_name", &pc.InstanceName},
+		{"cluster", &pc.Cluster},
+	}
+	for _, field := range fields {
+		raw, ok := context[field.key]
+		if !ok || raw == nil {
+			continue
+		}
+		value, ok := raw.(string)
+		if !ok {
+			return nil, osb.HTTPStatusCodeError{
+				StatusCode:  http.StatusBadRequest,
+				Description: strPtr(fmt.Sprintf("invalid context: %q must be a string", field.key)),
+			}
+		}
+		*field.value = value
+	}
+
+	if pc.Platform == "" && pc.Namespace != "" {
+		pc.Platform = PlatformKubernetes
+	}
+	// The namespace is only meaningful on Kubernetes.
+	if pc.Platform != PlatformKubernetes {
+		pc.Namespace = ""
+	}
+	return pc, nil
+}
+
+// NamespaceTemplate maps the resolved OSB contexts to the namespaces the instances are provisioned
+// into, e.g. "cf-{{ .SpaceGUID }}" or "{{ .Namespace }}".
+type NamespaceTemplate struct {
+	template *template.Template
+}
+
+// NewNamespaceTemplate parses a namespace template. The template executes against a
+// PlatformContext; referencing unknown fields or missing keys of the raw context fails the request.
+func NewNamespaceTemplate(text string) (*NamespaceTemplate, error) {
+	tmpl, err := template.New("namespace").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the namespace template: %w", err)
+	}
+	return &NamespaceTemplate{template: tmpl}, nil
+}
+
+// Namespace renders the namespace for the context. An empty result means the default namespace
+// should be used. An OSB 400 error is returned when the result is not a valid namespace name.
+func (nt *NamespaceTemplate) Namespace(pc *PlatformContext) (string, error) {
+	var buf bytes.Buffer
+	if err := nt.template.Execute(&buf, pc); err != nil {
+		return "", osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusBadRequest,
+			Description: strPtr(fmt.Sprintf("could not map the context to a namespace: %v", err)),
+		}
+	}
+	namespace := strings.TrimSpace(buf.String())
+	if namespace == "" {
+		return "", nil
+	}
+	if errs := validation.IsDNS1123Label(namespace); len(errs) > 0 {
+		return "", osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusBadRequest,
+			Description: strPtr(fmt.Sprintf("the context maps to an invalid namespace %q: %s", namespace, strings.Join(errs, ", "))),
+		}
+	}
+	return namespace, nil
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker_test
+
+import (
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	osbbroker "github.com/pmorie/osb-broker-lib/pkg/broker"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/broker/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+)
+
+var _ = Describe("Context", func() {
+	cloudFoundryContext := map[string]interface{}{
+		"platform":          "cloudfoundry",
+		"organization_guid": "org-guid",
+		"organization_name": "org",
+		"space_guid":        "space-guid",
+		"space_name":        "space",
+		"instance_name":     "db",
+	}
+
+	Describe("ResolveContext", func() {
+		It("resolves Kubernetes contexts", func() {
+			pc, err := broker.ResolveContext(map[string]interface{}{
+				"platform":  "kubernetes",
+				"namespace": "team-a",
+				"clusterid": "cluster-id",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.Platform).To(Equal(broker.PlatformKubernetes))
+			Expect(pc.Namespace).To(Equal("team-a"))
+			Expect(pc.ClusterID).To(Equal("cluster-id"))
+		})
+
+		It("resolves contexts with a namespace and no platform as Kubernetes contexts", func() {
+			pc, err := broker.ResolveContext(map[string]interface{}{"namespace": "team-a"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.Platform).To(Equal(broker.PlatformKubernetes))
+			Expect(pc.Namespace).To(Equal("team-a"))
+		})
+
+		It("resolves Cloud Foundry contexts", func() {
+			pc, err := broker.ResolveContext(cloudFoundryContext)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pc.Platform).To(Equal(broker.PlatformCloudFoundry))
+			Expect(pc.OrganizationGUID).To(Equal("org-guid"))
+			Expect(pc.OrganizationName).To(Equal("org"))
+			Expect(pc.SpaceGUID).To(Equal("space-guid"))
+			Expect(pc.SpaceName).To(Equal("space"))
+			Expect(pc.InstanceName).To(Equal("db"))
+			Expect(pc.Namespace).To(BeEmpty())
+			Expect(pc.Raw).To(Equal(cloudFoundryContext))
+		})
+
+		It("rejects malformed contexts", func() {
+			_, err := broker.ResolveContext(map[string]interface{}{"namespace": 42})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(osb.HTTPStatusCodeError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("NamespaceTemplate", func() {
+		It("renders the namespace", func() {
+			tmpl, err := broker.NewNamespaceTemplate(`{{ if eq .Platform "cloudfoundry" }}cf-{{ .SpaceGUID }}{{ end }}`)
+			Expect(err).ToNot(HaveOccurred())
+
+			pc, _ := broker.ResolveContext(cloudFoundryContext)
+			Expect(tmpl.Namespace(pc)).To(Equal("cf-space-guid"))
+
+			pc, _ = broker.ResolveContext(map[string]interface{}{"namespace": "team-a"})
+			Expect(tmpl.Namespace(pc)).To(BeEmpty())
+		})
+
+		It("rejects invalid namespaces", func() {
+			tmpl, err := broker.NewNamespaceTemplate(`{{ .SpaceName }}_space`)
+			Expect(err).ToNot(HaveOccurred())
+
+			pc, _ := broker.ResolveContext(cloudFoundryContext)
+			_, err = tmpl.Namespace(pc)
+			Expect(err).To(HaveOccurred())
+			Expect(err.(osb.HTTPStatusCodeError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("fails on invalid templates", func() {
+			_, err := broker.NewNamespaceTemplate(`{{ .SpaceName`)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Broker.Provision", func() {
+		var (
+			ctrl     *gomock.Controller
+			mbclient *mocks.MockMinibrokerClient
+		)
+
+		BeforeEach(func() {
+			ctrl = gomock.NewController(GinkgoT())
+			mbclient = mocks.NewMockMinibrokerClient(ctrl)
+		})
+
+		AfterEach(func() {
+			ctrl.Finish()
+		})
+
+		It("rejects malformed contexts without panicking", func() {
+			b := broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, nil, nil)
+			request := &osb.ProvisionRequest{
+				ServiceID: "redis",
+				Context:   map[string]interface{}{"namespace": []string{"team-a"}},
+			}
+
+			_, err := b.Provision(request, &osbbroker.RequestContext{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(osb.HTTPStatusCodeError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+
+		It("provisions Cloud Foundry instances into the templated namespace and records the context", func() {
+			tmpl, err := broker.NewNamespaceTemplate(`cf-{{ .SpaceGUID }}`)
+			Expect(err).ToNot(HaveOccurred())
+			b := broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, nil, tmpl)
+			mbclient.EXPECT().
+				Provision(gomock.Any(), gomock.Eq(&minibroker.ProvisionRequest{
+					ServiceID: "redis",
+					Namespace: "cf-space-guid",
+					Context:   cloudFoundryContext,
+					Params:    minibroker.NewProvisionParams(nil),
+				}))
+
+			_, err = b.Provision(&osb.ProvisionRequest{ServiceID: "redis", Context: cloudFoundryContext}, &osbbroker.RequestContext{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("provisions Cloud Foundry instances into the default namespace without a template", func() {
+			b := broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, nil, nil)
+			mbclient.EXPECT().
+				Provision(gomock.Any(), gomock.Eq(&minibroker.ProvisionRequest{
+					ServiceID: "redis",
+					Namespace: "default",
+					Context:   cloudFoundryContext,
+					Params:    minibroker.NewProvisionParams(nil),
+				}))
+
+			_, err := b.Provision(&osb.ProvisionRequest{ServiceID: "redis", Context: cloudFoundryContext}, &osbbroker.RequestContext{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
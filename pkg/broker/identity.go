@@ -0,0 +1,77 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+)
+
+// ResolveIdentity decodes the originating identity of a request following the profile of its
+// platform. See
+// https://github.com/openservicebrokerapi/servicebroker/blob/master/profile.md#originating-identity-header.
+// A nil identity is returned when the request has none, and an OSB 400 error when its value is not
+// a JSON object.
+func ResolveIdentity(originatingIdentity *osb.OriginatingIdentity) (*minibroker.Identity, error) {
+	if originatingIdentity == nil {
+		return nil, nil
+	}
+	identity := &minibroker.Identity{
+		Platform: originatingIdentity.Platform,
+		Value:    json.RawMessage(originatingIdentity.Value),
+	}
+
+	var value struct {
+		// Kubernetes.
+		Username string              `json:"username"`
+		UID      string              `json:"uid"`
+		Groups   []string            `json:"groups"`
+		Extra    map[string][]string `json:"extra"`
+		// Cloud Foundry.
+		UserID string `json:"user_id"`
+	}
+	var err error
+	switch identity.Platform {
+	case PlatformKubernetes, PlatformCloudFoundry:
+		err = json.Unmarshal([]byte(originatingIdentity.Value), &value)
+	default:
+		var object map[string]interface{}
+		err = json.Unmarshal([]byte(originatingIdentity.Value), &object)
+	}
+	if err != nil {
+		return nil, osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusBadRequest,
+			Description: strPtr(fmt.Sprintf("invalid %s header: %v", osb.OriginatingIdentityHeader, err)),
+		}
+	}
+
+	switch identity.Platform {
+	case PlatformKubernetes:
+		identity.Username = value.Username
+		identity.UID = value.UID
+		identity.Groups = value.Groups
+		identity.Extra = value.Extra
+	case PlatformCloudFoundry:
+		identity.UserID = value.UserID
+	}
+	return identity, nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker_test
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	osbbroker "github.com/pmorie/osb-broker-lib/pkg/broker"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/broker/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+)
+
+var _ = Describe("Identity", func() {
+	kubernetesIdentity := &osb.OriginatingIdentity{
+		Platform: "kubernetes",
+		Value:    `{"username":"jane","uid":"1234","groups":["admins"],"extra":{"scopes":["all"]}}`,
+	}
+
+	Describe("ResolveIdentity", func() {
+		It("returns nil without an identity", func() {
+			Expect(broker.ResolveIdentity(nil)).To(BeNil())
+		})
+
+		It("decodes Kubernetes identities", func() {
+			identity, err := broker.ResolveIdentity(kubernetesIdentity)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(identity.Platform).To(Equal(broker.PlatformKubernetes))
+			Expect(identity.Username).To(Equal("jane"))
+			Expect(identity.UID).To(Equal("1234"))
+			Expect(identity.Groups).To(Equal([]string{"admins"}))
+			Expect(identity.Extra).To(Equal(map[string][]string{"scopes": {"all"}}))
+			Expect(identity.String()).To(Equal("kubernetes:jane"))
+		})
+
+		It("decodes Cloud Foundry identities", func() {
+			identity, err := broker.ResolveIdentity(&osb.OriginatingIdentity{
+				Platform: "cloudfoundry",
+				Value:    `{"user_id":"683ea748-3092-4ff4-b656-39cacc4d5360"}`,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(identity.UserID).To(Equal("683ea748-3092-4ff4-b656-39cacc4d5360"))
+			Expect(identity.String()).To(Equal("cloudfoundry:683ea748-3092-4ff4-b656-39cacc4d5360"))
+		})
+
+		It("keeps the value of the other platforms", func() {
+			identity, err := broker.ResolveIdentity(&osb.OriginatingIdentity{
+				Platform: "other",
+				Value:    `{"user":"x"}`,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(identity.Platform).To(Equal("other"))
+			Expect(identity.Value).To(Equal(json.RawMessage(`{"user":"x"}`)))
+		})
+
+		It("rejects malformed values", func() {
+			_, err := broker.ResolveIdentity(&osb.OriginatingIdentity{Platform: "kubernetes", Value: "jane"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.(osb.HTTPStatusCodeError).StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("Broker", func() {
+		var (
+			ctrl     *gomock.Controller
+			mbclient *mocks.MockMinibrokerClient
+			b        *broker.Broker
+			identity *minibroker.Identity
+		)
+
+		BeforeEach(func() {
+			ctrl = gomock.NewController(GinkgoT())
+			mbclient = mocks.NewMockMinibrokerClient(ctrl)
+			b = broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, nil, nil)
+			identity, _ = broker.ResolveIdentity(kubernetesIdentity)
+		})
+
+		AfterEach(func() {
+			ctrl.Finish()
+		})
+
+		It("records the identity on provisioning", func() {
+			mbclient.EXPECT().
+				Provision(gomock.Any(), gomock.Eq(&minibroker.ProvisionRequest{
+					ServiceID: "redis",
+					Namespace: "default",
+					Identity:  identity,
+					Params:    minibroker.NewProvisionParams(nil),
+				}))
+
+			_, err := b.Provision(&osb.ProvisionRequest{
+				ServiceID:           "redis",
+				OriginatingIdentity: kubernetesIdentity,
+			}, &osbbroker.RequestContext{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("records the identity on binding", func() {
+			mbclient.EXPECT().
+				Bind(gomock.Any(), gomock.Eq(&minibroker.BindRequest{
+					InstanceID:        "instance",
+					ServiceID:         "redis",
+					BindingID:         "binding",
+					Identity:          identity,
+					AcceptsIncomplete: true,
+					Params:            minibroker.NewBindParams(nil),
+				}))
+
+			_, err := b.Bind(&osb.BindRequest{
+				InstanceID:          "instance",
+				ServiceID:           "redis",
+				BindingID:           "binding",
+				AcceptsIncomplete:   true,
+				OriginatingIdentity: kubernetesIdentity,
+			}, &osbbroker.RequestContext{})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+})
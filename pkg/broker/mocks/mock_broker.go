@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,48 +37,63 @@ func (m *MockMinibrokerClient) EXPECT() *MockMinibrokerClientMockRecorder {
 }
 
 // Bind mocks base method.
-func (m *MockMinibrokerClient) Bind(arg0, arg1, arg2 string, arg3 bool, arg4 *minibroker.BindParams) (string, error) {
+func (m *MockMinibrokerClient) Bind(arg0 context.Context, arg1 *minibroker.BindRequest) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Bind", arg0, arg1, arg2, arg3, arg4)
+	ret := m.ctrl.Call(m, "Bind", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Bind indicates an expected call of Bind.
-func (mr *MockMinibrokerClientMockRecorder) Bind(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) Bind(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockMinibrokerClient)(nil).Bind), arg0, arg1, arg2, arg3, arg4)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bind", reflect.TypeOf((*MockMinibrokerClient)(nil).Bind), arg0, arg1)
 }
 
 // Deprovision mocks base method.
-func (m *MockMinibrokerClient) Deprovision(arg0 string, arg1 bool) (string, error) {
+func (m *MockMinibrokerClient) Deprovision(arg0 context.Context, arg1 string, arg2 bool) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Deprovision", arg0, arg1)
+	ret := m.ctrl.Call(m, "Deprovision", arg0, arg1, arg2)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Deprovision indicates an expected call of Deprovision.
-func (mr *MockMinibrokerClientMockRecorder) Deprovision(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) Deprovision(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deprovision", reflect.TypeOf((*MockMinibrokerClient)(nil).Deprovision), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Deprovision", reflect.TypeOf((*MockMinibrokerClient)(nil).Deprovision), arg0, arg1, arg2)
+}
+
+// FetchInstance mocks base method.
+func (m *MockMinibrokerClient) FetchInstance(arg0 context.Context, arg1 string) (*minibroker.FetchedInstance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FetchInstance", arg0, arg1)
+	ret0, _ := ret[0].(*minibroker.FetchedInstance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FetchInstance indicates an expected call of FetchInstance.
+func (mr *MockMinibrokerClientMockRecorder) FetchInstance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FetchInstance", reflect.TypeOf((*MockMinibrokerClient)(nil).FetchInstance), arg0, arg1)
 }
 
 // GetBinding mocks base method.
-func (m *MockMinibrokerClient) GetBinding(arg0, arg1 string) (*v2.GetBindingResponse, error) {
+func (m *MockMinibrokerClient) GetBinding(arg0 context.Context, arg1, arg2 string) (*v2.GetBindingResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetBinding", arg0, arg1)
+	ret := m.ctrl.Call(m, "GetBinding", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*v2.GetBindingResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetBinding indicates an expected call of GetBinding.
-func (mr *MockMinibrokerClientMockRecorder) GetBinding(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) GetBinding(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBinding", reflect.TypeOf((*MockMinibrokerClient)(nil).GetBinding), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBinding", reflect.TypeOf((*MockMinibrokerClient)(nil).GetBinding), arg0, arg1, arg2)
 }
 
 // Init mocks base method.
@@ -95,75 +111,90 @@ func (mr *MockMinibrokerClientMockRecorder) Init(arg0 interface{}) *gomock.Call
 }
 
 // LastBindingOperationState mocks base method.
-func (m *MockMinibrokerClient) LastBindingOperationState(arg0, arg1 string) (*v2.LastOperationResponse, error) {
+func (m *MockMinibrokerClient) LastBindingOperationState(arg0 context.Context, arg1, arg2 string) (*v2.LastOperationResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LastBindingOperationState", arg0, arg1)
+	ret := m.ctrl.Call(m, "LastBindingOperationState", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*v2.LastOperationResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // LastBindingOperationState indicates an expected call of LastBindingOperationState.
-func (mr *MockMinibrokerClientMockRecorder) LastBindingOperationState(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) LastBindingOperationState(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastBindingOperationState", reflect.TypeOf((*MockMinibrokerClient)(nil).LastBindingOperationState), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastBindingOperationState", reflect.TypeOf((*MockMinibrokerClient)(nil).LastBindingOperationState), arg0, arg1, arg2)
 }
 
 // LastOperationState mocks base method.
-func (m *MockMinibrokerClient) LastOperationState(arg0 string, arg1 *v2.OperationKey) (*v2.LastOperationResponse, error) {
+func (m *MockMinibrokerClient) LastOperationState(arg0 context.Context, arg1 string, arg2 *v2.OperationKey) (*v2.LastOperationResponse, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LastOperationState", arg0, arg1)
+	ret := m.ctrl.Call(m, "LastOperationState", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*v2.LastOperationResponse)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // LastOperationState indicates an expected call of LastOperationState.
-func (mr *MockMinibrokerClientMockRecorder) LastOperationState(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) LastOperationState(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastOperationState", reflect.TypeOf((*MockMinibrokerClient)(nil).LastOperationState), arg0, arg1, arg2)
+}
+
+// ListInstances mocks base method.
+func (m *MockMinibrokerClient) ListInstances(arg0 context.Context) ([]minibroker.InstanceSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInstances", arg0)
+	ret0, _ := ret[0].([]minibroker.InstanceSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInstances indicates an expected call of ListInstances.
+func (mr *MockMinibrokerClientMockRecorder) ListInstances(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LastOperationState", reflect.TypeOf((*MockMinibrokerClient)(nil).LastOperationState), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInstances", reflect.TypeOf((*MockMinibrokerClient)(nil).ListInstances), arg0)
 }
 
 // ListServices mocks base method.
-func (m *MockMinibrokerClient) ListServices() ([]v2.Service, error) {
+func (m *MockMinibrokerClient) ListServices(arg0 context.Context) ([]v2.Service, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListServices")
+	ret := m.ctrl.Call(m, "ListServices", arg0)
 	ret0, _ := ret[0].([]v2.Service)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // ListServices indicates an expected call of ListServices.
-func (mr *MockMinibrokerClientMockRecorder) ListServices() *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) ListServices(arg0 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServices", reflect.TypeOf((*MockMinibrokerClient)(nil).ListServices))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListServices", reflect.TypeOf((*MockMinibrokerClient)(nil).ListServices), arg0)
 }
 
 // Provision mocks base method.
-func (m *MockMinibrokerClient) Provision(arg0, arg1, arg2, arg3 string, arg4 bool, arg5 *minibroker.ProvisionParams) (string, error) {
+func (m *MockMinibrokerClient) Provision(arg0 context.Context, arg1 *minibroker.ProvisionRequest) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Provision", arg0, arg1, arg2, arg3, arg4, arg5)
+	ret := m.ctrl.Call(m, "Provision", arg0, arg1)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Provision indicates an expected call of Provision.
-func (mr *MockMinibrokerClientMockRecorder) Provision(arg0, arg1, arg2, arg3, arg4, arg5 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) Provision(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Provision", reflect.TypeOf((*MockMinibrokerClient)(nil).Provision), arg0, arg1, arg2, arg3, arg4, arg5)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Provision", reflect.TypeOf((*MockMinibrokerClient)(nil).Provision), arg0, arg1)
 }
 
 // Unbind mocks base method.
-func (m *MockMinibrokerClient) Unbind(arg0, arg1 string) error {
+func (m *MockMinibrokerClient) Unbind(arg0 context.Context, arg1, arg2 string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Unbind", arg0, arg1)
+	ret := m.ctrl.Call(m, "Unbind", arg0, arg1, arg2)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Unbind indicates an expected call of Unbind.
-func (mr *MockMinibrokerClientMockRecorder) Unbind(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockMinibrokerClientMockRecorder) Unbind(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockMinibrokerClient)(nil).Unbind), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unbind", reflect.TypeOf((*MockMinibrokerClient)(nil).Unbind), arg0, arg1, arg2)
 }
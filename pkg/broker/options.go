@@ -16,6 +16,8 @@ limitations under the License.
 
 package broker
 
+import "time"
+
 type Options struct {
 	HelmRepoURL string
 	CatalogPath string
@@ -29,4 +31,41 @@ type Options struct {
 	// The k8s cluster domain. If not set via the CLI flags, Minibroker tries to
 	// infer from the /etc/resolv.conf.
 	ClusterDomain string
+	// Whether Minibroker creates a dedicated database user for each binding of the services
+	// supporting it.
+	PerBindingUsers bool
+	// How long the credentials replaced by a rotation remain valid.
+	CredentialRotationGracePeriod time.Duration
+	// The age after which the binding credentials are rotated automatically. Zero disables the
+	// scheduled rotation.
+	CredentialRotationMaxAge time.Duration
+	// Whether the binding credentials are resolved from the current services and secrets on every
+	// fetch instead of returning the snapshot stored at bind time.
+	LiveCredentials bool
+	// Whether all the chart secret keys are included in the binding credentials, instead of only
+	// the keys allowed by the service providers.
+	SecretKeysPassthrough bool
+	// The chart secret keys included in the binding credentials of the charts without a service
+	// provider.
+	GenericSecretKeys []string
+	// Whether the binding credentials are projected into Secrets following the Service Binding for
+	// Kubernetes specification.
+	ProjectBindingSecrets bool
+	// The kubeconfig file used to reach the cluster. When empty along with KubeContext, the
+	// in-cluster configuration is used.
+	KubeConfig string
+	// The kubeconfig context used to reach the cluster. When empty, the current context is used.
+	KubeContext string
+	// The YAML file describing the target clusters releases can be installed into.
+	ClustersConfigPath string
+	// Whether each instance is installed into a generated namespace.
+	NamespacePerInstance bool
+	// The YAML file with the ResourceQuota and LimitRange templates of the generated namespaces.
+	IsolationConfigPath string
+	// The YAML file with the namespace allow and deny lists and the instance quotas.
+	NamespacePolicyPath string
+	// The template mapping the OSB contexts to the namespaces instances are provisioned into.
+	NamespaceTemplate string
+	// How often the chart repository index is reloaded. Zero disables the reload.
+	ChartIndexRefreshInterval time.Duration
 }
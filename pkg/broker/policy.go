@@ -0,0 +1,290 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ghodss/yaml"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// OSB error codes of the provisioning requests rejected by the namespace policy.
+const (
+	NamespaceNotAllowedError = "NamespaceNotAllowed"
+	QuotaExceededError       = "QuotaExceeded"
+)
+
+// AnyNamespace is the key of the identity rule of the namespaces without a rule of their own.
+const AnyNamespace = "*"
+
+// NamespacePolicySettings restricts the namespaces instances are provisioned into and the number of
+// instances per namespace.
+type NamespacePolicySettings struct {
+	// The namespaces instances may be provisioned into. All namespaces are allowed when empty.
+	AllowedNamespaces []string `json:"allowedNamespaces"`
+	// The namespaces instances may not be provisioned into.
+	DeniedNamespaces []string `json:"deniedNamespaces"`
+	// A label selector the namespaces must match, e.g. "minibroker.io/enabled=true".
+	NamespaceSelector string `json:"namespaceSelector"`
+	// A label selector the namespaces must not match.
+	DeniedNamespaceSelector string `json:"deniedNamespaceSelector"`
+	// The originating identities allowed to provision into specific namespaces, keyed by namespace,
+	// with "*" applying to the namespaces without a rule. All identities are allowed when empty.
+	NamespaceIdentities map[string]IdentityRule `json:"namespaceIdentities"`
+	// The instance count quotas.
+	Quotas InstanceQuotas `json:"quotas"`
+}
+
+// IdentityRule lists the originating identities allowed by a namespace policy rule. Kubernetes
+// identities match by user name or group, and Cloud Foundry identities by user ID.
+type IdentityRule struct {
+	Users  []string `json:"users"`
+	Groups []string `json:"groups"`
+}
+
+// allows returns whether the rule allows the identity.
+func (r IdentityRule) allows(identity *minibroker.Identity) bool {
+	if identity == nil {
+		return false
+	}
+	users := stringSet(r.Users)
+	if (identity.Username != "" && users[identity.Username]) || (identity.UserID != "" && users[identity.UserID]) {
+		return true
+	}
+	groups := stringSet(r.Groups)
+	for _, group := range identity.Groups {
+		if groups[group] {
+			return true
+		}
+	}
+	return false
+}
+
+// InstanceQuotas are the maximum numbers of instances in a namespace. Zero means unlimited.
+type InstanceQuotas struct {
+	// The maximum number of instances in any namespace.
+	MaxInstancesPerNamespace int `json:"maxInstancesPerNamespace"`
+	// The maximum number of instances of specific namespaces, overriding MaxInstancesPerNamespace.
+	Namespaces map[string]int `json:"namespaces"`
+	// The maximum number of instances of specific services in any namespace.
+	Services map[string]int `json:"services"`
+}
+
+// NamespaceGetter gets the namespaces of the cluster the instances are provisioned into for
+// matching their labels.
+type NamespaceGetter interface {
+	GetTargetNamespace(ctx context.Context, planID, cluster, name string) (*corev1.Namespace, error)
+}
+
+// InstanceLister lists the existing instances for enforcing the quotas.
+type InstanceLister interface {
+	ListInstances(ctx context.Context) ([]minibroker.InstanceSummary, error)
+}
+
+// NamespacePolicy enforces the NamespacePolicySettings on the provisioning requests.
+type NamespacePolicy struct {
+	settings        NamespacePolicySettings
+	allowed         map[string]bool
+	denied          map[string]bool
+	selector        labels.Selector
+	deniedSelector  labels.Selector
+	namespaceGetter NamespaceGetter
+}
+
+// LoadNamespacePolicySettings parses the namespace policy settings from raw YAML.
+func LoadNamespacePolicySettings(data []byte) (*NamespacePolicySettings, error) {
+	settings := &NamespacePolicySettings{}
+	if err := yaml.UnmarshalStrict(data, settings, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("failed to load the namespace policy: %w", err)
+	}
+	return settings, nil
+}
+
+// NewNamespacePolicy creates a new NamespacePolicy. The namespaceGetter is only used when a label
+// selector is set.
+func NewNamespacePolicy(settings NamespacePolicySettings, namespaceGetter NamespaceGetter) (*NamespacePolicy, error) {
+	p := &NamespacePolicy{
+		settings:        settings,
+		allowed:         stringSet(settings.AllowedNamespaces),
+		denied:          stringSet(settings.DeniedNamespaces),
+		namespaceGetter: namespaceGetter,
+	}
+	var err error
+	if settings.NamespaceSelector != "" {
+		if p.selector, err = labels.Parse(settings.NamespaceSelector); err != nil {
+			return nil, fmt.Errorf("failed to create the namespace policy: invalid namespace selector: %w", err)
+		}
+	}
+	if settings.DeniedNamespaceSelector != "" {
+		if p.deniedSelector, err = labels.Parse(settings.DeniedNamespaceSelector); err != nil {
+			return nil, fmt.Errorf("failed to create the namespace policy: invalid denied namespace selector: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// CheckNamespace returns an OSB 403 error when the instance of the request may not be provisioned
+// into its namespace, or not by the originating identity of the request. The namespace labels are
+// looked up in the cluster the instance is provisioned into.
+func (p *NamespacePolicy) CheckNamespace(ctx context.Context, request *minibroker.ProvisionRequest) error {
+	namespace := request.Namespace
+	if p.denied[namespace] || (len(p.allowed) > 0 && !p.allowed[namespace]) {
+		return namespaceNotAllowed("namespace %q is not allowed", namespace)
+	}
+	rule, ok := p.settings.NamespaceIdentities[namespace]
+	if !ok {
+		rule, ok = p.settings.NamespaceIdentities[AnyNamespace]
+	}
+	if ok && !rule.allows(request.Identity) {
+		if request.Identity == nil {
+			return namespaceNotAllowed("namespace %q requires an originating identity", namespace)
+		}
+		return namespaceNotAllowed("identity %q is not allowed in namespace %q", request.Identity, namespace)
+	}
+	if p.selector == nil && p.deniedSelector == nil {
+		return nil
+	}
+
+	ns, err := p.namespaceGetter.GetTargetNamespace(ctx, request.PlanID, request.Cluster, namespace)
+	if apierrors.IsNotFound(err) {
+		return osb.HTTPStatusCodeError{
+			StatusCode:   http.StatusBadRequest,
+			ErrorMessage: strPtr(NamespaceNotAllowedError),
+			Description:  strPtr(fmt.Sprintf("namespace %q does not exist", namespace)),
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check namespace %q: %w", namespace, err)
+	}
+	nsLabels := labels.Set(ns.Labels)
+	if p.selector != nil && !p.selector.Matches(nsLabels) {
+		return namespaceNotAllowed("namespace %q does not match the selector %q", namespace, p.selector)
+	}
+	if p.deniedSelector != nil && p.deniedSelector.Matches(nsLabels) {
+		return namespaceNotAllowed("namespace %q matches the denied selector %q", namespace, p.deniedSelector)
+	}
+	return nil
+}
+
+// CheckQuotas returns an OSB 403 error when provisioning another instance of the service into the
+// namespace would exceed the quotas. The quotas are checked against the recorded instances without
+// any reservation: the callers serialize the checks with the broker lock, which only covers a
+// single replica, so concurrent requests to several replicas may exceed the quotas.
+func (p *NamespacePolicy) CheckQuotas(ctx context.Context, namespace, serviceID string, lister InstanceLister) error {
+	quotas := p.settings.Quotas
+	namespaceQuota, ok := quotas.Namespaces[namespace]
+	if !ok {
+		namespaceQuota = quotas.MaxInstancesPerNamespace
+	}
+	serviceQuota := quotas.Services[serviceID]
+	if namespaceQuota == 0 && serviceQuota == 0 {
+		return nil
+	}
+
+	instances, err := lister.ListInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check the quotas of namespace %q: %w", namespace, err)
+	}
+	namespaceCount, serviceCount := 0, 0
+	for _, instance := range instances {
+		if instance.PlatformNamespace != namespace {
+			continue
+		}
+		namespaceCount++
+		if instance.ServiceID == serviceID {
+			serviceCount++
+		}
+	}
+
+	if namespaceQuota > 0 && namespaceCount >= namespaceQuota {
+		return quotaExceeded("namespace %q reached its quota of %d instances", namespace, namespaceQuota)
+	}
+	if serviceQuota > 0 && serviceCount >= serviceQuota {
+		return quotaExceeded("namespace %q reached its quota of %d %s instances", namespace, serviceQuota, serviceID)
+	}
+	return nil
+}
+
+// checkPolicy enforces the namespace policy, if any, on a provisioning request. The caller must hold
+// the broker lock.
+func (b *Broker) checkPolicy(ctx context.Context, request *minibroker.ProvisionRequest) error {
+	if b.namespacePolicy == nil {
+		return nil
+	}
+	if err := b.namespacePolicy.CheckNamespace(ctx, request); err != nil {
+		return err
+	}
+	return b.namespacePolicy.CheckQuotas(ctx, request.Namespace, request.ServiceID, b.client)
+}
+
+// PolicyClient returns the client for the provisioning paths other than the OSB API, such as the
+// operator, enforcing the namespace policy as Provision does.
+func (b *Broker) PolicyClient() MinibrokerClient {
+	return policyClient{MinibrokerClient: b.client, broker: b}
+}
+
+// policyClient enforces the namespace policy of the broker on the provisioning requests.
+type policyClient struct {
+	MinibrokerClient
+	broker *Broker
+}
+
+// Provision checks the request against the namespace policy before provisioning it.
+func (c policyClient) Provision(ctx context.Context, request *minibroker.ProvisionRequest) (string, error) {
+	c.broker.Lock()
+	defer c.broker.Unlock()
+
+	if err := c.broker.checkPolicy(ctx, request); err != nil {
+		return "", err
+	}
+	return c.MinibrokerClient.Provision(ctx, request)
+}
+
+func namespaceNotAllowed(format string, a ...interface{}) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusForbidden,
+		ErrorMessage: strPtr(NamespaceNotAllowedError),
+		Description:  strPtr(fmt.Sprintf(format, a...)),
+	}
+}
+
+func quotaExceeded(format string, a ...interface{}) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:   http.StatusForbidden,
+		ErrorMessage: strPtr(QuotaExceededError),
+		Description:  strPtr(fmt.Sprintf(format, a...)),
+	}
+}
+
+func stringSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+func strPtr(s string) *string {
+	return &s
+}
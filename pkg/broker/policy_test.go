@@ -0,0 +1,266 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker_test
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/golang/mock/gomock"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	osbbroker "github.com/pmorie/osb-broker-lib/pkg/broker"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/broker/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+)
+
+func expectStatusError(err error, statusCode int, errorMessage string) {
+	Expect(err).To(HaveOccurred())
+	statusErr, ok := err.(osb.HTTPStatusCodeError)
+	Expect(ok).To(BeTrue(), "expected an osb.HTTPStatusCodeError, got %v", err)
+	Expect(statusErr.StatusCode).To(Equal(statusCode))
+	Expect(*statusErr.ErrorMessage).To(Equal(errorMessage))
+	Expect(statusErr.Description).NotTo(BeNil())
+}
+
+// fakeNamespaceGetter gets the namespaces of the fake clusters, the empty name being the local one.
+type fakeNamespaceGetter map[string]*fake.Clientset
+
+func (f fakeNamespaceGetter) GetTargetNamespace(ctx context.Context, _, cluster, name string) (*corev1.Namespace, error) {
+	return f[cluster].CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+}
+
+var _ = Describe("NamespacePolicy", func() {
+	var (
+		ctrl     *gomock.Controller
+		mbclient *mocks.MockMinibrokerClient
+
+		namespaces = fakeNamespaceGetter{"": fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "team-a",
+				Labels: map[string]string{"minibroker.io/enabled": "true"},
+			}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "team-b",
+				Labels: map[string]string{"minibroker.io/enabled": "true", "env": "prod"},
+			}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name: "team-c",
+			}},
+		), "remote": fake.NewSimpleClientset(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+				Name:   "team-c",
+				Labels: map[string]string{"minibroker.io/enabled": "true"},
+			}},
+		)}
+
+		checkNamespace = func(policy *broker.NamespacePolicy, namespace string) error {
+			return policy.CheckNamespace(context.Background(), &minibroker.ProvisionRequest{Namespace: namespace})
+		}
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mbclient = mocks.NewMockMinibrokerClient(ctrl)
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	Describe("LoadNamespacePolicySettings", func() {
+		It("loads valid data", func() {
+			settings, err := broker.LoadNamespacePolicySettings([]byte(`
+allowedNamespaces: [team-a]
+namespaceSelector: minibroker.io/enabled=true
+namespaceIdentities:
+  team-a:
+    groups: [team-a-admins]
+quotas:
+  maxInstancesPerNamespace: 10
+  services:
+    postgresql: 2
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(settings.AllowedNamespaces).To(Equal([]string{"team-a"}))
+			Expect(settings.NamespaceIdentities).To(HaveKeyWithValue("team-a", broker.IdentityRule{Groups: []string{"team-a-admins"}}))
+			Expect(settings.Quotas.MaxInstancesPerNamespace).To(Equal(10))
+			Expect(settings.Quotas.Services).To(HaveKeyWithValue("postgresql", 2))
+		})
+
+		It("returns an error on unknown fields", func() {
+			_, err := broker.LoadNamespacePolicySettings([]byte("allowed: [team-a]"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("NewNamespacePolicy", func() {
+		It("returns an error on invalid selectors", func() {
+			_, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{NamespaceSelector: "a=b=c"}, namespaces)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("CheckNamespace", func() {
+		It("enforces the allow and deny lists", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				AllowedNamespaces: []string{"team-a", "team-b"},
+				DeniedNamespaces:  []string{"team-b"},
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(checkNamespace(policy, "team-a")).To(Succeed())
+			expectStatusError(checkNamespace(policy, "team-b"), http.StatusForbidden, broker.NamespaceNotAllowedError)
+			expectStatusError(checkNamespace(policy, "team-c"), http.StatusForbidden, broker.NamespaceNotAllowedError)
+		})
+
+		It("enforces the label selectors", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				NamespaceSelector:       "minibroker.io/enabled=true",
+				DeniedNamespaceSelector: "env=prod",
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(checkNamespace(policy, "team-a")).To(Succeed())
+			expectStatusError(checkNamespace(policy, "team-b"), http.StatusForbidden, broker.NamespaceNotAllowedError)
+			expectStatusError(checkNamespace(policy, "team-c"), http.StatusForbidden, broker.NamespaceNotAllowedError)
+			expectStatusError(checkNamespace(policy, "missing"), http.StatusBadRequest, broker.NamespaceNotAllowedError)
+		})
+
+		It("enforces the identity rules", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				NamespaceIdentities: map[string]broker.IdentityRule{
+					"team-a":            {Users: []string{"alice", "cf-user"}},
+					broker.AnyNamespace: {Groups: []string{"admins"}},
+				},
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+
+			check := func(namespace string, identity *minibroker.Identity) error {
+				return policy.CheckNamespace(context.Background(), &minibroker.ProvisionRequest{Namespace: namespace, Identity: identity})
+			}
+			alice := &minibroker.Identity{Platform: "kubernetes", Username: "alice"}
+			bob := &minibroker.Identity{Platform: "kubernetes", Username: "bob", Groups: []string{"admins"}}
+			cfUser := &minibroker.Identity{Platform: "cloudfoundry", UserID: "cf-user"}
+
+			Expect(check("team-a", alice)).To(Succeed())
+			Expect(check("team-a", cfUser)).To(Succeed())
+			expectStatusError(check("team-a", bob), http.StatusForbidden, broker.NamespaceNotAllowedError)
+			expectStatusError(check("team-a", nil), http.StatusForbidden, broker.NamespaceNotAllowedError)
+			Expect(check("team-b", bob)).To(Succeed())
+			expectStatusError(check("team-b", alice), http.StatusForbidden, broker.NamespaceNotAllowedError)
+		})
+
+		It("matches the labels of the namespace in the target cluster", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				NamespaceSelector: "minibroker.io/enabled=true",
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+
+			request := &minibroker.ProvisionRequest{Namespace: "team-c", Cluster: "remote"}
+			Expect(policy.CheckNamespace(context.Background(), request)).To(Succeed())
+			request = &minibroker.ProvisionRequest{Namespace: "team-a", Cluster: "remote"}
+			expectStatusError(policy.CheckNamespace(context.Background(), request), http.StatusBadRequest, broker.NamespaceNotAllowedError)
+		})
+	})
+
+	Describe("CheckQuotas", func() {
+		instances := []minibroker.InstanceSummary{
+			{ID: "1", ServiceID: "postgresql", PlatformNamespace: "team-a"},
+			{ID: "2", ServiceID: "redis", PlatformNamespace: "team-a"},
+			{ID: "3", ServiceID: "postgresql", PlatformNamespace: "team-b"},
+		}
+
+		It("does not list the instances without quotas", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(policy.CheckQuotas(context.Background(), "team-a", "postgresql", mbclient)).To(Succeed())
+		})
+
+		It("enforces the namespace quotas", func() {
+			mbclient.EXPECT().ListInstances(gomock.Any()).Return(instances, nil).Times(2)
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				Quotas: broker.InstanceQuotas{
+					MaxInstancesPerNamespace: 2,
+					Namespaces:               map[string]int{"team-b": 1},
+				},
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+
+			expectStatusError(policy.CheckQuotas(context.Background(), "team-a", "mysql", mbclient), http.StatusForbidden, broker.QuotaExceededError)
+			expectStatusError(policy.CheckQuotas(context.Background(), "team-b", "mysql", mbclient), http.StatusForbidden, broker.QuotaExceededError)
+		})
+
+		It("enforces the service quotas", func() {
+			mbclient.EXPECT().ListInstances(gomock.Any()).Return(instances, nil).Times(2)
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				Quotas: broker.InstanceQuotas{
+					Services: map[string]int{"postgresql": 1},
+				},
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+
+			expectStatusError(policy.CheckQuotas(context.Background(), "team-a", "postgresql", mbclient), http.StatusForbidden, broker.QuotaExceededError)
+			Expect(policy.CheckQuotas(context.Background(), "team-c", "postgresql", mbclient)).To(Succeed())
+		})
+	})
+
+	Describe("Broker.Provision", func() {
+		It("rejects the provisioning before reaching the client", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				DeniedNamespaces: []string{"kube-system"},
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+			b := broker.NewBroker(mbclient, "kube-system", &broker.ProvisioningSettings{}, policy, nil)
+
+			_, err = b.Provision(&osb.ProvisionRequest{ServiceID: "redis"}, &osbbroker.RequestContext{})
+			expectStatusError(err, http.StatusForbidden, broker.NamespaceNotAllowedError)
+		})
+	})
+
+	Describe("Broker.PolicyClient", func() {
+		It("enforces the policy on the provisioning outside of the OSB API", func() {
+			policy, err := broker.NewNamespacePolicy(broker.NamespacePolicySettings{
+				DeniedNamespaces: []string{"kube-system"},
+				Quotas:           broker.InstanceQuotas{Services: map[string]int{"postgresql": 1}},
+			}, namespaces)
+			Expect(err).ToNot(HaveOccurred())
+			b := broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, policy, nil)
+			client := b.PolicyClient()
+
+			_, err = client.Provision(context.Background(), &minibroker.ProvisionRequest{ServiceID: "redis", Namespace: "kube-system"})
+			expectStatusError(err, http.StatusForbidden, broker.NamespaceNotAllowedError)
+
+			mbclient.EXPECT().ListInstances(gomock.Any()).Return([]minibroker.InstanceSummary{
+				{ID: "1", ServiceID: "postgresql", PlatformNamespace: "team-a"},
+			}, nil).Times(2)
+			_, err = client.Provision(context.Background(), &minibroker.ProvisionRequest{ServiceID: "postgresql", Namespace: "team-a"})
+			expectStatusError(err, http.StatusForbidden, broker.QuotaExceededError)
+
+			request := &minibroker.ProvisionRequest{ServiceID: "postgresql", Namespace: "team-b"}
+			mbclient.EXPECT().Provision(gomock.Any(), request).Return("provision", nil)
+			Expect(client.Provision(context.Background(), request)).To(Equal("provision"))
+		})
+	})
+})
@@ -0,0 +1,174 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
+)
+
+// InstancePath is the route of the OSB instance fetch, which the broker library does not serve.
+const InstancePath = "/v2/service_instances/{instance_id}"
+
+const catalogPath = "/v2/catalog"
+
+// GetInstanceResponse is the response of the OSB instance fetch.
+type GetInstanceResponse struct {
+	ServiceID string `json:"service_id"`
+	PlanID    string `json:"plan_id"`
+	// Minibroker provisions no dashboard, so the DashboardURL is never set.
+	DashboardURL *string                `json:"dashboard_url,omitempty"`
+	Parameters   map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// GetInstance fetches a service instance. The parameters are returned as stored.
+func (b *Broker) GetInstance(ctx context.Context, instanceID string) (_ *GetInstanceResponse, err error) {
+	ctx, span := tracing.Start(ctx, "broker.GetInstance", attribute.String("instance.id", instanceID))
+	defer func() { tracing.End(span, err) }()
+
+	logger := b.logger(ctx).WithValues("instanceID", instanceID)
+	logger.V(4).Info("getting instance")
+
+	instance, err := b.client.FetchInstance(ctx, instanceID)
+	if err != nil {
+		logger.V(4).Info("failed to get instance", "error", err)
+		return nil, err
+	}
+
+	logger.V(4).Info("got instance")
+
+	return &GetInstanceResponse{
+		ServiceID:  instance.ServiceID,
+		PlanID:     instance.PlanID,
+		Parameters: instance.Parameters,
+	}, nil
+}
+
+// InstanceHandler serves the OSB instance fetch, redacting the parameters.
+type InstanceHandler struct {
+	broker *Broker
+	redact func(map[string]interface{}) map[string]interface{}
+}
+
+var _ http.Handler = &InstanceHandler{}
+
+// NewInstanceHandler creates a new InstanceHandler redacting the parameters with the given function.
+func NewInstanceHandler(b *Broker, redact func(map[string]interface{}) map[string]interface{}) *InstanceHandler {
+	return &InstanceHandler{broker: b, redact: redact}
+}
+
+// ServeHTTP serves GET on the InstancePath route.
+func (h *InstanceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.broker.ValidateBrokerAPIVersion(r.Header.Get(osb.APIVersionHeader)); err != nil {
+		writeOSBError(w, err)
+		return
+	}
+	response, err := h.broker.GetInstance(r.Context(), mux.Vars(r)["instance_id"])
+	if err != nil {
+		writeOSBError(w, err)
+		return
+	}
+	response.Parameters = h.redact(response.Parameters)
+	writeJSON(w, http.StatusOK, response)
+}
+
+// CatalogMiddleware declares the services of the catalog served by the broker library as
+// instances_retrievable, which its catalog types do not support.
+func CatalogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := mux.CurrentRoute(r)
+		if route == nil || r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if path, err := route.GetPathTemplate(); err != nil || path != catalogPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &catalogRecorder{header: http.Header{}, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		for key, values := range recorder.header {
+			w.Header()[key] = values
+		}
+
+		var catalog struct {
+			Services []retrievableService `json:"services"`
+		}
+		if recorder.statusCode != http.StatusOK || json.Unmarshal(recorder.body.Bytes(), &catalog) != nil {
+			w.WriteHeader(recorder.statusCode)
+			w.Write(recorder.body.Bytes())
+			return
+		}
+		for i := range catalog.Services {
+			catalog.Services[i].InstancesRetrievable = true
+		}
+		writeJSON(w, http.StatusOK, catalog)
+	})
+}
+
+// retrievableService is a catalog service with the instances_retrievable field.
+type retrievableService struct {
+	osb.Service
+	InstancesRetrievable bool `json:"instances_retrievable,omitempty"`
+}
+
+// catalogRecorder buffers the catalog response to rewrite it.
+type catalogRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *catalogRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *catalogRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+}
+
+func (r *catalogRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+// writeOSBError writes an error in the format of the broker library: the status code, error and
+// description of an OSB error, or a 500 with the description of any other.
+func writeOSBError(w http.ResponseWriter, err error) {
+	if httpErr, ok := osb.IsHTTPError(err); ok {
+		writeJSON(w, httpErr.StatusCode, struct {
+			ErrorMessage *string `json:"error,omitempty"`
+			Description  *string `json:"description,omitempty"`
+		}{httpErr.ErrorMessage, httpErr.Description})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"description": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/golang/mock/gomock"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"github.com/pmorie/osb-broker-lib/pkg/metrics"
+	"github.com/pmorie/osb-broker-lib/pkg/rest"
+	"github.com/pmorie/osb-broker-lib/pkg/server"
+	"github.com/prometheus/client_golang/prometheus"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/broker/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+)
+
+var _ = Describe("Retrieval", func() {
+	var (
+		ctrl     *gomock.Controller
+		mbclient *mocks.MockMinibrokerClient
+		handler  http.Handler
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mbclient = mocks.NewMockMinibrokerClient(ctrl)
+		b := broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, nil, nil)
+		api, err := rest.NewAPISurface(b, metrics.New())
+		Expect(err).NotTo(HaveOccurred())
+		s := server.New(api, prometheus.NewRegistry())
+		redact := func(parameters map[string]interface{}) map[string]interface{} {
+			return map[string]interface{}{"redacted": len(parameters)}
+		}
+		s.Router.Path(broker.InstancePath).Methods(http.MethodGet).Handler(broker.NewInstanceHandler(b, redact))
+		s.Router.Use(broker.CatalogMiddleware)
+		handler = s.Router
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	get := func(path string) (*httptest.ResponseRecorder, map[string]interface{}) {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.Header.Set(osb.APIVersionHeader, "2.14")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		var body map[string]interface{}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &body)).To(Succeed())
+		return recorder, body
+	}
+
+	It("declares the instance and binding fetches in the catalog", func() {
+		mbclient.EXPECT().
+			ListServices(gomock.Any()).
+			Return([]osb.Service{{ID: "redis", Name: "redis", Bindable: true, BindingsRetrievable: true}}, nil)
+
+		recorder, body := get("/v2/catalog")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(body["services"]).To(ConsistOf(SatisfyAll(
+			HaveKeyWithValue("id", "redis"),
+			HaveKeyWithValue("instances_retrievable", true),
+			HaveKeyWithValue("bindings_retrievable", true),
+		)))
+	})
+
+	It("fetches an instance with its redacted parameters", func() {
+		mbclient.EXPECT().
+			FetchInstance(gomock.Any(), "db").
+			Return(&minibroker.FetchedInstance{
+				ServiceID:  "redis",
+				PlanID:     "redis-5-0-7",
+				Parameters: map[string]interface{}{"password": "hunter2"},
+			}, nil)
+
+		recorder, body := get("/v2/service_instances/db")
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(body).To(Equal(map[string]interface{}{
+			"service_id": "redis",
+			"plan_id":    "redis-5-0-7",
+			"parameters": map[string]interface{}{"redacted": float64(1)},
+		}))
+	})
+
+	It("returns the OSB errors of the instance fetch", func() {
+		mbclient.EXPECT().
+			FetchInstance(gomock.Any(), "db").
+			Return(nil, osb.HTTPStatusCodeError{
+				StatusCode:   http.StatusUnprocessableEntity,
+				ErrorMessage: &[]string{minibroker.ConcurrencyErrorMessage}[0],
+			})
+
+		recorder, body := get("/v2/service_instances/db")
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(body).To(HaveKeyWithValue("error", minibroker.ConcurrencyErrorMessage))
+	})
+
+	It("rejects the instance fetch without an API version", func() {
+		request := httptest.NewRequest(http.MethodGet, "/v2/service_instances/db", nil)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		Expect(recorder.Code).To(Equal(http.StatusPreconditionFailed))
+	})
+})
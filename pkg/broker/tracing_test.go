@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+
+	"github.com/golang/mock/gomock"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	osbbroker "github.com/pmorie/osb-broker-lib/pkg/broker"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+	"github.com/kubernetes-sigs/minibroker/pkg/broker/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		ctrl     *gomock.Controller
+		mbclient *mocks.MockMinibrokerClient
+		b        *broker.Broker
+		exporter *tracetest.InMemoryExporter
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(GinkgoT())
+		mbclient = mocks.NewMockMinibrokerClient(ctrl)
+		b = broker.NewBroker(mbclient, "default", &broker.ProvisioningSettings{}, nil, nil)
+		exporter = tracing.SetupInMemory()
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	spanNamed := func(name string) tracetest.SpanStub {
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				return span
+			}
+		}
+		Fail("span not found: " + name)
+		return tracetest.SpanStub{}
+	}
+
+	It("passes the span of the OSB request to the client", func() {
+		ctx, requestSpan := tracing.Start(context.Background(), "HTTP PUT")
+		request := httptest.NewRequest("PUT", "/v2/service_instances/db", nil).WithContext(ctx)
+
+		var clientSpan trace.SpanContext
+		mbclient.EXPECT().
+			Provision(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, _ *minibroker.ProvisionRequest) (string, error) {
+				clientSpan = trace.SpanContextFromContext(ctx)
+				return "", errors.New("install failed")
+			})
+
+		_, err := b.Provision(&osb.ProvisionRequest{InstanceID: "db", ServiceID: "redis"},
+			&osbbroker.RequestContext{Request: request})
+		Expect(err).To(HaveOccurred())
+		requestSpan.End()
+
+		span := spanNamed("broker.Provision")
+		Expect(span.Parent.SpanID()).To(Equal(requestSpan.SpanContext().SpanID()))
+		Expect(clientSpan.SpanID()).To(Equal(span.SpanContext.SpanID()))
+		Expect(span.Status.Code).To(Equal(codes.Error))
+	})
+
+	It("starts a root span without a request", func() {
+		mbclient.EXPECT().Unbind(gomock.Any(), "db", "binding")
+
+		_, err := b.Unbind(&osb.UnbindRequest{InstanceID: "db", BindingID: "binding"}, nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		span := spanNamed("broker.Unbind")
+		Expect(span.Parent.IsValid()).To(BeFalse())
+		Expect(span.Status.Code).To(Equal(codes.Unset))
+	})
+})
@@ -0,0 +1,85 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// The range of the OSB API versions supported by the broker, inclusive.
+const (
+	MinBrokerAPIVersion = "2.13"
+	MaxBrokerAPIVersion = "2.17"
+)
+
+// ValidateBrokerAPIVersion rejects the requests with a missing or unsupported
+// X-Broker-API-Version header with an OSB 412 error.
+func (b *Broker) ValidateBrokerAPIVersion(version string) error {
+	if version == "" {
+		return versionError(fmt.Sprintf("missing %s header", osb.APIVersionHeader))
+	}
+	requested, err := parseAPIVersion(version)
+	if err != nil {
+		return versionError(err.Error())
+	}
+	min, _ := parseAPIVersion(MinBrokerAPIVersion)
+	max, _ := parseAPIVersion(MaxBrokerAPIVersion)
+	if requested.less(min) || max.less(requested) {
+		return versionError(fmt.Sprintf(
+			"unsupported %s %q: the broker supports %s to %s",
+			osb.APIVersionHeader, version, MinBrokerAPIVersion, MaxBrokerAPIVersion))
+	}
+	return nil
+}
+
+// apiVersion is an OSB API version, major.minor.
+type apiVersion struct {
+	major int
+	minor int
+}
+
+func parseAPIVersion(version string) (apiVersion, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) != 2 {
+		return apiVersion{}, fmt.Errorf("invalid %s %q", osb.APIVersionHeader, version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return apiVersion{}, fmt.Errorf("invalid %s %q", osb.APIVersionHeader, version)
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return apiVersion{}, fmt.Errorf("invalid %s %q", osb.APIVersionHeader, version)
+	}
+	return apiVersion{major: major, minor: minor}, nil
+}
+
+func (v apiVersion) less(other apiVersion) bool {
+	return v.major < other.major || (v.major == other.major && v.minor < other.minor)
+}
+
+func versionError(description string) error {
+	return osb.HTTPStatusCodeError{
+		StatusCode:  http.StatusPreconditionFailed,
+		Description: strPtr(description),
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker_test
+
+import (
+	"net/http"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/broker"
+)
+
+var _ = Describe("ValidateBrokerAPIVersion", func() {
+	b := broker.NewBroker(nil, "default", &broker.ProvisioningSettings{}, nil, nil)
+
+	It("accepts the supported versions", func() {
+		for _, version := range []string{broker.MinBrokerAPIVersion, "2.14", broker.MaxBrokerAPIVersion} {
+			Expect(b.ValidateBrokerAPIVersion(version)).To(Succeed(), version)
+		}
+	})
+
+	It("rejects missing and unsupported versions with a 412", func() {
+		for _, version := range []string{"", "2.12", "2.18", "3.0", "latest"} {
+			err := b.ValidateBrokerAPIVersion(version)
+			Expect(err).To(HaveOccurred(), version)
+			Expect(err.(osb.HTTPStatusCodeError).StatusCode).To(Equal(http.StatusPreconditionFailed), version)
+		}
+	})
+})
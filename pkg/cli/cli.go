@@ -0,0 +1,248 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+)
+
+// command is a client subcommand.
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx *commandContext, args []string) error
+}
+
+var commands = map[string]command{
+	"catalog": {
+		usage:   "catalog",
+		summary: "List the services and plans offered by the broker",
+		run:     runCatalog,
+	},
+	"provision": {
+		usage:   "provision SERVICE PLAN",
+		summary: "Provision a service instance",
+		run:     runProvision,
+	},
+	"deprovision": {
+		usage:   "deprovision INSTANCE_ID",
+		summary: "Deprovision a service instance",
+		run:     runDeprovision,
+	},
+	"bind": {
+		usage:   "bind INSTANCE_ID",
+		summary: "Bind a service instance and print the credentials",
+		run:     runBind,
+	},
+	"unbind": {
+		usage:   "unbind INSTANCE_ID BINDING_ID",
+		summary: "Unbind a service instance",
+		run:     runUnbind,
+	},
+	"status": {
+		usage:   "status INSTANCE_ID [BINDING_ID]",
+		summary: "Show the state of the last operation on an instance or binding",
+		run:     runStatus,
+	},
+	"instances": {
+		usage:   "instances",
+		summary: "List the instances provisioned with this client",
+		run:     runInstances,
+	},
+	"bindings": {
+		usage:   "bindings [INSTANCE_ID]",
+		summary: "List the bindings created with this client",
+		run:     runBindings,
+	},
+}
+
+// newClient creates the OSB client. It is replaced in the tests.
+var newClient osb.CreateFunc = osb.NewClient
+
+// IsCommand returns whether the name is a client subcommand.
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok
+}
+
+// Usage writes the list of the client subcommands.
+func Usage(w io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Client commands:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-36s %s\n", commands[name].usage, commands[name].summary)
+	}
+}
+
+// Run runs the client subcommand named by the first argument.
+func Run(args []string, stdout io.Writer) error {
+	if len(args) == 0 || !IsCommand(args[0]) {
+		return fmt.Errorf("unknown command")
+	}
+	cmd := commands[args[0]]
+
+	ctx := &commandContext{stdout: stdout}
+	flags := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: minibroker %s [flags]\n\n%s.\n\nFlags:\n", cmd.usage, cmd.summary)
+		flags.PrintDefaults()
+	}
+	ctx.registerFlags(flags)
+	ctx.flags = flags
+	return cmd.run(ctx, args[1:])
+}
+
+// commandContext holds the flags shared by the client subcommands.
+type commandContext struct {
+	stdout io.Writer
+	flags  *flag.FlagSet
+
+	url       string
+	username  string
+	password  string
+	token     string
+	caFile    string
+	insecure  bool
+	timeout   time.Duration
+	stateFile string
+
+	wait         bool
+	waitTimeout  time.Duration
+	pollInterval time.Duration
+}
+
+func (ctx *commandContext) registerFlags(flags *flag.FlagSet) {
+	flags.StringVar(&ctx.url, "url", os.Getenv("MINIBROKER_URL"),
+		"The URL of the broker - defaults to $MINIBROKER_URL")
+	flags.StringVar(&ctx.username, "username", os.Getenv("MINIBROKER_USERNAME"),
+		"The username for basic authentication - defaults to $MINIBROKER_USERNAME")
+	flags.StringVar(&ctx.password, "password", os.Getenv("MINIBROKER_PASSWORD"),
+		"The password for basic authentication - defaults to $MINIBROKER_PASSWORD")
+	flags.StringVar(&ctx.token, "token", os.Getenv("MINIBROKER_TOKEN"),
+		"The bearer token for authentication - defaults to $MINIBROKER_TOKEN")
+	flags.StringVar(&ctx.caFile, "caFile", os.Getenv("MINIBROKER_CA_FILE"),
+		"The PEM file of the CA certificates trusted for the broker TLS - defaults to $MINIBROKER_CA_FILE")
+	flags.BoolVar(&ctx.insecure, "insecure", false,
+		"Skip the verification of the broker TLS certificate")
+	flags.DurationVar(&ctx.timeout, "requestTimeout", time.Minute,
+		"The timeout of each request to the broker")
+	flags.StringVar(&ctx.stateFile, "stateFile", os.Getenv("MINIBROKER_STATE_FILE"),
+		"The file recording the instances and bindings created with this client - defaults to $MINIBROKER_STATE_FILE or minibroker/state.json in the user config directory")
+	flags.BoolVar(&ctx.wait, "wait", true,
+		"Wait for the asynchronous operations to complete")
+	flags.DurationVar(&ctx.waitTimeout, "timeout", 15*time.Minute,
+		"How long to wait for an asynchronous operation to complete")
+	flags.DurationVar(&ctx.pollInterval, "pollInterval", 5*time.Second,
+		"How often the last operation is polled while waiting")
+}
+
+// parse parses the flags, checking the number of positional arguments.
+func (ctx *commandContext) parse(args []string, minArgs, maxArgs int) ([]string, error) {
+	if err := ctx.flags.Parse(args); err != nil {
+		return nil, err
+	}
+	positional := ctx.flags.Args()
+	if len(positional) < minArgs || len(positional) > maxArgs {
+		ctx.flags.Usage()
+		return nil, fmt.Errorf("wrong number of arguments")
+	}
+	return positional, nil
+}
+
+// client creates the OSB client from the connection flags.
+func (ctx *commandContext) client() (osb.Client, error) {
+	if ctx.url == "" {
+		return nil, fmt.Errorf("the broker URL must be set with --url or $MINIBROKER_URL")
+	}
+
+	config := osb.DefaultClientConfiguration()
+	config.Name = "minibroker"
+	config.URL = strings.TrimSuffix(ctx.url, "/")
+	config.Insecure = ctx.insecure
+	config.TimeoutSeconds = int(ctx.timeout.Seconds())
+	// The asynchronous bindings are an alpha feature of the client.
+	config.EnableAlphaFeatures = true
+
+	switch {
+	case ctx.token != "" && ctx.username != "":
+		return nil, fmt.Errorf("only one of --token and --username can be set")
+	case ctx.token != "":
+		config.AuthConfig = &osb.AuthConfig{BearerConfig: &osb.BearerConfig{Token: ctx.token}}
+	case ctx.username != "":
+		config.AuthConfig = &osb.AuthConfig{BasicAuthConfig: &osb.BasicAuthConfig{
+			Username: ctx.username,
+			Password: ctx.password,
+		}}
+	}
+
+	if ctx.caFile != "" {
+		caData, err := ioutil.ReadFile(ctx.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		config.CAData = caData
+	}
+
+	return newClient(config)
+}
+
+// pollOperation polls the last operation until it completes, returning its final state. It returns
+// the first state without waiting if --wait is disabled.
+func (ctx *commandContext) pollOperation(poll func() (*osb.LastOperationResponse, error)) (*osb.LastOperationResponse, error) {
+	deadline := time.Now().Add(ctx.waitTimeout)
+	for {
+		response, err := poll()
+		if err != nil {
+			return nil, err
+		}
+		if response.State != osb.StateInProgress || !ctx.wait {
+			return response, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for the operation: %s", description(response))
+		}
+		time.Sleep(ctx.pollInterval)
+	}
+}
+
+// checkOperation returns an error if the operation failed.
+func checkOperation(response *osb.LastOperationResponse) error {
+	if response.State == osb.StateFailed {
+		return fmt.Errorf("the operation failed: %s", description(response))
+	}
+	return nil
+}
+
+func description(response *osb.LastOperationResponse) string {
+	if response.Description == nil || *response.Description == "" {
+		return string(response.State)
+	}
+	return *response.Description
+}
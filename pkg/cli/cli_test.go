@@ -0,0 +1,164 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"github.com/pmorie/go-open-service-broker-client/v2/fake"
+)
+
+func TestWriteCredentials(t *testing.T) {
+	creds := map[string]interface{}{
+		"host":     "db.apps.svc.cluster.local",
+		"port":     5432,
+		"password": "it's",
+	}
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{
+			outputEnv,
+			"HOST='db.apps.svc.cluster.local'\nPASSWORD='it'\\''s'\nPORT='5432'\n",
+		},
+		{
+			outputJSON,
+			"{\n  \"host\": \"db.apps.svc.cluster.local\",\n  \"password\": \"it's\",\n  \"port\": 5432\n}\n",
+		},
+		{
+			outputSecret,
+			"apiVersion: v1\nkind: Secret\nmetadata:\n  creationTimestamp: null\n  name: creds\n  namespace: apps\n" +
+				"stringData:\n  host: db.apps.svc.cluster.local\n  password: it's\n  port: \"5432\"\ntype: Opaque\n",
+		},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := writeCredentials(&out, creds, tt.format, "creds", "apps"); err != nil {
+			t.Fatalf("writeCredentials(%s): unexpected error %v", tt.format, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("writeCredentials(%s): expected %q, actual %q", tt.format, tt.expected, out.String())
+		}
+	}
+
+	if err := writeCredentials(&bytes.Buffer{}, creds, "xml", "creds", "apps"); err == nil {
+		t.Errorf("writeCredentials(xml): expected an error")
+	}
+}
+
+func TestParameters(t *testing.T) {
+	params := make(paramsFlag)
+	for _, param := range []string{"persistence.size=1Gi", "persistence.enabled=false", "replicas=2"} {
+		if err := params.Set(param); err != nil {
+			t.Fatalf("Set(%s): unexpected error %v", param, err)
+		}
+	}
+	if err := params.Set("invalid"); err == nil {
+		t.Errorf("Set(invalid): expected an error")
+	}
+
+	merged, err := parameters(`{"persistence": {"storageClass": "fast", "size": "8Gi"}}`, params)
+	if err != nil {
+		t.Fatalf("parameters: unexpected error %v", err)
+	}
+	persistence := merged["persistence"].(map[string]interface{})
+	if persistence["size"] != "1Gi" || persistence["enabled"] != false || persistence["storageClass"] != "fast" {
+		t.Errorf("parameters: unexpected persistence %v", persistence)
+	}
+	if merged["replicas"] != float64(2) {
+		t.Errorf("parameters: expected 2 replicas, actual %v", merged["replicas"])
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	operation := osb.OperationKey("operation")
+	client := fake.NewFakeClient(fake.FakeClientConfiguration{
+		CatalogReaction: &fake.CatalogReaction{Response: &osb.CatalogResponse{Services: []osb.Service{{
+			ID:    "redis",
+			Name:  "redis",
+			Plans: []osb.Plan{{ID: "redis-5-0-7", Name: "5-0-7"}},
+		}}}},
+		ProvisionReaction: &fake.ProvisionReaction{Response: &osb.ProvisionResponse{Async: true, OperationKey: &operation}},
+		PollLastOperationReaction: &fake.PollLastOperationReaction{Response: &osb.LastOperationResponse{
+			State: osb.StateSucceeded,
+		}},
+		BindReaction: &fake.BindReaction{Response: &osb.BindResponse{Async: true}},
+		PollBindingLastOperationReaction: &fake.PollBindingLastOperationReaction{Response: &osb.LastOperationResponse{
+			State: osb.StateSucceeded,
+		}},
+		GetBindingReaction: &fake.GetBindingReaction{Response: &osb.GetBindingResponse{
+			Credentials: map[string]interface{}{"password": "secret"},
+		}},
+		UnbindReaction:      &fake.UnbindReaction{Response: &osb.UnbindResponse{}},
+		DeprovisionReaction: &fake.DeprovisionReaction{Response: &osb.DeprovisionResponse{}},
+	})
+	newClient = fake.ReturnFakeClientFunc(client)
+	defer func() { newClient = osb.NewClient }()
+
+	dir, err := ioutil.TempDir("", "minibroker-cli")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state.json")
+	run := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		args = append([]string{args[0], "--url", "http://broker", "--stateFile", stateFile}, args[1:]...)
+		if err := Run(args, &out); err != nil {
+			t.Fatalf("%v: unexpected error %v", args, err)
+		}
+		return out.String()
+	}
+
+	run("provision", "--id", "instance", "redis", "5-0-7")
+	if out := run("instances"); !strings.Contains(out, "instance  redis    redis-5-0-7") {
+		t.Errorf("instances: expected the provisioned instance, actual %q", out)
+	}
+
+	if out := run("bind", "--id", "binding", "instance"); out != "PASSWORD='secret'\n" {
+		t.Errorf("bind: unexpected credentials %q", out)
+	}
+	if out := run("bindings", "instance"); !strings.Contains(out, "binding  instance") {
+		t.Errorf("bindings: expected the binding, actual %q", out)
+	}
+
+	run("unbind", "instance", "binding")
+	run("deprovision", "instance")
+	if out := run("instances"); strings.Contains(out, "instance ") {
+		t.Errorf("instances: expected no instance, actual %q", out)
+	}
+
+	var bindRequest *osb.BindRequest
+	for _, action := range client.Actions() {
+		if action.Type == fake.Bind {
+			bindRequest = action.Request.(*osb.BindRequest)
+		}
+	}
+	if bindRequest == nil || bindRequest.ServiceID != "redis" || bindRequest.PlanID != "redis-5-0-7" {
+		t.Errorf("bind: expected the service and plan from the state file, actual %+v", bindRequest)
+	}
+}
@@ -0,0 +1,454 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+func runCatalog(ctx *commandContext, args []string) error {
+	output := ctx.flags.String("output", "table", "The output format: table or json")
+	if _, err := ctx.parse(args, 0, 0); err != nil {
+		return err
+	}
+	client, err := ctx.client()
+	if err != nil {
+		return err
+	}
+	catalog, err := client.GetCatalog()
+	if err != nil {
+		return fmt.Errorf("failed to get the catalog: %w", err)
+	}
+
+	switch *output {
+	case "json":
+		data, err := json.MarshalIndent(catalog.Services, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(ctx.stdout, string(data))
+		return err
+	case "table":
+		w := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVICE\tPLAN\tPLAN ID\tDESCRIPTION")
+		for _, service := range catalog.Services {
+			for _, plan := range service.Plans {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", service.Name, plan.Name, plan.ID, plan.Description)
+			}
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q: use table or json", *output)
+	}
+}
+
+func runProvision(ctx *commandContext, args []string) error {
+	instanceID := ctx.flags.String("id", "", "The ID of the instance - generated when empty")
+	namespace := ctx.flags.String("namespace", "", "The namespace of the instance - the broker default when empty")
+	paramsJSON := ctx.flags.String("params", "", "The provisioning parameters as a JSON object")
+	params := make(paramsFlag)
+	ctx.flags.Var(params, "param", "A provisioning parameter in the format key=value, e.g. persistence.size=1Gi - can be repeated")
+	positional, err := ctx.parse(args, 2, 2)
+	if err != nil {
+		return err
+	}
+	parameters, err := parameters(*paramsJSON, params)
+	if err != nil {
+		return err
+	}
+	client, err := ctx.client()
+	if err != nil {
+		return err
+	}
+
+	serviceID, planID, err := resolvePlan(client, positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	if *instanceID == "" {
+		*instanceID = string(uuid.NewUUID())
+	}
+
+	request := &osb.ProvisionRequest{
+		InstanceID:        *instanceID,
+		AcceptsIncomplete: true,
+		ServiceID:         serviceID,
+		PlanID:            planID,
+		Parameters:        parameters,
+	}
+	if *namespace != "" {
+		request.Context = map[string]interface{}{
+			"platform":  "kubernetes",
+			"namespace": *namespace,
+		}
+	}
+	response, err := client.ProvisionInstance(request)
+	if err != nil {
+		return fmt.Errorf("failed to provision: %w", err)
+	}
+
+	s, err := ctx.loadState()
+	if err != nil {
+		return err
+	}
+	s.Instances = append(s.Instances, instanceRecord{
+		ID:        *instanceID,
+		BrokerURL: ctx.url,
+		ServiceID: serviceID,
+		PlanID:    planID,
+		Namespace: *namespace,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err := ctx.saveState(s); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.stdout, "instance %s: provisioning %s/%s\n", *instanceID, serviceID, planID)
+
+	if !response.Async {
+		fmt.Fprintf(ctx.stdout, "instance %s: provisioned\n", *instanceID)
+		return nil
+	}
+	state, err := ctx.pollOperation(func() (*osb.LastOperationResponse, error) {
+		return client.PollLastOperation(&osb.LastOperationRequest{
+			InstanceID:   *instanceID,
+			ServiceID:    &serviceID,
+			PlanID:       &planID,
+			OperationKey: response.OperationKey,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.stdout, "instance %s: %s\n", *instanceID, description(state))
+	return checkOperation(state)
+}
+
+func runDeprovision(ctx *commandContext, args []string) error {
+	serviceID := ctx.flags.String("service", "", "The service ID of the instance - read from the state file when empty")
+	planID := ctx.flags.String("plan", "", "The plan ID of the instance - read from the state file when empty")
+	positional, err := ctx.parse(args, 1, 1)
+	if err != nil {
+		return err
+	}
+	instanceID := positional[0]
+	s, err := ctx.loadState()
+	if err != nil {
+		return err
+	}
+	if err := resolveInstance(s, instanceID, serviceID, planID); err != nil {
+		return err
+	}
+	client, err := ctx.client()
+	if err != nil {
+		return err
+	}
+
+	response, err := client.DeprovisionInstance(&osb.DeprovisionRequest{
+		InstanceID:        instanceID,
+		AcceptsIncomplete: true,
+		ServiceID:         *serviceID,
+		PlanID:            *planID,
+	})
+	if err != nil && !osb.IsGoneError(err) {
+		return fmt.Errorf("failed to deprovision: %w", err)
+	}
+
+	if err == nil && response.Async {
+		state, err := ctx.pollOperation(func() (*osb.LastOperationResponse, error) {
+			state, err := client.PollLastOperation(&osb.LastOperationRequest{
+				InstanceID:   instanceID,
+				ServiceID:    serviceID,
+				PlanID:       planID,
+				OperationKey: response.OperationKey,
+			})
+			// The instance is gone once deprovisioned.
+			if osb.IsGoneError(err) {
+				return &osb.LastOperationResponse{State: osb.StateSucceeded}, nil
+			}
+			return state, err
+		})
+		if err != nil {
+			return err
+		}
+		if err := checkOperation(state); err != nil {
+			return err
+		}
+		if state.State == osb.StateInProgress {
+			fmt.Fprintf(ctx.stdout, "instance %s: %s\n", instanceID, description(state))
+			return nil
+		}
+	}
+
+	s.removeInstance(instanceID)
+	if err := ctx.saveState(s); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.stdout, "instance %s: deprovisioned\n", instanceID)
+	return nil
+}
+
+func runBind(ctx *commandContext, args []string) error {
+	bindingID := ctx.flags.String("id", "", "The ID of the binding - generated when empty")
+	serviceID := ctx.flags.String("service", "", "The service ID of the instance - read from the state file when empty")
+	planID := ctx.flags.String("plan", "", "The plan ID of the instance - read from the state file when empty")
+	namespace := ctx.flags.String("namespace", "", "The namespace of the consumer and of the Secret manifest")
+	paramsJSON := ctx.flags.String("params", "", "The binding parameters as a JSON object")
+	params := make(paramsFlag)
+	ctx.flags.Var(params, "param", "A binding parameter in the format key=value, e.g. role=readonly - can be repeated")
+	output := ctx.flags.String("output", outputEnv, "The credentials output format: env, json or secret")
+	secretName := ctx.flags.String("secretName", "", "The name of the Secret manifest - defaults to minibroker-BINDING_ID")
+	positional, err := ctx.parse(args, 1, 1)
+	if err != nil {
+		return err
+	}
+	instanceID := positional[0]
+	parameters, err := parameters(*paramsJSON, params)
+	if err != nil {
+		return err
+	}
+	s, err := ctx.loadState()
+	if err != nil {
+		return err
+	}
+	if err := resolveInstance(s, instanceID, serviceID, planID); err != nil {
+		return err
+	}
+	client, err := ctx.client()
+	if err != nil {
+		return err
+	}
+	if *bindingID == "" {
+		*bindingID = string(uuid.NewUUID())
+	}
+
+	request := &osb.BindRequest{
+		BindingID:         *bindingID,
+		InstanceID:        instanceID,
+		AcceptsIncomplete: true,
+		ServiceID:         *serviceID,
+		PlanID:            *planID,
+		Parameters:        parameters,
+	}
+	if *namespace != "" {
+		request.Context = map[string]interface{}{
+			"platform":  "kubernetes",
+			"namespace": *namespace,
+		}
+	}
+	response, err := client.Bind(request)
+	if err != nil {
+		return fmt.Errorf("failed to bind: %w", err)
+	}
+
+	s.Bindings = append(s.Bindings, bindingRecord{
+		ID:         *bindingID,
+		InstanceID: instanceID,
+		CreatedAt:  time.Now().UTC(),
+	})
+	if err := ctx.saveState(s); err != nil {
+		return err
+	}
+
+	creds := response.Credentials
+	if response.Async {
+		// The credentials are only available once bound.
+		wait := ctx.wait
+		ctx.wait = true
+		state, err := ctx.pollOperation(func() (*osb.LastOperationResponse, error) {
+			return client.PollBindingLastOperation(&osb.BindingLastOperationRequest{
+				InstanceID:   instanceID,
+				BindingID:    *bindingID,
+				ServiceID:    serviceID,
+				PlanID:       planID,
+				OperationKey: response.OperationKey,
+			})
+		})
+		ctx.wait = wait
+		if err != nil {
+			return err
+		}
+		if err := checkOperation(state); err != nil {
+			return err
+		}
+		binding, err := client.GetBinding(&osb.GetBindingRequest{InstanceID: instanceID, BindingID: *bindingID})
+		if err != nil {
+			return fmt.Errorf("failed to get the binding: %w", err)
+		}
+		creds = binding.Credentials
+	}
+
+	if *secretName == "" {
+		*secretName = "minibroker-" + *bindingID
+	}
+	return writeCredentials(ctx.stdout, creds, *output, *secretName, *namespace)
+}
+
+func runUnbind(ctx *commandContext, args []string) error {
+	serviceID := ctx.flags.String("service", "", "The service ID of the instance - read from the state file when empty")
+	planID := ctx.flags.String("plan", "", "The plan ID of the instance - read from the state file when empty")
+	positional, err := ctx.parse(args, 2, 2)
+	if err != nil {
+		return err
+	}
+	instanceID, bindingID := positional[0], positional[1]
+	s, err := ctx.loadState()
+	if err != nil {
+		return err
+	}
+	if err := resolveInstance(s, instanceID, serviceID, planID); err != nil {
+		return err
+	}
+	client, err := ctx.client()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Unbind(&osb.UnbindRequest{
+		InstanceID: instanceID,
+		BindingID:  bindingID,
+		ServiceID:  *serviceID,
+		PlanID:     *planID,
+	})
+	if err != nil && !osb.IsGoneError(err) {
+		return fmt.Errorf("failed to unbind: %w", err)
+	}
+
+	s.removeBinding(bindingID)
+	if err := ctx.saveState(s); err != nil {
+		return err
+	}
+	fmt.Fprintf(ctx.stdout, "binding %s: unbound\n", bindingID)
+	return nil
+}
+
+func runStatus(ctx *commandContext, args []string) error {
+	positional, err := ctx.parse(args, 1, 2)
+	if err != nil {
+		return err
+	}
+	client, err := ctx.client()
+	if err != nil {
+		return err
+	}
+
+	instanceID := positional[0]
+	var state *osb.LastOperationResponse
+	if len(positional) == 2 {
+		state, err = client.PollBindingLastOperation(&osb.BindingLastOperationRequest{
+			InstanceID: instanceID,
+			BindingID:  positional[1],
+		})
+	} else {
+		state, err = client.PollLastOperation(&osb.LastOperationRequest{InstanceID: instanceID})
+	}
+	if osb.IsGoneError(err) {
+		fmt.Fprintln(ctx.stdout, "gone")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the last operation: %w", err)
+	}
+	fmt.Fprintf(ctx.stdout, "%s: %s\n", state.State, description(state))
+	return nil
+}
+
+func runInstances(ctx *commandContext, args []string) error {
+	if _, err := ctx.parse(args, 0, 0); err != nil {
+		return err
+	}
+	s, err := ctx.loadState()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSERVICE\tPLAN\tNAMESPACE\tBROKER\tCREATED")
+	for _, record := range s.Instances {
+		if ctx.url != "" && record.BrokerURL != ctx.url {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			record.ID, record.ServiceID, record.PlanID, record.Namespace, record.BrokerURL,
+			record.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+func runBindings(ctx *commandContext, args []string) error {
+	positional, err := ctx.parse(args, 0, 1)
+	if err != nil {
+		return err
+	}
+	s, err := ctx.loadState()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(ctx.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tINSTANCE\tCREATED")
+	for _, record := range s.Bindings {
+		if len(positional) == 1 && record.InstanceID != positional[0] {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", record.ID, record.InstanceID, record.CreatedAt.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// resolvePlan resolves the service and plan IDs from their IDs or names in the catalog.
+func resolvePlan(client osb.Client, service, plan string) (string, string, error) {
+	catalog, err := client.GetCatalog()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get the catalog: %w", err)
+	}
+	for _, s := range catalog.Services {
+		if s.ID != service && s.Name != service {
+			continue
+		}
+		for _, p := range s.Plans {
+			if p.ID == plan || p.Name == plan {
+				return s.ID, p.ID, nil
+			}
+		}
+		return "", "", fmt.Errorf("plan %q not found for service %q", plan, service)
+	}
+	return "", "", fmt.Errorf("service %q not found", service)
+}
+
+// resolveInstance fills the service and plan IDs of an instance from the state file, unless set.
+func resolveInstance(s *state, instanceID string, serviceID, planID *string) error {
+	if *serviceID != "" && *planID != "" {
+		return nil
+	}
+	record, ok := s.instance(instanceID)
+	if !ok {
+		return fmt.Errorf("instance %q is not in the state file: set --service and --plan", instanceID)
+	}
+	if *serviceID == "" {
+		*serviceID = record.ServiceID
+	}
+	if *planID == "" {
+		*planID = record.PlanID
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The credentials output formats.
+const (
+	outputEnv    = "env"
+	outputJSON   = "json"
+	outputSecret = "secret"
+)
+
+var invalidEnvChars = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// credentialValue returns the string value of a credential. Non-string values are encoded as JSON.
+func credentialValue(value interface{}) (string, error) {
+	if str, ok := value.(string); ok {
+		return str, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// envName converts a credential key into an environment variable name.
+func envName(key string) string {
+	name := invalidEnvChars.ReplaceAllString(strings.ToUpper(key), "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// shellQuote quotes a value for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// writeCredentials writes the credentials in the given format. The secret format uses the name and
+// namespace for the Secret manifest.
+func writeCredentials(w io.Writer, creds map[string]interface{}, format, name, namespace string) error {
+	keys := make([]string, 0, len(creds))
+	for key := range creds {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	switch format {
+	case outputEnv:
+		for _, key := range keys {
+			value, err := credentialValue(creds[key])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s=%s\n", envName(key), shellQuote(value))
+		}
+		return nil
+	case outputJSON:
+		data, err := json.MarshalIndent(creds, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case outputSecret:
+		secret := corev1.Secret{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Type:       corev1.SecretTypeOpaque,
+			StringData: make(map[string]string, len(creds)),
+		}
+		for _, key := range keys {
+			value, err := credentialValue(creds[key])
+			if err != nil {
+				return err
+			}
+			secret.StringData[key] = value
+		}
+		data, err := yaml.Marshal(secret)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	default:
+		return fmt.Errorf("unknown output format %q: use %s, %s or %s", format, outputEnv, outputJSON, outputSecret)
+	}
+}
+
+// paramsFlag is a repeatable flag collecting key=value parameters. Dotted keys set nested values,
+// and values are decoded as JSON when valid, e.g. numbers and booleans.
+type paramsFlag map[string]interface{}
+
+func (p paramsFlag) String() string {
+	return ""
+}
+
+func (p paramsFlag) Set(param string) error {
+	parts := strings.SplitN(param, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return fmt.Errorf("the parameter must be in the format key=value")
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(parts[1]), &value); err != nil {
+		value = parts[1]
+	}
+
+	keys := strings.Split(parts[0], ".")
+	current := map[string]interface{}(p)
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+	return nil
+}
+
+// parameters merges the JSON parameters with the key=value parameters, which take precedence.
+func parameters(paramsJSON string, params paramsFlag) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	if paramsJSON != "" {
+		if err := json.Unmarshal([]byte(paramsJSON), &merged); err != nil {
+			return nil, fmt.Errorf("failed to parse the JSON parameters: %w", err)
+		}
+	}
+	mergeParameters(merged, params)
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+func mergeParameters(dst, src map[string]interface{}) {
+	for k, v := range src {
+		srcMap, srcIsMap := v.(map[string]interface{})
+		dstMap, dstIsMap := dst[k].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeParameters(dstMap, srcMap)
+			continue
+		}
+		dst[k] = v
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The OSB API does not list instances and bindings, so the client records those it creates.
+
+// instanceRecord records an instance provisioned with the client.
+type instanceRecord struct {
+	ID        string    `json:"id"`
+	BrokerURL string    `json:"brokerURL"`
+	ServiceID string    `json:"serviceID"`
+	PlanID    string    `json:"planID"`
+	Namespace string    `json:"namespace,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// bindingRecord records a binding created with the client.
+type bindingRecord struct {
+	ID         string    `json:"id"`
+	InstanceID string    `json:"instanceID"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// state is the content of the state file.
+type state struct {
+	Instances []instanceRecord `json:"instances"`
+	Bindings  []bindingRecord  `json:"bindings"`
+}
+
+// stateFilePath returns the path of the state file, defaulting to the user config directory.
+func (ctx *commandContext) stateFilePath() (string, error) {
+	if ctx.stateFile != "" {
+		return ctx.stateFile, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the state file: %w", err)
+	}
+	return filepath.Join(configDir, "minibroker", "state.json"), nil
+}
+
+// loadState loads the state file. A missing file is an empty state.
+func (ctx *commandContext) loadState() (*state, error) {
+	path, err := ctx.stateFilePath()
+	if err != nil {
+		return nil, err
+	}
+	s := &state{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, fmt.Errorf("failed to load the state file: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to load the state file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// saveState saves the state file.
+func (ctx *commandContext) saveState(s *state) error {
+	path, err := ctx.stateFilePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to save the state file: %w", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to save the state file: %w", err)
+	}
+	return nil
+}
+
+// instance returns the record of an instance, if any.
+func (s *state) instance(instanceID string) (instanceRecord, bool) {
+	for _, record := range s.Instances {
+		if record.ID == instanceID {
+			return record, true
+		}
+	}
+	return instanceRecord{}, false
+}
+
+// removeInstance removes the record of an instance and of its bindings.
+func (s *state) removeInstance(instanceID string) {
+	instances := make([]instanceRecord, 0, len(s.Instances))
+	for _, record := range s.Instances {
+		if record.ID != instanceID {
+			instances = append(instances, record)
+		}
+	}
+	s.Instances = instances
+
+	bindings := make([]bindingRecord, 0, len(s.Bindings))
+	for _, record := range s.Bindings {
+		if record.InstanceID != instanceID {
+			bindings = append(bindings, record)
+		}
+	}
+	s.Bindings = bindings
+}
+
+// removeBinding removes the record of a binding.
+func (s *state) removeBinding(bindingID string) {
+	bindings := make([]bindingRecord, 0, len(s.Bindings))
+	for _, record := range s.Bindings {
+		if record.ID != bindingID {
+			bindings = append(bindings, record)
+		}
+	}
+	s.Bindings = bindings
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+)
+
+// DefaultTimeout is how long a single check may run before it is reported as failed.
+const DefaultTimeout = 5 * time.Second
+
+// The statuses of the checks and of the whole report.
+const (
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// Check is a named dependency check. Run returns nil when the dependency is available.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// Result is the outcome of a single check.
+type Result struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the outcome of all the checks. Status is StatusOK only when every check passed.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []Result `json:"checks"`
+}
+
+// Checker runs the checks concurrently and serves the report over HTTP.
+type Checker struct {
+	timeout time.Duration
+	checks  []Check
+}
+
+var _ http.Handler = &Checker{}
+
+// NewChecker creates a new Checker. A zero timeout uses DefaultTimeout.
+func NewChecker(timeout time.Duration, checks ...Check) *Checker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Checker{timeout: timeout, checks: checks}
+}
+
+// Run runs all the checks and returns their results in the order the checks were given.
+func (c *Checker) Run(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := Report{Status: StatusOK, Checks: make([]Result, len(c.checks))}
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusFailed
+		}
+	}
+	return report
+}
+
+// run runs a single check, giving up when the context is done even if the check does not honour
+// it.
+func run(ctx context.Context, check Check) Result {
+	done := make(chan error, 1)
+	go func() {
+		done <- check.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		log.FromContext(ctx).WithName("health").V(2).Info("check failed", "check", check.Name, "error", err)
+		return Result{Name: check.Name, Status: StatusFailed, Error: err.Error()}
+	}
+	return Result{Name: check.Name, Status: StatusOK}
+}
+
+// ServeHTTP runs the checks and writes the report as JSON, with a 200 status when all the checks
+// passed and 503 otherwise.
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context())
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.FromContext(r.Context()).WithName("health").V(2).Info("failed to write the report", "error", err)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func passing(name string) Check {
+	return Check{Name: name, Run: func(context.Context) error { return nil }}
+}
+
+func failing(name string) Check {
+	return Check{Name: name, Run: func(context.Context) error { return errors.New("unavailable") }}
+}
+
+func serve(t *testing.T, checker *Checker) (int, Report) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	checker.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode the report %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+func TestCheckerReady(t *testing.T) {
+	code, report := serve(t, NewChecker(0, passing("a"), passing("b")))
+	if code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", code)
+	}
+	if report.Status != StatusOK {
+		t.Errorf("expected report status %q, got %q", StatusOK, report.Status)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Name != "a" || report.Checks[1].Name != "b" {
+		t.Errorf("unexpected checks %+v", report.Checks)
+	}
+}
+
+func TestCheckerNotReady(t *testing.T) {
+	code, report := serve(t, NewChecker(0, passing("a"), failing("b")))
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", code)
+	}
+	if report.Status != StatusFailed {
+		t.Errorf("expected report status %q, got %q", StatusFailed, report.Status)
+	}
+	expected := []Result{
+		{Name: "a", Status: StatusOK},
+		{Name: "b", Status: StatusFailed, Error: "unavailable"},
+	}
+	for i, result := range expected {
+		if report.Checks[i] != result {
+			t.Errorf("expected check %+v, got %+v", result, report.Checks[i])
+		}
+	}
+}
+
+func TestCheckerTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	defer close(blocked)
+	hanging := Check{Name: "hanging", Run: func(context.Context) error {
+		<-blocked
+		return nil
+	}}
+
+	report := NewChecker(10*time.Millisecond, hanging).Run(context.Background())
+	if report.Status != StatusFailed {
+		t.Fatalf("expected report status %q, got %q", StatusFailed, report.Status)
+	}
+	if report.Checks[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("expected a deadline error, got %q", report.Checks[0].Error)
+	}
+}
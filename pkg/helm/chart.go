@@ -17,10 +17,12 @@ limitations under the License.
 package helm
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 
+	"go.opentelemetry.io/otel/attribute"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/chart/loader"
@@ -29,6 +31,7 @@ import (
 
 	"github.com/kubernetes-sigs/minibroker/pkg/log"
 	"github.com/kubernetes-sigs/minibroker/pkg/nameutil"
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
 )
 
 // ChartClient allows users of this client to install and uninstall charts.
@@ -41,11 +44,17 @@ type ChartClient struct {
 
 // NewDefaultChartClient creates a new ChartClient with the default dependencies.
 func NewDefaultChartClient() *ChartClient {
+	return NewChartClientForConfig(NewDefaultConfigProvider())
+}
+
+// NewChartClientForConfig creates a new ChartClient with the default dependencies, using the
+// ConfigProvider for the Helm actions.
+func NewChartClientForConfig(configProvider ConfigProvider) *ChartClient {
 	return NewChartClient(
 		log.NewKlog(),
 		NewDefaultChartManager(),
 		nameutil.NewDefaultNameGenerator(),
-		NewDefaultChartHelm(),
+		NewChartHelmForConfig(configProvider),
 	)
 }
 
@@ -64,12 +73,29 @@ func NewChartClient(
 	}
 }
 
+type releaseNotifierKey struct{}
+
+// WithReleaseNotifier returns a copy of the context on which Install calls notify with the name
+// and namespace of the release before installing it, e.g. for watching the release progress while
+// the install waits for its resources.
+func WithReleaseNotifier(ctx context.Context, notify func(release, namespace string)) context.Context {
+	return context.WithValue(ctx, releaseNotifierKey{}, notify)
+}
+
 // Install installs a chart version into a specific namespace using the provided values.
 func (cc *ChartClient) Install(
+	ctx context.Context,
 	chartDef *repo.ChartVersion,
 	namespace string,
 	values map[string]interface{},
-) (*release.Release, error) {
+) (rls *release.Release, err error) {
+	ctx, span := tracing.Start(ctx, "helm.ChartClient.Install",
+		attribute.String("chart.name", chartName(chartDef)),
+		attribute.String("chart.version", chartVersion(chartDef)),
+		attribute.String("release.namespace", namespace),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	if len(chartDef.URLs) == 0 {
 		err := fmt.Errorf("missing chart URL for %q", chartDef.Name)
 		return nil, fmt.Errorf("failed to install chart: %v", err)
@@ -77,9 +103,10 @@ func (cc *ChartClient) Install(
 	// TODO(f0rmiga): deal with multiple chart URLs.
 	chartURL := chartDef.URLs[0]
 
-	chartRequested, err := cc.chartLoader.Load(chartURL)
+	chartRequested, err := cc.chartLoader.Load(ctx, chartURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to install chart: %v", err)
+		chartDownloadFailuresTotal.WithLabelValues(chartName(chartDef)).Inc()
+		return nil, &Error{Class: ErrChartDownload, Err: fmt.Errorf("failed to install chart: %v", err)}
 	}
 
 	if chartRequested.Metadata.Deprecated {
@@ -101,36 +128,81 @@ func (cc *ChartClient) Install(
 		return nil, fmt.Errorf("failed to install chart: %v", err)
 	}
 
+	span.SetAttributes(attribute.String("release.name", releaseName))
+	if notify, ok := ctx.Value(releaseNotifierKey{}).(func(string, string)); ok {
+		notify(releaseName, namespace)
+	}
+	logger := log.FromContext(ctx).WithName("helm").WithValues("release", releaseName, "namespace", namespace)
+	logger.V(3).Info("installing chart", "chart", chartDef.Name, "chartVersion", chartDef.Version)
+
 	installer, err := cc.ChartHelmClientProvider.ProvideInstaller(releaseName, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to install chart: %v", err)
 	}
 
-	rls, err := installer(chartRequested, values)
+	// The installer renders the chart, applies the manifests and waits for the resources to be
+	// ready.
+	_, installSpan := tracing.Start(ctx, "helm.install")
+	rls, err = installer(chartRequested, values)
+	tracing.End(installSpan, err)
 	if err != nil {
-		return nil, fmt.Errorf("failed to install chart: %v", err)
+		logger.V(3).Info("failed to install chart", "error", err)
+		class := installErrorClass(err)
+		err = fmt.Errorf("failed to install chart: %v", err)
+		if class != nil {
+			return nil, &Error{Class: class, Release: releaseName, Namespace: namespace, Err: err}
+		}
+		return nil, err
 	}
 
+	logger.V(4).Info("installed chart")
 	return rls, nil
 }
 
+// chartName returns the name of a chart version, or an empty string when its metadata is missing.
+func chartName(chartDef *repo.ChartVersion) string {
+	if chartDef.Metadata == nil {
+		return ""
+	}
+	return chartDef.Name
+}
+
+// chartVersion returns the version of a chart version, or an empty string when its metadata is
+// missing.
+func chartVersion(chartDef *repo.ChartVersion) string {
+	if chartDef.Metadata == nil {
+		return ""
+	}
+	return chartDef.Version
+}
+
 // Uninstall uninstalls a release from a namespace.
-func (cc *ChartClient) Uninstall(releaseName, namespace string) error {
+func (cc *ChartClient) Uninstall(ctx context.Context, releaseName, namespace string) (err error) {
+	ctx, span := tracing.Start(ctx, "helm.ChartClient.Uninstall",
+		attribute.String("release.name", releaseName),
+		attribute.String("release.namespace", namespace),
+	)
+	defer func() { tracing.End(span, err) }()
+	logger := log.FromContext(ctx).WithName("helm").WithValues("release", releaseName, "namespace", namespace)
+	logger.V(3).Info("uninstalling release")
+
 	uninstaller, err := cc.ChartHelmClientProvider.ProvideUninstaller(namespace)
 	if err != nil {
 		return fmt.Errorf("failed to uninstall chart: %v", err)
 	}
 
 	if _, err := uninstaller(releaseName); err != nil {
+		logger.V(3).Info("failed to uninstall release", "error", err)
 		return fmt.Errorf("failed to uninstall chart: %v", err)
 	}
 
+	logger.V(4).Info("uninstalled release")
 	return nil
 }
 
 // ChartLoader is the interface that wraps the Load method.
 type ChartLoader interface {
-	Load(chartURL string) (*chart.Chart, error)
+	Load(ctx context.Context, chartURL string) (*chart.Chart, error)
 }
 
 // ChartManager satisfies the ChartLoader interface.
@@ -160,7 +232,10 @@ func NewChartManager(
 
 // Load loads a chart from a URL.
 // TODO(f0rmiga): implement caching for chart archives.
-func (cm *ChartManager) Load(chartURL string) (*chart.Chart, error) {
+func (cm *ChartManager) Load(ctx context.Context, chartURL string) (_ *chart.Chart, err error) {
+	_, span := tracing.Start(ctx, "helm.ChartManager.Load", attribute.String("chart.url", chartURL))
+	defer func() { tracing.End(span, err) }()
+
 	chartResp, err := cm.httpGetter.Get(chartURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load chart: %v", err)
@@ -191,8 +266,14 @@ type ChartHelm struct {
 
 // NewDefaultChartHelm creates a new ChartHelm with the default dependencies.
 func NewDefaultChartHelm() *ChartHelm {
+	return NewChartHelmForConfig(NewDefaultConfigProvider())
+}
+
+// NewChartHelmForConfig creates a new ChartHelm with the default dependencies, using the
+// ConfigProvider for the Helm actions.
+func NewChartHelmForConfig(configProvider ConfigProvider) *ChartHelm {
 	return NewChartHelm(
-		NewDefaultConfigProvider(),
+		configProvider,
 		action.NewInstall,
 		action.NewUninstall,
 	)
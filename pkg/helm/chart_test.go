@@ -17,6 +17,8 @@ limitations under the License.
 package helm_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,15 +29,21 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/apimachinery/pkg/util/wait"
 
 	"github.com/kubernetes-sigs/minibroker/pkg/helm"
 	"github.com/kubernetes-sigs/minibroker/pkg/helm/mocks"
 	"github.com/kubernetes-sigs/minibroker/pkg/log"
 	nameutilmocks "github.com/kubernetes-sigs/minibroker/pkg/nameutil/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
 )
 
 //go:generate mockgen -destination=./mocks/mock_testutil_chart.go -package=mocks github.com/kubernetes-sigs/minibroker/pkg/helm/testutil ChartInstallRunner,ChartUninstallRunner
@@ -43,6 +51,27 @@ import (
 //go:generate mockgen -destination=./mocks/mock_http.go -package=mocks github.com/kubernetes-sigs/minibroker/pkg/helm HTTPGetter
 //go:generate mockgen -destination=./mocks/mock_io.go -package=mocks io ReadCloser
 
+// chartDownloadFailures returns the chart download failures counted for a chart.
+func chartDownloadFailures(name string) float64 {
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(helm.Collectors()...)
+	families, err := reg.Gather()
+	Expect(err).NotTo(HaveOccurred())
+	for _, family := range families {
+		if family.GetName() != "minibroker_chart_download_failures_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "chart" && label.GetValue() == name {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
 var _ = Describe("Chart", func() {
 	var ctrl *gomock.Controller
 
@@ -69,7 +98,7 @@ var _ = Describe("Chart", func() {
 					Metadata: &chart.Metadata{Name: "foo"},
 					URLs:     make([]string, 0),
 				}
-				release, err := client.Install(chartDef, "", nil)
+				release, err := client.Install(context.Background(), chartDef, "", nil)
 				Expect(err).To(Equal(fmt.Errorf("failed to install chart: missing chart URL for \"foo\"")))
 				Expect(release).To(BeNil())
 			})
@@ -78,21 +107,54 @@ var _ = Describe("Chart", func() {
 				chartURL := "https://foo/bar.tar.gz"
 				chartLoader := mocks.NewMockChartLoader(ctrl)
 				chartLoader.EXPECT().
-					Load(chartURL).
+					Load(gomock.Any(), chartURL).
 					Return(nil, fmt.Errorf("error from chart loader")).
 					Times(1)
 				client := helm.NewChartClient(log.NewNoop(), chartLoader, nil, nil)
-				chartDef := &repo.ChartVersion{URLs: []string{chartURL}}
-				release, err := client.Install(chartDef, "", nil)
-				Expect(err).To(Equal(fmt.Errorf("failed to install chart: error from chart loader")))
+				chartDef := &repo.ChartVersion{
+					Metadata: &chart.Metadata{Name: "download-failure"},
+					URLs:     []string{chartURL},
+				}
+				failures := chartDownloadFailures("download-failure")
+				release, err := client.Install(context.Background(), chartDef, "", nil)
+				Expect(err).To(MatchError("failed to install chart: error from chart loader"))
+				Expect(err).To(MatchError(helm.ErrChartDownload))
 				Expect(release).To(BeNil())
+				Expect(chartDownloadFailures("download-failure")).To(Equal(failures + 1))
+			})
+
+			It("should trace the install and pass the span to the chart loader", func() {
+				exporter := tracing.SetupInMemory()
+				var loadSpan trace.SpanContext
+				chartLoader := mocks.NewMockChartLoader(ctrl)
+				chartLoader.EXPECT().
+					Load(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ string) (*chart.Chart, error) {
+						loadSpan = trace.SpanContextFromContext(ctx)
+						return nil, fmt.Errorf("error from chart loader")
+					}).
+					Times(1)
+				client := helm.NewChartClient(log.NewNoop(), chartLoader, nil, nil)
+				chartDef := &repo.ChartVersion{
+					Metadata: &chart.Metadata{Name: "foo", Version: "1.2.3"},
+					URLs:     []string{"https://foo/bar.tar.gz"},
+				}
+				_, err := client.Install(context.Background(), chartDef, "", nil)
+				Expect(err).To(HaveOccurred())
+
+				spans := exporter.GetSpans()
+				Expect(spans).To(HaveLen(1))
+				Expect(spans[0].Name).To(Equal("helm.ChartClient.Install"))
+				Expect(spans[0].Status.Code).To(Equal(codes.Error))
+				Expect(spans[0].Attributes).To(ContainElement(attribute.String("chart.version", "1.2.3")))
+				Expect(loadSpan.SpanID()).To(Equal(spans[0].SpanContext.SpanID()))
 			})
 
 			It("should fail when the name generator fails", func() {
 				chartRequested := &chart.Chart{Metadata: &chart.Metadata{Deprecated: false}}
 				chartLoader := mocks.NewMockChartLoader(ctrl)
 				chartLoader.EXPECT().
-					Load(gomock.Any()).
+					Load(gomock.Any(), gomock.Any()).
 					Return(chartRequested, nil).
 					Times(1)
 				nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
@@ -105,7 +167,7 @@ var _ = Describe("Chart", func() {
 					Metadata: &chart.Metadata{Name: "foo"},
 					URLs:     []string{"https://foo/bar.tar.gz"},
 				}
-				release, err := client.Install(chartDef, "", nil)
+				release, err := client.Install(context.Background(), chartDef, "", nil)
 				Expect(err).To(Equal(fmt.Errorf("failed to install chart: error from name generator")))
 				Expect(release).To(BeNil())
 			})
@@ -115,7 +177,7 @@ var _ = Describe("Chart", func() {
 				releaseName := strings.Repeat("x", 54)
 				chartLoader := mocks.NewMockChartLoader(ctrl)
 				chartLoader.EXPECT().
-					Load(gomock.Any()).
+					Load(gomock.Any(), gomock.Any()).
 					Return(chartRequested, nil).
 					Times(1)
 				nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
@@ -128,7 +190,7 @@ var _ = Describe("Chart", func() {
 					Metadata: &chart.Metadata{Name: "foo"},
 					URLs:     []string{"https://foo/bar.tar.gz"},
 				}
-				release, err := client.Install(chartDef, "", nil)
+				release, err := client.Install(context.Background(), chartDef, "", nil)
 				Expect(err).To(Equal(fmt.Errorf("failed to install chart: invalid release name %q: names cannot exceed 53 characters", releaseName)))
 				Expect(release).To(BeNil())
 			})
@@ -139,7 +201,7 @@ var _ = Describe("Chart", func() {
 				chartRequested := &chart.Chart{Metadata: &chart.Metadata{Deprecated: false}}
 				chartLoader := mocks.NewMockChartLoader(ctrl)
 				chartLoader.EXPECT().
-					Load(gomock.Any()).
+					Load(gomock.Any(), gomock.Any()).
 					Return(chartRequested, nil).
 					Times(1)
 				nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
@@ -157,7 +219,7 @@ var _ = Describe("Chart", func() {
 					Metadata: &chart.Metadata{Name: "foo"},
 					URLs:     []string{"https://foo/bar.tar.gz"},
 				}
-				release, err := client.Install(chartDef, namespace, nil)
+				release, err := client.Install(context.Background(), chartDef, namespace, nil)
 				Expect(err).To(Equal(fmt.Errorf("failed to install chart: error from client provider")))
 				Expect(release).To(BeNil())
 			})
@@ -169,7 +231,7 @@ var _ = Describe("Chart", func() {
 				values := map[string]interface{}{"bar": "baz"}
 				chartLoader := mocks.NewMockChartLoader(ctrl)
 				chartLoader.EXPECT().
-					Load(gomock.Any()).
+					Load(gomock.Any(), gomock.Any()).
 					Return(chartRequested, nil).
 					Times(1)
 				nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
@@ -192,11 +254,100 @@ var _ = Describe("Chart", func() {
 					Metadata: &chart.Metadata{Name: "foo"},
 					URLs:     []string{"https://foo/bar.tar.gz"},
 				}
-				release, err := client.Install(chartDef, namespace, values)
+				release, err := client.Install(context.Background(), chartDef, namespace, values)
 				Expect(err).To(Equal(fmt.Errorf("failed to install chart: error from client install runner")))
 				Expect(release).To(BeNil())
 			})
 
+			It("should notify the release before installing it", func() {
+				releaseName := "foo-12345"
+				namespace := "foo-namespace"
+				chartRequested := &chart.Chart{Metadata: &chart.Metadata{Deprecated: false}}
+				chartLoader := mocks.NewMockChartLoader(ctrl)
+				chartLoader.EXPECT().
+					Load(gomock.Any(), gomock.Any()).
+					Return(chartRequested, nil).
+					Times(1)
+				nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
+				nameGenerator.EXPECT().
+					Generate(gomock.Any()).
+					Return(releaseName, nil).
+					Times(1)
+				var notified []string
+				installRunner := mocks.NewMockChartInstallRunner(ctrl)
+				installRunner.EXPECT().
+					ChartInstallRunner(chartRequested, gomock.Any()).
+					DoAndReturn(func(*chart.Chart, map[string]interface{}) (*release.Release, error) {
+						Expect(notified).To(Equal([]string{releaseName, namespace}))
+						return &release.Release{Name: releaseName}, nil
+					}).
+					Times(1)
+				chartHelmClientProvider := mocks.NewMockChartHelmClientProvider(ctrl)
+				chartHelmClientProvider.EXPECT().
+					ProvideInstaller(releaseName, namespace).
+					Return(installRunner.ChartInstallRunner, nil).
+					Times(1)
+				client := helm.NewChartClient(log.NewNoop(), chartLoader, nameGenerator, chartHelmClientProvider)
+				chartDef := &repo.ChartVersion{
+					Metadata: &chart.Metadata{Name: "foo"},
+					URLs:     []string{"https://foo/bar.tar.gz"},
+				}
+				ctx := helm.WithReleaseNotifier(context.Background(), func(release, namespace string) {
+					notified = append(notified, release, namespace)
+				})
+				_, err := client.Install(ctx, chartDef, namespace, nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(notified).To(Equal([]string{releaseName, namespace}))
+			})
+
+			It("should classify the install client failures", func() {
+				releaseName := "foo-12345"
+				namespace := "foo-namespace"
+				tests := []struct {
+					err   error
+					class error
+				}{
+					{err: fmt.Errorf("release %s failed: %w", releaseName, wait.ErrWaitTimeout), class: helm.ErrReleaseTimeout},
+					{err: fmt.Errorf("template: foo/templates/secret.yaml:7:20: executing \"foo\": nil pointer"), class: helm.ErrChartRender},
+					{err: fmt.Errorf("unable to build kubernetes objects from release manifest: invalid"), class: helm.ErrChartRender},
+				}
+				for _, tt := range tests {
+					chartRequested := &chart.Chart{Metadata: &chart.Metadata{Deprecated: false}}
+					chartLoader := mocks.NewMockChartLoader(ctrl)
+					chartLoader.EXPECT().
+						Load(gomock.Any(), gomock.Any()).
+						Return(chartRequested, nil).
+						Times(1)
+					nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
+					nameGenerator.EXPECT().
+						Generate(gomock.Any()).
+						Return(releaseName, nil).
+						Times(1)
+					installRunner := mocks.NewMockChartInstallRunner(ctrl)
+					installRunner.EXPECT().
+						ChartInstallRunner(chartRequested, gomock.Any()).
+						Return(nil, tt.err).
+						Times(1)
+					chartHelmClientProvider := mocks.NewMockChartHelmClientProvider(ctrl)
+					chartHelmClientProvider.EXPECT().
+						ProvideInstaller(releaseName, namespace).
+						Return(installRunner.ChartInstallRunner, nil).
+						Times(1)
+					client := helm.NewChartClient(log.NewNoop(), chartLoader, nameGenerator, chartHelmClientProvider)
+					chartDef := &repo.ChartVersion{
+						Metadata: &chart.Metadata{Name: "foo"},
+						URLs:     []string{"https://foo/bar.tar.gz"},
+					}
+					_, err := client.Install(context.Background(), chartDef, namespace, nil)
+					Expect(err).To(MatchError(fmt.Sprintf("failed to install chart: %v", tt.err)))
+					Expect(err).To(MatchError(tt.class))
+					var helmErr *helm.Error
+					Expect(errors.As(err, &helmErr)).To(BeTrue())
+					Expect(helmErr.Release).To(Equal(releaseName))
+					Expect(helmErr.Namespace).To(Equal(namespace))
+				}
+			})
+
 			Describe("Succeeding", func() {
 				tests := []struct {
 					title      string
@@ -222,7 +373,7 @@ var _ = Describe("Chart", func() {
 						values := map[string]interface{}{"bar": "baz"}
 						chartLoader := mocks.NewMockChartLoader(ctrl)
 						chartLoader.EXPECT().
-							Load(gomock.Any()).
+							Load(gomock.Any(), gomock.Any()).
 							Return(chartRequested, nil).
 							Times(1)
 						nameGenerator := nameutilmocks.NewMockGenerator(ctrl)
@@ -245,7 +396,7 @@ var _ = Describe("Chart", func() {
 							Metadata: &chart.Metadata{Name: "foo"},
 							URLs:     []string{"https://foo/bar.tar.gz"},
 						}
-						release, err := client.Install(chartDef, namespace, values)
+						release, err := client.Install(context.Background(), chartDef, namespace, values)
 						Expect(err).NotTo(HaveOccurred())
 						Expect(release).To(Equal(expectedRelease))
 					})
@@ -263,7 +414,7 @@ var _ = Describe("Chart", func() {
 					Return(nil, fmt.Errorf("error from client provider")).
 					Times(1)
 				client := helm.NewChartClient(log.NewNoop(), nil, nil, chartHelmClientProvider)
-				err := client.Uninstall(releaseName, namespace)
+				err := client.Uninstall(context.Background(), releaseName, namespace)
 				Expect(err).To(Equal(fmt.Errorf("failed to uninstall chart: error from client provider")))
 			})
 
@@ -281,7 +432,7 @@ var _ = Describe("Chart", func() {
 					Return(uninstallRunner.ChartUninstallRunner, nil).
 					Times(1)
 				client := helm.NewChartClient(log.NewNoop(), nil, nil, chartHelmClientProvider)
-				err := client.Uninstall(releaseName, namespace)
+				err := client.Uninstall(context.Background(), releaseName, namespace)
 				Expect(err).To(Equal(fmt.Errorf("failed to uninstall chart: error from client uninstall runner")))
 			})
 
@@ -299,7 +450,7 @@ var _ = Describe("Chart", func() {
 					Return(uninstallRunner.ChartUninstallRunner, nil).
 					Times(1)
 				client := helm.NewChartClient(log.NewNoop(), nil, nil, chartHelmClientProvider)
-				err := client.Uninstall(releaseName, namespace)
+				err := client.Uninstall(context.Background(), releaseName, namespace)
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
@@ -322,7 +473,7 @@ var _ = Describe("Chart", func() {
 					Return(nil, fmt.Errorf("http error")).
 					Times(1)
 				chartManager := helm.NewChartManager(httpGetter, nil)
-				chart, err := chartManager.Load(chartURL)
+				chart, err := chartManager.Load(context.Background(), chartURL)
 				Expect(err).To(Equal(fmt.Errorf("failed to load chart: http error")))
 				Expect(chart).To(BeNil())
 			})
@@ -344,7 +495,7 @@ var _ = Describe("Chart", func() {
 					return nil, fmt.Errorf("load chart archive error")
 				}
 				chartManager := helm.NewChartManager(httpGetter, loadChartArchive)
-				chart, err := chartManager.Load(chartURL)
+				chart, err := chartManager.Load(context.Background(), chartURL)
 				Expect(err).To(Equal(fmt.Errorf("failed to load chart: load chart archive error")))
 				Expect(chart).To(BeNil())
 			})
@@ -371,7 +522,7 @@ var _ = Describe("Chart", func() {
 					return expectedChart, nil
 				}
 				chartManager := helm.NewChartManager(httpGetter, loadChartArchive)
-				chart, err := chartManager.Load(chartURL)
+				chart, err := chartManager.Load(context.Background(), chartURL)
 				Expect(err).NotTo(HaveOccurred())
 				Expect(chart).To(Equal(expectedChart))
 			})
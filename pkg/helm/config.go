@@ -22,6 +22,7 @@ import (
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/kube"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/kubernetes-sigs/minibroker/pkg/log"
 )
@@ -39,23 +40,60 @@ type ConfigProvider func(namespace string) (*action.Configuration, error)
 
 // NewDefaultConfigProvider creates a new ConfigProvider using the default dependencies.
 func NewDefaultConfigProvider() ConfigProvider {
+	return NewKubeConfigProvider(defaultKubeConfig, defaultContext)
+}
+
+// NewKubeConfigProvider creates a new ConfigProvider using the default dependencies and the given
+// kubeconfig path and context. Empty values mean the internal defaults will be used.
+func NewKubeConfigProvider(kubeConfig, context string) ConfigProvider {
 	return NewConfigProvider(
 		log.NewKlog(),
 		DefaultConfigInitializerProvider,
-		defaultKubeConfig,
-		defaultContext,
+		kubeConfig,
+		context,
 	)
 }
 
+// NewKubeConfigDataProvider creates a new ConfigProvider using the default dependencies and the
+// given kubeconfig content and context, e.g. for reaching a cluster whose kubeconfig is stored in a
+// Secret. An empty context means the current context of the kubeconfig will be used.
+func NewKubeConfigDataProvider(kubeConfig []byte, context string) (ConfigProvider, error) {
+	rawConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	return newConfigProvider(
+		log.NewKlog(),
+		DefaultConfigInitializerProvider,
+		func(namespace string) genericclioptions.RESTClientGetter {
+			return newKubeConfigGetter(*rawConfig, context, namespace)
+		},
+	), nil
+}
+
 // NewConfigProvider creates a new ConfigProvider using the explicit dependencies.
 func NewConfigProvider(
 	log log.Verboser,
 	configInitializerProvider ConfigInitializerProvider,
 	kubeConfig string,
 	context string,
+) ConfigProvider {
+	return newConfigProvider(
+		log,
+		configInitializerProvider,
+		func(namespace string) genericclioptions.RESTClientGetter {
+			return kube.GetConfig(kubeConfig, context, namespace)
+		},
+	)
+}
+
+func newConfigProvider(
+	log log.Verboser,
+	configInitializerProvider ConfigInitializerProvider,
+	restGetterProvider func(namespace string) genericclioptions.RESTClientGetter,
 ) ConfigProvider {
 	return func(namespace string) (*action.Configuration, error) {
-		restGetter := kube.GetConfig(kubeConfig, context, namespace)
+		restGetter := restGetterProvider(namespace)
 		debug := func(format string, v ...interface{}) {
 			log.V(4).Log("helm client: "+format, v...)
 		}
@@ -43,6 +43,44 @@ var _ = Describe("Config", func() {
 			})
 		})
 
+		Describe("NewKubeConfigProvider", func() {
+			It("should return a new ConfigProvider", func() {
+				var config helm.ConfigProvider = helm.NewKubeConfigProvider("/path/to/kubeconfig", "my-context")
+				Expect(config).NotTo(BeNil())
+			})
+		})
+
+		Describe("NewKubeConfigDataProvider", func() {
+			It("should return a new ConfigProvider", func() {
+				kubeConfig := []byte(`apiVersion: v1
+kind: Config
+current-context: remote
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example.com
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: broker
+users:
+- name: broker
+  user:
+    token: token
+`)
+				config, err := helm.NewKubeConfigDataProvider(kubeConfig, "remote")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config).NotTo(BeNil())
+			})
+
+			It("should fail when the kubeconfig is invalid", func() {
+				config, err := helm.NewKubeConfigDataProvider([]byte("{"), "")
+				Expect(err).To(MatchError(ContainSubstring("failed to load kubeconfig")))
+				Expect(config).To(BeNil())
+			})
+		})
+
 		Describe("ConfigProvider", func() {
 			var ctrl *gomock.Controller
 
@@ -0,0 +1,77 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"errors"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The classes of the chart operation errors, matched with errors.Is.
+var (
+	// ErrChartNotFound is the class of the charts or chart versions missing from the index.
+	ErrChartNotFound = errors.New("chart not found")
+	// ErrChartDownload is the class of the charts failing to download or load.
+	ErrChartDownload = errors.New("chart download failed")
+	// ErrChartRender is the class of the charts failing to render into valid manifests.
+	ErrChartRender = errors.New("chart rendering failed")
+	// ErrReleaseTimeout is the class of the releases whose resources did not become ready in time.
+	ErrReleaseTimeout = errors.New("release readiness timed out")
+)
+
+// Error is a classified chart operation error. It keeps the message of the underlying error.
+type Error struct {
+	// The class of the error, one of the Err* variables.
+	Class error
+	// The release and its namespace, when the error happened after the release name was chosen.
+	Release   string
+	Namespace string
+	Err       error
+}
+
+// Error returns the message of the underlying error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the class of the error.
+func (e *Error) Is(target error) bool {
+	return target == e.Class
+}
+
+// installErrorClass returns the class of an error of the Helm install action, or nil when it is not
+// a known class. Helm does not type these errors, so they are recognized by their message.
+func installErrorClass(err error) error {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, wait.ErrWaitTimeout) || strings.Contains(msg, wait.ErrWaitTimeout.Error()):
+		return ErrReleaseTimeout
+	case strings.Contains(msg, "template: "),
+		strings.Contains(msg, "parse error"),
+		strings.Contains(msg, "render error"),
+		strings.Contains(msg, "unable to build kubernetes objects"):
+		return ErrChartRender
+	}
+	return nil
+}
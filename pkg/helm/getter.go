@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// kubeConfigGetter is a genericclioptions.RESTClientGetter for an in-memory kubeconfig.
+type kubeConfigGetter struct {
+	clientConfig clientcmd.ClientConfig
+}
+
+var _ genericclioptions.RESTClientGetter = &kubeConfigGetter{}
+
+// newKubeConfigGetter creates a new kubeConfigGetter for the given kubeconfig context, overriding
+// the namespace.
+func newKubeConfigGetter(rawConfig clientcmdapi.Config, context, namespace string) *kubeConfigGetter {
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: context,
+		Context:        clientcmdapi.Context{Namespace: namespace},
+	}
+	return &kubeConfigGetter{
+		clientConfig: clientcmd.NewNonInteractiveClientConfig(rawConfig, context, overrides, nil),
+	}
+}
+
+// ToRESTConfig returns the REST configuration of the kubeconfig context.
+func (g *kubeConfigGetter) ToRESTConfig() (*rest.Config, error) {
+	return g.clientConfig.ClientConfig()
+}
+
+// ToDiscoveryClient returns a memory-cached discovery client for the kubeconfig context.
+func (g *kubeConfigGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	config, err := g.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(discoveryClient), nil
+}
+
+// ToRESTMapper returns a REST mapper backed by the discovery client.
+func (g *kubeConfigGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	discoveryClient, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
+	return restmapper.NewShortcutExpander(mapper, discoveryClient), nil
+}
+
+// ToRawKubeConfigLoader returns the client configuration of the kubeconfig context.
+func (g *kubeConfigGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.clientConfig
+}
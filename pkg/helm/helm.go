@@ -17,7 +17,10 @@ limitations under the License.
 package helm
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/getter"
@@ -40,16 +43,27 @@ type Client struct {
 	repositoryClient RepositoryInitializeDownloadLoader
 	chartClient      *ChartClient
 
-	settings  *cli.EnvSettings
-	chartRepo *repo.ChartRepository
+	settings *cli.EnvSettings
+	repoURL  string
+
+	// The chart repository and the time its index was loaded, replaced on every refresh.
+	mutex         sync.RWMutex
+	chartRepo     *repo.ChartRepository
+	indexLoadedAt time.Time
 }
 
 // NewDefaultClient creates a new Client with the default dependencies.
 func NewDefaultClient() *Client {
+	return NewClientForConfig(NewDefaultConfigProvider())
+}
+
+// NewClientForConfig creates a new Client with the default dependencies, using the ConfigProvider
+// for the Helm actions.
+func NewClientForConfig(configProvider ConfigProvider) *Client {
 	return NewClient(
 		log.NewKlog(),
 		NewDefaultRepositoryClient(),
-		NewDefaultChartClient(),
+		NewChartClientForConfig(configProvider),
 	)
 }
 
@@ -75,8 +89,6 @@ func NewClient(
 // Initialize initializes a chart repository.
 // TODO(f0rmiga): be able to handle multiple repositories. How can we handle charts with the same
 // name across repositories?
-// TODO(f0rmiga): add a readiness probe for this initialization process. A health endpoint would be
-// enough.
 func (c *Client) Initialize(repoURL string) error {
 	c.log.V(3).Log("helm client: initializing")
 
@@ -107,18 +119,53 @@ func (c *Client) Initialize(repoURL string) error {
 	}
 
 	chartRepo.IndexFile = indexFile
+	c.mutex.Lock()
+	c.repoURL = repoURL
 	c.chartRepo = chartRepo
+	c.indexLoadedAt = time.Now()
+	c.mutex.Unlock()
 
 	c.log.V(3).Log("helm client: successfully initialized")
 
 	return nil
 }
 
+// IndexLoadedAt returns when the chart repository index was last loaded, or the zero time when it
+// was never loaded.
+func (c *Client) IndexLoadedAt() time.Time {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.indexLoadedAt
+}
+
+// WatchIndex reloads the chart repository index on every interval until the context is done. The
+// current index is kept when reloading fails.
+func (c *Client) WatchIndex(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.mutex.RLock()
+			repoURL := c.repoURL
+			c.mutex.RUnlock()
+			if err := c.Initialize(repoURL); err != nil {
+				c.log.V(1).Log("helm client: keeping the current index: %v", err)
+			}
+		}
+	}
+}
+
 // ListCharts lists the charts from the chart repository.
 func (c *Client) ListCharts() map[string]repo.ChartVersions {
-	c.log.V(4).Log("helm client: listing charts from %s", c.chartRepo.Config.URL)
-	defer c.log.V(4).Log("helm client: listed charts from %s", c.chartRepo.Config.URL)
-	return c.chartRepo.IndexFile.Entries
+	c.mutex.RLock()
+	chartRepo := c.chartRepo
+	c.mutex.RUnlock()
+	c.log.V(4).Log("helm client: listing charts from %s", chartRepo.Config.URL)
+	defer c.log.V(4).Log("helm client: listed charts from %s", chartRepo.Config.URL)
+	return chartRepo.IndexFile.Entries
 }
 
 // GetChart gets a chart that exists in the chart repository. IndexFile.Get() cannot be used here
@@ -132,7 +179,7 @@ func (c *Client) GetChart(name, appVersion string) (*repo.ChartVersion, error) {
 	if !ok {
 		err := fmt.Errorf("chart not found: %s", name)
 		c.log.V(4).Log("helm client: %v", err)
-		return nil, fmt.Errorf("failed to get chart: %v", err)
+		return nil, &Error{Class: ErrChartNotFound, Err: fmt.Errorf("failed to get chart: %v", err)}
 	}
 
 	for _, v := range versions {
@@ -144,7 +191,7 @@ func (c *Client) GetChart(name, appVersion string) (*repo.ChartVersion, error) {
 
 	err := fmt.Errorf("chart app version not found for %q: %s", name, appVersion)
 	c.log.V(4).Log("helm client: %v", err)
-	return nil, fmt.Errorf("failed to get chart: %v", err)
+	return nil, &Error{Class: ErrChartNotFound, Err: fmt.Errorf("failed to get chart: %v", err)}
 }
 
 // ChartClient returns the chart client for installing and uninstalling a chart.
@@ -18,6 +18,7 @@ package helm_test
 
 import (
 	"fmt"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -50,6 +51,14 @@ var _ = Describe("Helm", func() {
 			})
 		})
 
+		Describe("NewClientForConfig", func() {
+			It("should create a new Client", func() {
+				client := helm.NewClientForConfig(helm.NewKubeConfigProvider("/path/to/kubeconfig", "my-context"))
+				Expect(client).NotTo(BeNil())
+				Expect(client.ChartClient()).NotTo(BeNil())
+			})
+		})
+
 		Describe("Initialize", func() {
 			It("should fail when repoInitializer.Initialize fails", func() {
 				repoClient := mocks.NewMockRepositoryInitializeDownloadLoader(ctrl)
@@ -136,8 +145,10 @@ var _ = Describe("Helm", func() {
 					repoClient,
 					nil,
 				)
+				Expect(client.IndexLoadedAt().IsZero()).To(BeTrue())
 				err := client.Initialize("")
 				Expect(err).NotTo(HaveOccurred())
+				Expect(client.IndexLoadedAt()).To(BeTemporally("~", time.Now(), time.Minute))
 			})
 		})
 
@@ -166,7 +177,8 @@ var _ = Describe("Helm", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				chart, err := client.GetChart("bar", "")
-				Expect(err).To(Equal(fmt.Errorf("failed to get chart: chart not found: bar")))
+				Expect(err).To(MatchError("failed to get chart: chart not found: bar"))
+				Expect(err).To(MatchError(helm.ErrChartNotFound))
 				Expect(chart).To(BeNil())
 			})
 
@@ -178,7 +190,8 @@ var _ = Describe("Helm", func() {
 				Expect(err).NotTo(HaveOccurred())
 
 				chart, err := client.GetChart("bar", "1.2.3")
-				Expect(err).To(Equal(fmt.Errorf("failed to get chart: chart app version not found for \"bar\": 1.2.3")))
+				Expect(err).To(MatchError("failed to get chart: chart app version not found for \"bar\": 1.2.3"))
+				Expect(err).To(MatchError(helm.ErrChartNotFound))
 				Expect(chart).To(BeNil())
 			})
 
@@ -0,0 +1,37 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var chartDownloadFailuresTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "minibroker",
+		Name:      "chart_download_failures_total",
+		Help:      "The number of charts that failed to download.",
+	},
+	[]string{"chart"},
+)
+
+// Collectors returns the Prometheus collectors of the Helm client.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		chartDownloadFailuresTotal,
+	}
+}
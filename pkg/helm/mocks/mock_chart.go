@@ -5,6 +5,7 @@
 package mocks
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -36,18 +37,18 @@ func (m *MockChartLoader) EXPECT() *MockChartLoaderMockRecorder {
 }
 
 // Load mocks base method.
-func (m *MockChartLoader) Load(arg0 string) (*chart.Chart, error) {
+func (m *MockChartLoader) Load(arg0 context.Context, arg1 string) (*chart.Chart, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Load", arg0)
+	ret := m.ctrl.Call(m, "Load", arg0, arg1)
 	ret0, _ := ret[0].(*chart.Chart)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Load indicates an expected call of Load.
-func (mr *MockChartLoaderMockRecorder) Load(arg0 interface{}) *gomock.Call {
+func (mr *MockChartLoaderMockRecorder) Load(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockChartLoader)(nil).Load), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockChartLoader)(nil).Load), arg0, arg1)
 }
 
 // MockChartHelmClientProvider is a mock of ChartHelmClientProvider interface.
@@ -0,0 +1,73 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/transport"
+)
+
+// RESTConfig returns the configuration for connecting to the k8s API. The in-cluster configuration
+// is used when neither the kubeconfig nor the context are set and the process runs in a pod;
+// otherwise, the kubeconfig is loaded following the kubectl rules, i.e. from the given path, the
+// KUBECONFIG environment variable or ~/.kube/config.
+func RESTConfig(kubeConfig, kubeContext string) (*rest.Config, error) {
+	if kubeConfig == "" && kubeContext == "" {
+		config, err := rest.InClusterConfig()
+		if err == nil {
+			return traced(config), nil
+		}
+		if err != rest.ErrNotInCluster {
+			return nil, fmt.Errorf("failed to load the in-cluster configuration: %w", err)
+		}
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeConfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	return traced(config), nil
+}
+
+// RESTConfigFromKubeConfig returns the configuration for connecting to the k8s API described by the
+// given kubeconfig content. An empty context means the current context of the kubeconfig is used.
+func RESTConfigFromKubeConfig(kubeConfig []byte, kubeContext string) (*rest.Config, error) {
+	rawConfig, err := clientcmd.Load(kubeConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	config, err := clientcmd.NewNonInteractiveClientConfig(*rawConfig, kubeContext, overrides, nil).ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the kubeconfig: %w", err)
+	}
+	return traced(config), nil
+}
+
+// traced wraps the transport of the configuration so every API call is traced as a child of the
+// span in the request context.
+func traced(config *rest.Config) *rest.Config {
+	config.WrapTransport = transport.Wrappers(config.WrapTransport, tracing.WrapTransport)
+	return config
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubernetes_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/kubernetes"
+)
+
+const kubeConfig = `apiVersion: v1
+kind: Config
+current-context: laptop
+clusters:
+- name: laptop
+  cluster:
+    server: https://127.0.0.1:6443
+- name: management
+  cluster:
+    server: https://management.example.com
+contexts:
+- name: laptop
+  context:
+    cluster: laptop
+    user: developer
+- name: management
+  context:
+    cluster: management
+    user: developer
+users:
+- name: developer
+  user:
+    token: token
+`
+
+var _ = Describe("Config", func() {
+	var kubeConfigPath string
+
+	BeforeEach(func() {
+		f, err := ioutil.TempFile("", "kubeconfig")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = f.WriteString(kubeConfig)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		kubeConfigPath = f.Name()
+	})
+
+	AfterEach(func() {
+		os.Remove(kubeConfigPath)
+	})
+
+	It("should use the current context of the kubeconfig", func() {
+		config, err := kubernetes.RESTConfig(kubeConfigPath, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Host).To(Equal("https://127.0.0.1:6443"))
+		Expect(config.BearerToken).To(Equal("token"))
+	})
+
+	It("should use the selected context", func() {
+		config, err := kubernetes.RESTConfig(kubeConfigPath, "management")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config.Host).To(Equal("https://management.example.com"))
+	})
+
+	It("should fail when the context does not exist", func() {
+		config, err := kubernetes.RESTConfig(kubeConfigPath, "missing")
+		Expect(config).To(BeNil())
+		Expect(err).To(MatchError(ContainSubstring("failed to load the kubeconfig")))
+	})
+
+	It("should fail when the kubeconfig does not exist", func() {
+		config, err := kubernetes.RESTConfig(kubeConfigPath+".missing", "")
+		Expect(config).To(BeNil())
+		Expect(err).To(MatchError(ContainSubstring("failed to load the kubeconfig")))
+	})
+
+	Describe("RESTConfigFromKubeConfig", func() {
+		It("should use the current context of the kubeconfig", func() {
+			config, err := kubernetes.RESTConfigFromKubeConfig([]byte(kubeConfig), "")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Host).To(Equal("https://127.0.0.1:6443"))
+		})
+
+		It("should use the selected context", func() {
+			config, err := kubernetes.RESTConfigFromKubeConfig([]byte(kubeConfig), "management")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config.Host).To(Equal("https://management.example.com"))
+		})
+
+		It("should fail when the kubeconfig is invalid", func() {
+			config, err := kubernetes.RESTConfigFromKubeConfig([]byte("{"), "")
+			Expect(config).To(BeNil())
+			Expect(err).To(MatchError(ContainSubstring("failed to load the kubeconfig")))
+		})
+	})
+})
@@ -0,0 +1,245 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	klog "k8s.io/klog/v2"
+	"k8s.io/klog/v2/klogr"
+)
+
+// Format is the output format of the logs.
+type Format string
+
+const (
+	// FormatText is the klog text format.
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line.
+	FormatJSON Format = "json"
+)
+
+// CorrelationIDHeader is the HTTP header carrying the correlation ID of a request.
+const CorrelationIDHeader = "X-Correlation-ID"
+
+// CorrelationIDKey is the key of the correlation ID in the structured log lines.
+const CorrelationIDKey = "correlationID"
+
+var validCorrelationID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// defaultLogger writes through klog, so it honours the verbosity flags and the output format.
+var defaultLogger logr.Logger = klogr.NewWithOptions(klogr.WithFormat(klogr.FormatKlog))
+
+type correlationIDContextKey struct{}
+
+// SetFormat sets the output format of the process logs. The JSON format also applies to the
+// printf-style klog lines.
+func SetFormat(format Format, out io.Writer) error {
+	switch format {
+	case FormatText, "":
+		klog.SetLogger(nil)
+	case FormatJSON:
+		klog.SetLogger(NewJSON(out))
+	default:
+		return fmt.Errorf("invalid log format %q: must be %q or %q", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// FromContext returns the structured logger of the context, or the default logger.
+func FromContext(ctx context.Context) logr.Logger {
+	if logger := logr.FromContext(ctx); logger != nil {
+		return logger
+	}
+	return defaultLogger
+}
+
+// IntoContext returns a copy of the context carrying the logger.
+func IntoContext(ctx context.Context, logger logr.Logger) context.Context {
+	return logr.NewContext(ctx, logger)
+}
+
+// NewCorrelationID generates a random correlation ID.
+func NewCorrelationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithCorrelationID returns a copy of the context carrying the correlation ID, and a logger that
+// adds it to every line.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, correlationIDContextKey{}, id)
+	return IntoContext(ctx, FromContext(ctx).WithValues(CorrelationIDKey, id))
+}
+
+// CorrelationID returns the correlation ID of the context, if any.
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDContextKey{}).(string)
+	return id
+}
+
+// Inherit returns a copy of ctx carrying the correlation ID and the logger of parent. It is used
+// by the asynchronous operations, which outlive the request context.
+func Inherit(ctx, parent context.Context) context.Context {
+	if id := CorrelationID(parent); id != "" {
+		ctx = context.WithValue(ctx, correlationIDContextKey{}, id)
+	}
+	if logger := logr.FromContext(parent); logger != nil {
+		ctx = IntoContext(ctx, logger)
+	}
+	return ctx
+}
+
+// CorrelationMiddleware assigns a correlation ID to every request: the one sent by the client in
+// the X-Correlation-ID header when it is valid, or a new one. The ID is returned in the same
+// header and carried by the request context.
+func CorrelationMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(CorrelationIDHeader)
+		if !validCorrelationID.MatchString(id) {
+			id = NewCorrelationID()
+		}
+		w.Header().Set(CorrelationIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(WithCorrelationID(r.Context(), id)))
+	})
+}
+
+// jsonLogger satisfies the logr.Logger interface, writing one JSON object per line.
+type jsonLogger struct {
+	out    *syncWriter
+	now    func() time.Time
+	name   string
+	level  int
+	values []interface{}
+}
+
+type syncWriter struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// NewJSON creates a logr.Logger writing JSON lines to out. The verbosity is controlled by the
+// klog flags.
+func NewJSON(out io.Writer) logr.Logger {
+	return &jsonLogger{out: &syncWriter{w: out}, now: time.Now}
+}
+
+// Enabled returns whether the logger level is enabled by the klog verbosity.
+func (l *jsonLogger) Enabled() bool {
+	return klog.V(klog.Level(l.level)).Enabled()
+}
+
+// Info logs a message with key/value pairs.
+func (l *jsonLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.write("info", nil, msg, keysAndValues)
+}
+
+// Error logs an error with a message and key/value pairs.
+func (l *jsonLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	l.write("error", err, msg, keysAndValues)
+}
+
+// V returns a logger for the verbosity level.
+func (l *jsonLogger) V(level int) logr.Logger {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+// WithValues returns a logger adding the key/value pairs to every line.
+func (l *jsonLogger) WithValues(keysAndValues ...interface{}) logr.Logger {
+	clone := *l
+	clone.values = append(append([]interface{}{}, l.values...), keysAndValues...)
+	return &clone
+}
+
+// WithName returns a logger with the name appended to its name.
+func (l *jsonLogger) WithName(name string) logr.Logger {
+	clone := *l
+	if clone.name != "" {
+		clone.name += "/"
+	}
+	clone.name += name
+	return &clone
+}
+
+func (l *jsonLogger) write(level string, err error, msg string, keysAndValues []interface{}) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	writeField(&buf, "ts", l.now().UTC().Format(time.RFC3339Nano))
+	writeField(&buf, "level", level)
+	if l.level > 0 {
+		writeField(&buf, "v", l.level)
+	}
+	if l.name != "" {
+		writeField(&buf, "logger", l.name)
+	}
+	// The printf-style klog lines end with a newline.
+	writeField(&buf, "msg", strings.TrimSuffix(msg, "\n"))
+	if err != nil {
+		writeField(&buf, "error", err.Error())
+	}
+	kvs := append(append([]interface{}{}, l.values...), keysAndValues...)
+	for i := 0; i < len(kvs); i += 2 {
+		key := fmt.Sprint(kvs[i])
+		var value interface{} = "(MISSING)"
+		if i+1 < len(kvs) {
+			value = kvs[i+1]
+		}
+		writeField(&buf, key, value)
+	}
+	buf.WriteString("}\n")
+
+	l.out.Lock()
+	defer l.out.Unlock()
+	l.out.w.Write(buf.Bytes())
+}
+
+func writeField(buf *bytes.Buffer, key string, value interface{}) {
+	if buf.Len() > 1 {
+		buf.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	buf.Write(k)
+	buf.WriteByte(':')
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprintf("%+v", value))
+	}
+	buf.Write(v)
+}
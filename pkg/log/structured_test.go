@@ -0,0 +1,159 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func TestJSONLogger(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	logger := (&jsonLogger{out: &syncWriter{w: &buf}, now: func() time.Time { return now }}).
+		WithName("broker").
+		WithValues(CorrelationIDKey, "abc")
+
+	logger.Info("provisioned\n", "instanceID", "i-1", "bindings", 2)
+	logger.V(3).Error(errors.New("boom"), "failed to provision", "instanceID", "i-1", "dangling")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	expected := []map[string]interface{}{
+		{
+			"ts":            "2020-01-02T03:04:05Z",
+			"level":         "info",
+			"logger":        "broker",
+			"msg":           "provisioned",
+			"correlationID": "abc",
+			"instanceID":    "i-1",
+			"bindings":      float64(2),
+		},
+		{
+			"ts":            "2020-01-02T03:04:05Z",
+			"level":         "error",
+			"v":             float64(3),
+			"logger":        "broker",
+			"msg":           "failed to provision",
+			"error":         "boom",
+			"correlationID": "abc",
+			"instanceID":    "i-1",
+			"dangling":      "(MISSING)",
+		},
+	}
+	for i, line := range lines {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("line %d is not JSON: %v: %s", i, err, line)
+		}
+		if len(entry) != len(expected[i]) {
+			t.Errorf("line %d: expected %v, got %v", i, expected[i], entry)
+		}
+		for key, value := range expected[i] {
+			if entry[key] != value {
+				t.Errorf("line %d: expected %s=%v, got %v", i, key, value, entry[key])
+			}
+		}
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	defer SetFormat(FormatText, nil)
+
+	for _, format := range []Format{FormatText, FormatJSON, ""} {
+		if err := SetFormat(format, &bytes.Buffer{}); err != nil {
+			t.Errorf("unexpected error for %q: %v", format, err)
+		}
+	}
+	if err := SetFormat("yaml", &bytes.Buffer{}); err == nil {
+		t.Error("expected an error for an invalid format")
+	}
+}
+
+func TestCorrelationMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected string
+	}{
+		{name: "generates an ID", header: ""},
+		{name: "reuses a valid ID", header: "req-42.a_b", expected: "req-42.a_b"},
+		{name: "replaces an invalid ID", header: "bad id\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := CorrelationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = CorrelationID(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodPut, "/v2/service_instances/i-1", nil)
+			if tt.header != "" {
+				r.Header.Set(CorrelationIDHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if got == "" {
+				t.Fatal("expected the request context to carry a correlation ID")
+			}
+			if tt.expected != "" && got != tt.expected {
+				t.Errorf("expected ID %q, got %q", tt.expected, got)
+			}
+			if tt.expected == "" && got == tt.header {
+				t.Errorf("expected a new ID, got %q", got)
+			}
+			if header := w.Header().Get(CorrelationIDHeader); header != got {
+				t.Errorf("expected response header %q, got %q", got, header)
+			}
+		})
+	}
+}
+
+func TestInherit(t *testing.T) {
+	parent := WithCorrelationID(context.Background(), "abc")
+	ctx := Inherit(context.Background(), parent)
+
+	if id := CorrelationID(ctx); id != "abc" {
+		t.Errorf("expected correlation ID %q, got %q", "abc", id)
+	}
+	if logr.FromContext(ctx) == nil {
+		t.Error("expected the logger of the parent context")
+	}
+	if ctx := Inherit(context.Background(), context.Background()); CorrelationID(ctx) != "" {
+		t.Error("expected no correlation ID")
+	}
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	a, b := NewCorrelationID(), NewCorrelationID()
+	if a == b {
+		t.Errorf("expected distinct IDs, got %q twice", a)
+	}
+	if !validCorrelationID.MatchString(a) {
+		t.Errorf("generated an invalid ID %q", a)
+	}
+}
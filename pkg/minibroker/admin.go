@@ -0,0 +1,370 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// OperationHistoryKey is the instance configmap key holding the recent operations of the instance
+// and its bindings.
+const OperationHistoryKey = "operation-history"
+
+// maxOperationHistory is the number of operations kept in the history of an instance.
+const maxOperationHistory = 50
+
+var (
+	// ErrNoOperationInProgress is the error for failing the operation of an instance that has no
+	// operation in progress.
+	ErrNoOperationInProgress = fmt.Errorf("no operation in progress")
+	// ErrReleaseExists is the error for force-deleting an instance whose release still exists.
+	ErrReleaseExists = fmt.Errorf("the release of the instance still exists")
+)
+
+// OperationRecord is an entry of the operation history of an instance.
+type OperationRecord struct {
+	Operation   string    `json:"operation,omitempty"`
+	BindingID   string    `json:"bindingID,omitempty"`
+	State       string    `json:"state"`
+	Description string    `json:"description,omitempty"`
+	Time        time.Time `json:"time"`
+}
+
+// InstanceSummary describes an instance from its configmap. The PlatformNamespace is the namespace
+// the instance was requested in, which differs from the release Namespace in the
+// namespace-per-instance mode.
+type InstanceSummary struct {
+	ID                string                 `json:"id"`
+	ServiceID         string                 `json:"serviceID"`
+	PlanID            string                 `json:"planID"`
+	Release           string                 `json:"release,omitempty"`
+	Namespace         string                 `json:"namespace,omitempty"`
+	PlatformNamespace string                 `json:"platformNamespace,omitempty"`
+	Cluster           string                 `json:"cluster,omitempty"`
+	State             string                 `json:"state,omitempty"`
+	Operation         string                 `json:"operation,omitempty"`
+	Description       string                 `json:"description,omitempty"`
+	Bindings          int                    `json:"bindings"`
+	Conditions        []InstanceCondition    `json:"conditions,omitempty"`
+	Context           map[string]interface{} `json:"context,omitempty"`
+	Identity          *Identity              `json:"identity,omitempty"`
+}
+
+// ListInstances lists the summaries of all the instances.
+func (c *Client) ListInstances(ctx context.Context) ([]InstanceSummary, error) {
+	configs, err := c.coreClient.CoreV1().
+		ConfigMaps(c.namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: ServiceKey})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list the instance configmaps")
+	}
+
+	summaries := make([]InstanceSummary, 0, len(configs.Items))
+	for i := range configs.Items {
+		summaries = append(summaries, instanceSummary(ctx, &configs.Items[i]))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].ID < summaries[j].ID })
+	return summaries, nil
+}
+
+// GetInstance returns the summary of an instance.
+func (c *Client) GetInstance(ctx context.Context, instanceID string) (*InstanceSummary, error) {
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	summary := instanceSummary(ctx, config)
+	return &summary, nil
+}
+
+// InstancePlatform returns the platform of the OSB context an instance was provisioned with, and
+// whether the instance exists. A context without a platform but with a namespace is a Kubernetes
+// context, as the broker resolves it.
+func (c *Client) InstancePlatform(ctx context.Context, instanceID string) (string, bool, error) {
+	config, err := c.getConfigMap(ctx, instanceID)
+	if apierrors.IsNotFound(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, errors.Wrapf(err, "failed to get the platform of instance %q", instanceID)
+	}
+	var platformContext struct {
+		Platform  string      `json:"platform"`
+		Namespace interface{} `json:"namespace"`
+	}
+	if contextJSON, ok := config.Data[PlatformContextKey]; ok {
+		if err := json.Unmarshal([]byte(contextJSON), &platformContext); err != nil {
+			return "", false, errors.Wrapf(err, "failed to decode the context of instance %q", instanceID)
+		}
+	}
+	if platformContext.Platform == "" && platformContext.Namespace != nil {
+		return "kubernetes", true, nil
+	}
+	return platformContext.Platform, true, nil
+}
+
+// OperationHistory returns the recent operations of an instance and its bindings, oldest first.
+func (c *Client) OperationHistory(ctx context.Context, instanceID string) ([]OperationRecord, error) {
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return operationHistory(config)
+}
+
+// FailOperation marks the operation in progress on an instance, or on one of its bindings when the
+// binding ID is not empty, as failed, e.g. when it is stuck after a broker restart.
+func (c *Client) FailOperation(ctx context.Context, instanceID, bindingID, reason string) error {
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	description := "marked as failed by an administrator"
+	if reason != "" {
+		description = fmt.Sprintf("%s: %s", description, reason)
+	}
+	if bindingID != "" {
+		return c.failBindingOperation(ctx, config, bindingID, description)
+	}
+	if config.Data[OperationStateKey] != string(osb.StateInProgress) {
+		return ErrNoOperationInProgress
+	}
+
+	contextLogger(ctx).V(2).Info("failing operation", "instanceID", instanceID, "operation", config.Data[OperationNameKey], "description", description)
+	return c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+		OperationStateKey:       string(osb.StateFailed),
+		OperationDescriptionKey: description,
+	})
+}
+
+func (c *Client) failBindingOperation(ctx context.Context, config *corev1.ConfigMap, bindingID, description string) error {
+	stateJSON, ok := config.Data[BindingStateKeyPrefix+bindingID]
+	if !ok {
+		return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	var state *osb.LastOperationResponse
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return errors.Wrapf(err, "could not decode the state of binding %q", bindingID)
+	}
+	if state == nil || state.State != osb.StateInProgress {
+		return ErrNoOperationInProgress
+	}
+
+	failedJSON, err := json.Marshal(osb.LastOperationResponse{State: osb.StateFailed, Description: &description})
+	if err != nil {
+		return err
+	}
+	contextLogger(ctx).V(2).Info("failing binding operation", "instanceID", config.Name, "bindingID", bindingID, "description", description)
+	return c.updateConfigMap(ctx, config.Name, map[string]interface{}{
+		(BindingStateKeyPrefix + bindingID): string(failedJSON),
+	})
+}
+
+// ForceDeleteInstance deletes the records of an instance whose release no longer exists, along with
+// the Secrets projected for its bindings and the namespace generated for it, if any.
+func (c *Client) ForceDeleteInstance(ctx context.Context, instanceID string) error {
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	if release := config.Data[ReleaseLabel]; release != "" {
+		target, err := c.instanceCluster(ctx, config)
+		if err != nil {
+			return err
+		}
+		exists, err := releaseExists(ctx, target.coreClient, release, config.Data[ReleaseNamespaceKey])
+		if err != nil {
+			return err
+		}
+		if exists {
+			return ErrReleaseExists
+		}
+	}
+
+	for _, bindingID := range bindingIDs(config) {
+		if err := c.deleteBindingSecret(ctx, config, bindingID); err != nil {
+			return err
+		}
+	}
+	if instanceNamespace := config.Data[InstanceNamespaceKey]; instanceNamespace != "" {
+		target, err := c.instanceCluster(ctx, config)
+		if err != nil {
+			return err
+		}
+		if err := deleteInstanceNamespace(ctx, target.coreClient, instanceNamespace); err != nil {
+			return err
+		}
+	}
+
+	contextLogger(ctx).V(2).Info("force-deleting instance", "instanceID", instanceID)
+	err = c.coreClient.CoreV1().
+		ConfigMaps(c.namespace).
+		Delete(ctx, instanceID, metav1.DeleteOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "could not delete configmap %s/%s", c.namespace, instanceID)
+	}
+	return nil
+}
+
+// releaseExists returns whether a Helm release exists, looking up the Secrets Helm stores the
+// release revisions in.
+func releaseExists(ctx context.Context, coreClient kubernetes.Interface, release, namespace string) (bool, error) {
+	secrets, err := coreClient.CoreV1().
+		Secrets(namespace).
+		List(ctx, metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{"owner": "helm", "name": release}).String(),
+		})
+	if err != nil {
+		return false, errors.Wrapf(err, "could not look up release %s/%s", namespace, release)
+	}
+	return len(secrets.Items) > 0, nil
+}
+
+// instanceSummary builds the summary of an instance from its configmap.
+func instanceSummary(ctx context.Context, config *corev1.ConfigMap) InstanceSummary {
+	summary := InstanceSummary{
+		ID:          config.Name,
+		ServiceID:   config.Data[ServiceKey],
+		PlanID:      config.Data[PlanKey],
+		Release:     config.Data[ReleaseLabel],
+		Namespace:   config.Data[ReleaseNamespaceKey],
+		Cluster:     config.Data[ClusterKey],
+		State:       config.Data[OperationStateKey],
+		Operation:   config.Data[OperationNameKey],
+		Description: config.Data[OperationDescriptionKey],
+		Bindings:    len(bindingIDs(config)),
+		// The instances provisioned before the platform namespace was recorded were installed into
+		// the requested namespace.
+		PlatformNamespace: config.Data[ReleaseNamespaceKey],
+	}
+	if namespace, ok := config.Labels[PlatformNamespaceLabel]; ok {
+		summary.PlatformNamespace = namespace
+	}
+	if contextJSON, ok := config.Data[PlatformContextKey]; ok {
+		if err := json.Unmarshal([]byte(contextJSON), &summary.Context); err != nil {
+			contextLogger(ctx).V(2).Info("failed to decode the context of instance", "instanceID", config.Name, "error", err)
+		}
+	}
+	summary.Identity = recordedIdentity(ctx, config, OriginatingIdentityKey)
+	if conditions, err := instanceConditions(config); err == nil && len(conditions) > 0 {
+		summary.Conditions = conditions
+	}
+	return summary
+}
+
+// bindingIDs returns the sorted IDs of the bindings of an instance.
+func bindingIDs(config *corev1.ConfigMap) []string {
+	ids := make([]string, 0)
+	for key := range config.Data {
+		if strings.HasPrefix(key, BindingStateKeyPrefix) {
+			ids = append(ids, strings.TrimPrefix(key, BindingStateKeyPrefix))
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// operationHistory decodes the operation history from the instance configmap.
+func operationHistory(config *corev1.ConfigMap) ([]OperationRecord, error) {
+	history := make([]OperationRecord, 0)
+	historyJSON, ok := config.Data[OperationHistoryKey]
+	if !ok {
+		return history, nil
+	}
+	if err := json.Unmarshal([]byte(historyJSON), &history); err != nil {
+		return nil, errors.Wrapf(err, "could not decode the operation history of instance %q", config.Name)
+	}
+	return history, nil
+}
+
+// recordOperations appends the operation state changes in the configmap update data to the
+// operation history.
+func recordOperations(ctx context.Context, config *corev1.ConfigMap, data map[string]interface{}) error {
+	now := time.Now().UTC()
+	records := make([]OperationRecord, 0)
+
+	if state, ok := data[OperationStateKey].(string); ok {
+		operation, ok := data[OperationNameKey].(string)
+		if !ok {
+			operation = config.Data[OperationNameKey]
+		}
+		description, ok := data[OperationDescriptionKey].(string)
+		if !ok {
+			description = config.Data[OperationDescriptionKey]
+		}
+		records = append(records, OperationRecord{
+			Operation:   operation,
+			State:       state,
+			Description: description,
+			Time:        now,
+		})
+	}
+	for key, value := range data {
+		stateJSON, ok := value.(string)
+		if !ok || !strings.HasPrefix(key, BindingStateKeyPrefix) {
+			continue
+		}
+		var state osb.LastOperationResponse
+		if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+			continue
+		}
+		record := OperationRecord{
+			BindingID: strings.TrimPrefix(key, BindingStateKeyPrefix),
+			State:     string(state.State),
+			Time:      now,
+		}
+		if state.Description != nil {
+			record.Description = *state.Description
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	history, err := operationHistory(config)
+	if err != nil {
+		// A corrupted history is replaced rather than blocking the operations.
+		contextLogger(ctx).V(2).Info("resetting the operation history", "instanceID", config.Name, "error", err)
+		history = nil
+	}
+	history = append(history, records...)
+	if len(history) > maxOperationHistory {
+		history = history[len(history)-maxOperationHistory:]
+	}
+	historyJSON, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	config.Data[OperationHistoryKey] = string(historyJSON)
+	return nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAdminOperations(t *testing.T) {
+	newConfig := func(name, state string) *corev1.ConfigMap {
+		return &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "minibroker",
+				Labels:    map[string]string{ServiceKey: "redis", PlanKey: "redis-5-0-7"},
+			},
+			Data: map[string]string{
+				ServiceKey:                      "redis",
+				PlanKey:                         "redis-5-0-7",
+				ReleaseLabel:                    name + "-release",
+				ReleaseNamespaceKey:             "apps",
+				OperationNameKey:                "provision-1",
+				OperationStateKey:               state,
+				BindingStateKeyPrefix + "first": `{"state":"succeeded"}`,
+				BindingKeyPrefix + "first":      `{"credentials":{}}`,
+			},
+		}
+	}
+	releaseSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "sh.helm.release.v1.running-release.v1",
+			Namespace: "apps",
+			Labels:    map[string]string{"owner": "helm", "name": "running-release"},
+		},
+	}
+	bindingStuck := newConfig("binding-stuck", "succeeded")
+	bindingStuck.Data[BindingStateKeyPrefix+"second"] = `{"state":"in progress"}`
+	coreClient := fake.NewSimpleClientset(newConfig("running", "succeeded"), newConfig("stuck", "in progress"), bindingStuck, releaseSecret)
+	c := &Client{namespace: "minibroker", coreClient: coreClient, helm: helm.NewDefaultClient()}
+
+	instances, err := c.ListInstances(context.TODO())
+	if err != nil {
+		t.Fatalf("ListInstances: unexpected error %v", err)
+	}
+	if len(instances) != 3 || instances[1].ID != "running" || instances[1].Bindings != 1 || instances[1].Release != "running-release" {
+		t.Errorf("ListInstances: unexpected instances %+v", instances)
+	}
+
+	if err := c.FailOperation(context.TODO(), "running", "", "stuck"); err != ErrNoOperationInProgress {
+		t.Errorf("FailOperation: expected ErrNoOperationInProgress, actual %v", err)
+	}
+	if err := c.FailOperation(context.TODO(), "stuck", "", "helm hung"); err != nil {
+		t.Fatalf("FailOperation: unexpected error %v", err)
+	}
+	if err := c.FailOperation(context.TODO(), "running", "first", ""); err != ErrNoOperationInProgress {
+		t.Errorf("FailOperation: expected ErrNoOperationInProgress for a completed binding, actual %v", err)
+	}
+	if err := c.FailOperation(context.TODO(), "running", "missing", ""); err == nil {
+		t.Errorf("FailOperation: expected an error for a missing binding")
+	}
+	if err := c.FailOperation(context.TODO(), "binding-stuck", "second", "job hung"); err != nil {
+		t.Fatalf("FailOperation: unexpected error %v", err)
+	}
+	state, err := c.LastBindingOperationState(context.TODO(), "binding-stuck", "second")
+	if err != nil {
+		t.Fatalf("LastBindingOperationState: unexpected error %v", err)
+	}
+	if state.State != "failed" || *state.Description != "marked as failed by an administrator: job hung" {
+		t.Errorf("FailOperation: unexpected binding state %+v", state)
+	}
+
+	instance, err := c.GetInstance(context.TODO(), "stuck")
+	if err != nil {
+		t.Fatalf("GetInstance: unexpected error %v", err)
+	}
+	if instance.State != "failed" || instance.Description != "marked as failed by an administrator: helm hung" {
+		t.Errorf("FailOperation: unexpected instance %+v", instance)
+	}
+	history, err := c.OperationHistory(context.TODO(), "stuck")
+	if err != nil {
+		t.Fatalf("OperationHistory: unexpected error %v", err)
+	}
+	if len(history) != 1 || history[0].Operation != "provision-1" || history[0].State != "failed" {
+		t.Errorf("OperationHistory: unexpected history %+v", history)
+	}
+
+	if err := c.ForceDeleteInstance(context.TODO(), "running"); err != ErrReleaseExists {
+		t.Errorf("ForceDeleteInstance: expected ErrReleaseExists, actual %v", err)
+	}
+	if err := c.ForceDeleteInstance(context.TODO(), "stuck"); err != nil {
+		t.Fatalf("ForceDeleteInstance: unexpected error %v", err)
+	}
+	if _, err := coreClient.CoreV1().ConfigMaps("minibroker").Get(context.TODO(), "stuck", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("ForceDeleteInstance: expected the configmap to be deleted, actual error %v", err)
+	}
+}
+
+func TestInstancePlatform(t *testing.T) {
+	newConfig := func(name, platformContext string) *corev1.ConfigMap {
+		config := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "minibroker"},
+			Data:       map[string]string{ServiceKey: "redis"},
+		}
+		if platformContext != "" {
+			config.Data[PlatformContextKey] = platformContext
+		}
+		return config
+	}
+	c := &Client{namespace: "minibroker", coreClient: fake.NewSimpleClientset(
+		newConfig("cf", `{"platform":"cloudfoundry","space_guid":"space"}`),
+		newConfig("k8s", `{"namespace":"apps"}`),
+		newConfig("legacy", ""),
+	)}
+
+	tests := []struct {
+		instanceID string
+		platform   string
+		found      bool
+	}{
+		{"cf", "cloudfoundry", true},
+		{"k8s", "kubernetes", true},
+		{"legacy", "", true},
+		{"missing", "", false},
+	}
+	for _, tt := range tests {
+		platform, found, err := c.InstancePlatform(context.TODO(), tt.instanceID)
+		if err != nil {
+			t.Errorf("InstancePlatform(%s): unexpected error %v", tt.instanceID, err)
+		} else if platform != tt.platform || found != tt.found {
+			t.Errorf("InstancePlatform(%s): expected %q %v, actual %q %v", tt.instanceID, tt.platform, tt.found, platform, found)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// BindingSecretKeyPrefix is the instance configmap key prefix recording the namespace and name
+	// of the Secret projected for a binding.
+	BindingSecretKeyPrefix = "binding-secret-"
+	// BindingLabel labels the Secrets projected for the bindings with the binding ID.
+	BindingLabel = "minibroker.binding"
+
+	// bindingSecretTypePrefix is the Secret type prefix defined by the Service Binding for
+	// Kubernetes specification.
+	bindingSecretTypePrefix = "servicebinding.io/"
+	bindingSecretProvider   = "minibroker"
+	bindingSecretNamePrefix = "minibroker-"
+	maxBindingSecretNameLen = 253
+)
+
+// bindingSecretEntries are the credentials keys projected into the binding Secrets, as defined by
+// the Service Binding for Kubernetes specification.
+var bindingSecretEntries = []string{"host", "port", "username", "password", "database", "uri"}
+
+// bindingSecretTypes maps the services to the well-known types of the specification when they
+// differ from the service ID.
+var bindingSecretTypes = map[string]string{
+	"mariadb": "mysql",
+}
+
+var invalidSecretNameChars = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// bindingSecretName builds a valid Secret name for the binding.
+func bindingSecretName(bindingID string) string {
+	name := bindingSecretNamePrefix + invalidSecretNameChars.ReplaceAllString(strings.ToLower(bindingID), "-")
+	if len(name) > maxBindingSecretNameLen {
+		name = name[:maxBindingSecretNameLen]
+	}
+	return strings.TrimRight(name, ".-")
+}
+
+// bindingSecretType returns the binding type of the service.
+func bindingSecretType(serviceID string) string {
+	if bindingType, ok := bindingSecretTypes[serviceID]; ok {
+		return bindingType
+	}
+	return serviceID
+}
+
+// newBindingSecret builds the Secret projecting the binding credentials following the Service
+// Binding for Kubernetes specification.
+func newBindingSecret(instanceID, serviceID, bindingID, namespace string, creds map[string]interface{}) *corev1.Secret {
+	bindingType := bindingSecretType(serviceID)
+	stringData := map[string]string{
+		"type":     bindingType,
+		"provider": bindingSecretProvider,
+	}
+	for _, entry := range bindingSecretEntries {
+		value, ok := creds[entry]
+		if !ok || value == nil {
+			continue
+		}
+		if str := fmt.Sprintf("%v", value); str != "" {
+			stringData[entry] = str
+		}
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      bindingSecretName(bindingID),
+			Namespace: namespace,
+			Labels: map[string]string{
+				InstanceLabel: instanceID,
+				BindingLabel:  bindingID,
+			},
+		},
+		Type:       corev1.SecretType(bindingSecretTypePrefix + bindingType),
+		StringData: stringData,
+	}
+}
+
+// projectBindingSecret creates or updates the Secret projecting the binding credentials into the
+// consumer namespace, recording its location in the instance configmap.
+func (c *Client) projectBindingSecret(
+	ctx context.Context,
+	instanceID,
+	serviceID,
+	bindingID,
+	namespace string,
+	creds map[string]interface{},
+) error {
+	secret := newBindingSecret(instanceID, serviceID, bindingID, namespace, creds)
+	secretsInterface := c.coreClient.CoreV1().Secrets(namespace)
+	existing, err := secretsInterface.Get(ctx, secret.Name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsNotFound(err):
+		if _, err := secretsInterface.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to project binding secret %s/%s: %w", namespace, secret.Name, err)
+		}
+	case err != nil:
+		return fmt.Errorf("failed to project binding secret %s/%s: %w", namespace, secret.Name, err)
+	default:
+		if existing.Labels[BindingLabel] != bindingID {
+			return fmt.Errorf(
+				"failed to project binding secret %s/%s: the secret exists and does not belong to the binding",
+				namespace, secret.Name)
+		}
+		// The Secret type is immutable, so only the data is updated.
+		existing.Data = nil
+		existing.StringData = secret.StringData
+		if _, err := secretsInterface.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to project binding secret %s/%s: %w", namespace, secret.Name, err)
+		}
+	}
+
+	contextLogger(ctx).V(3).Info("projected binding into secret", "bindingID", bindingID, "namespace", namespace, "secret", secret.Name)
+
+	return c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+		(BindingSecretKeyPrefix + bindingID): fmt.Sprintf("%s/%s", namespace, secret.Name),
+	})
+}
+
+// updateBindingSecret refreshes the projected Secret of a binding, if any, with new credentials.
+func (c *Client) updateBindingSecret(ctx context.Context, config *corev1.ConfigMap, bindingID string, creds map[string]interface{}) error {
+	location, ok := config.Data[BindingSecretKeyPrefix+bindingID]
+	if !ok {
+		return nil
+	}
+	namespace := strings.SplitN(location, "/", 2)[0]
+	return c.projectBindingSecret(ctx, config.Name, config.Data[ServiceKey], bindingID, namespace, creds)
+}
+
+// deleteBindingSecret deletes the projected Secret of a binding, if any.
+func (c *Client) deleteBindingSecret(ctx context.Context, config *corev1.ConfigMap, bindingID string) error {
+	location, ok := config.Data[BindingSecretKeyPrefix+bindingID]
+	if !ok {
+		return nil
+	}
+	parts := strings.SplitN(location, "/", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("failed to delete binding secret: invalid location %q", location)
+	}
+
+	err := c.coreClient.CoreV1().
+		Secrets(parts[0]).
+		Delete(ctx, parts[1], metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete binding secret %s: %w", location, err)
+	}
+
+	contextLogger(ctx).V(3).Info("deleted binding secret", "bindingID", bindingID, "secret", location)
+	return nil
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ghodss/yaml"
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	kube "github.com/kubernetes-sigs/minibroker/pkg/kubernetes"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ClusterKey is the instance configmap key recording the name of the target cluster owning the
+	// release. It is absent for the releases installed in the cluster Minibroker runs in.
+	ClusterKey = "cluster"
+
+	// DefaultKubeconfigKey is the key of the kubeconfig Secrets holding the kubeconfig.
+	DefaultKubeconfigKey = "kubeconfig"
+	// DefaultClusterDomain is the domain assumed for the target clusters not setting one.
+	DefaultClusterDomain = "cluster.local"
+)
+
+// ClusterConfig describes a target cluster Minibroker can install releases into.
+type ClusterConfig struct {
+	// The name the cluster is referenced by, e.g. in the "cluster" field of the OSB context.
+	Name string `json:"name"`
+	// The Secret in the Minibroker namespace holding the kubeconfig of the cluster.
+	KubeconfigSecret string `json:"kubeconfigSecret"`
+	// The key of the Secret holding the kubeconfig. Defaults to DefaultKubeconfigKey.
+	KubeconfigKey string `json:"kubeconfigKey,omitempty"`
+	// The kubeconfig context to use. Defaults to the current context of the kubeconfig.
+	Context string `json:"context,omitempty"`
+	// The k8s cluster domain of the cluster. Defaults to DefaultClusterDomain.
+	ClusterDomain string `json:"clusterDomain,omitempty"`
+	// The plan IDs provisioned into the cluster when the OSB context does not select a cluster.
+	Plans []string `json:"plans,omitempty"`
+}
+
+// ClustersConfig is the configuration of the target clusters.
+type ClustersConfig struct {
+	Clusters []ClusterConfig `json:"clusters"`
+}
+
+// LoadClustersConfig parses and validates the configuration of the target clusters from raw YAML.
+func LoadClustersConfig(data []byte) (*ClustersConfig, error) {
+	config := &ClustersConfig{}
+	if err := yaml.UnmarshalStrict(data, config, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("failed to load the clusters configuration: %w", err)
+	}
+
+	names := make(map[string]bool, len(config.Clusters))
+	for i := range config.Clusters {
+		cluster := &config.Clusters[i]
+		if cluster.Name == "" {
+			return nil, fmt.Errorf("failed to load the clusters configuration: cluster %d has no name", i)
+		}
+		if names[cluster.Name] {
+			return nil, fmt.Errorf("failed to load the clusters configuration: duplicate cluster %q", cluster.Name)
+		}
+		names[cluster.Name] = true
+		if cluster.KubeconfigSecret == "" {
+			return nil, fmt.Errorf("failed to load the clusters configuration: cluster %q has no kubeconfigSecret", cluster.Name)
+		}
+		if cluster.KubeconfigKey == "" {
+			cluster.KubeconfigKey = DefaultKubeconfigKey
+		}
+		if cluster.ClusterDomain == "" {
+			cluster.ClusterDomain = DefaultClusterDomain
+		}
+	}
+
+	return config, nil
+}
+
+// cluster holds the clients operating on the cluster owning a release.
+type cluster struct {
+	name        string
+	coreClient  kubernetes.Interface
+	chartClient *helm.ChartClient
+	providers   map[string]Provider
+	// resourceVersion is the version of the kubeconfig Secret the clients were built from.
+	resourceVersion string
+}
+
+// newProviders creates the service providers, building the service hosts for the cluster domain.
+func newProviders(clusterDomain string) map[string]Provider {
+	hb := hostBuilder{clusterDomain}
+	return map[string]Provider{
+		"mysql":      MySQLProvider{hb},
+		"mariadb":    MariadbProvider{hb},
+		"postgresql": PostgresProvider{hb},
+		"mongodb":    MongodbProvider{hb},
+		"redis":      RedisProvider{hb},
+		"rabbitmq":   RabbitmqProvider{hb},
+	}
+}
+
+// localCluster returns the cluster Minibroker runs in.
+func (c *Client) localCluster() *cluster {
+	return &cluster{
+		coreClient:  c.coreClient,
+		chartClient: c.helm.ChartClient(),
+		providers:   c.providers,
+	}
+}
+
+// selectCluster returns the name of the target cluster for provisioning the plan. The cluster
+// requested through the OSB context takes precedence over the clusters configured for the plan. An
+// empty name selects the cluster Minibroker runs in.
+func (c *Client) selectCluster(planID, requested string) (string, error) {
+	if requested != "" {
+		if _, ok := c.clusters[requested]; !ok {
+			return "", osb.HTTPStatusCodeError{
+				StatusCode:  http.StatusBadRequest,
+				Description: strPtr(fmt.Sprintf("unknown cluster %q", requested)),
+			}
+		}
+		return requested, nil
+	}
+	for _, name := range c.clusterNames {
+		for _, plan := range c.clusters[name].Plans {
+			if plan == planID {
+				return name, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// instanceCluster returns the cluster owning the release of the instance described by config.
+func (c *Client) instanceCluster(ctx context.Context, config *corev1.ConfigMap) (*cluster, error) {
+	return c.cluster(ctx, config.Data[ClusterKey])
+}
+
+// cluster returns the cluster with the given name, building its clients from the kubeconfig
+// Secret. The clients are cached until the Secret changes.
+func (c *Client) cluster(ctx context.Context, name string) (*cluster, error) {
+	if name == "" {
+		return c.localCluster(), nil
+	}
+	config, ok := c.clusters[name]
+	if !ok {
+		return nil, fmt.Errorf("failed to get cluster %q: the cluster is not configured", name)
+	}
+
+	secret, err := c.coreClient.CoreV1().
+		Secrets(c.namespace).
+		Get(ctx, config.KubeconfigSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster %q: %w", name, err)
+	}
+
+	c.clustersMutex.Lock()
+	defer c.clustersMutex.Unlock()
+
+	if cached, ok := c.clusterCache[name]; ok && cached.resourceVersion == secret.ResourceVersion {
+		return cached, nil
+	}
+
+	kubeConfig, ok := secret.Data[config.KubeconfigKey]
+	if !ok {
+		return nil, fmt.Errorf("failed to get cluster %q: secret %s/%s has no %q key",
+			name, c.namespace, config.KubeconfigSecret, config.KubeconfigKey)
+	}
+	restConfig, err := kube.RESTConfigFromKubeConfig(kubeConfig, config.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster %q: %w", name, err)
+	}
+	coreClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster %q: %w", name, err)
+	}
+	configProvider, err := helm.NewKubeConfigDataProvider(kubeConfig, config.Context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cluster %q: %w", name, err)
+	}
+
+	contextLogger(ctx).V(3).Info("initialized the clients of cluster", "cluster", name)
+	target := &cluster{
+		name:            name,
+		coreClient:      coreClient,
+		chartClient:     helm.NewChartClientForConfig(configProvider),
+		providers:       newProviders(config.ClusterDomain),
+		resourceVersion: secret.ResourceVersion,
+	}
+	c.clusterCache[name] = target
+	return target, nil
+}
+
+// GetTargetNamespace gets a namespace of the cluster the plan is provisioned into, the cluster
+// requested through the OSB context taking precedence.
+func (c *Client) GetTargetNamespace(ctx context.Context, planID, requestedCluster, name string) (*corev1.Namespace, error) {
+	clusterName, err := c.selectCluster(planID, requestedCluster)
+	if err != nil {
+		return nil, err
+	}
+	target, err := c.cluster(ctx, clusterName)
+	if err != nil {
+		return nil, err
+	}
+	return target.coreClient.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testKubeConfig = `apiVersion: v1
+kind: Config
+current-context: east
+clusters:
+- name: east
+  cluster:
+    server: https://east.example.com
+contexts:
+- name: east
+  context:
+    cluster: east
+    user: broker
+users:
+- name: broker
+  user:
+    token: token
+`
+
+func TestLoadClustersConfig(t *testing.T) {
+	config, err := LoadClustersConfig([]byte(`
+clusters:
+- name: east
+  kubeconfigSecret: east-kubeconfig
+  plans: [redis-5-0-7]
+- name: west
+  kubeconfigSecret: west-kubeconfig
+  kubeconfigKey: config
+  clusterDomain: west.local
+`))
+	if err != nil {
+		t.Fatalf("LoadClustersConfig: unexpected error %v", err)
+	}
+	if len(config.Clusters) != 2 {
+		t.Fatalf("LoadClustersConfig: expected 2 clusters, actual %d", len(config.Clusters))
+	}
+	east := config.Clusters[0]
+	if east.KubeconfigKey != DefaultKubeconfigKey || east.ClusterDomain != DefaultClusterDomain {
+		t.Errorf("LoadClustersConfig: expected the defaults, actual %+v", east)
+	}
+	west := config.Clusters[1]
+	if west.KubeconfigKey != "config" || west.ClusterDomain != "west.local" {
+		t.Errorf("LoadClustersConfig: expected the configured values, actual %+v", west)
+	}
+
+	invalid := []string{
+		"clusters: [{kubeconfigSecret: secret}]",
+		"clusters: [{name: east}]",
+		"clusters: [{name: east, kubeconfigSecret: a}, {name: east, kubeconfigSecret: b}]",
+		"clusters: [{name: east, kubeconfigSecret: a, unknown: true}]",
+	}
+	for _, data := range invalid {
+		if _, err := LoadClustersConfig([]byte(data)); err == nil {
+			t.Errorf("LoadClustersConfig(%q): expected an error", data)
+		}
+	}
+}
+
+func TestSelectCluster(t *testing.T) {
+	c := NewClient(context.TODO(), helm.NewDefaultClient(), fake.NewSimpleClientset(), ClientOptions{
+		Clusters: &ClustersConfig{Clusters: []ClusterConfig{
+			{Name: "east", KubeconfigSecret: "east", Plans: []string{"redis-5-0-7"}},
+			{Name: "west", KubeconfigSecret: "west", Plans: []string{"redis-5-0-7", "mysql-8-0-20"}},
+		}},
+	})
+
+	tests := []struct {
+		planID    string
+		requested string
+		expected  string
+	}{
+		{"redis-5-0-7", "", "east"},
+		{"mysql-8-0-20", "", "west"},
+		{"redis-5-0-7", "west", "west"},
+		{"postgresql-11-7-0", "", ""},
+	}
+	for _, tt := range tests {
+		actual, err := c.selectCluster(tt.planID, tt.requested)
+		if err != nil {
+			t.Errorf("selectCluster(%s, %s): unexpected error %v", tt.planID, tt.requested, err)
+		} else if actual != tt.expected {
+			t.Errorf("selectCluster(%s, %s): expected %q, actual %q", tt.planID, tt.requested, tt.expected, actual)
+		}
+	}
+
+	_, err := c.selectCluster("redis-5-0-7", "north")
+	if statusErr, ok := err.(osb.HTTPStatusCodeError); !ok || statusErr.StatusCode != 400 {
+		t.Errorf("selectCluster: expected a 400 error for an unknown cluster, actual %v", err)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "east-kubeconfig",
+			Namespace:       "minibroker",
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{DefaultKubeconfigKey: []byte(testKubeConfig)},
+	}
+	coreClient := fake.NewSimpleClientset(secret)
+	c := NewClient(context.TODO(), helm.NewDefaultClient(), coreClient, ClientOptions{
+		Namespace:     "minibroker",
+		ClusterDomain: "local.domain",
+		Clusters: &ClustersConfig{Clusters: []ClusterConfig{
+			{
+				Name:             "east",
+				KubeconfigSecret: "east-kubeconfig",
+				KubeconfigKey:    DefaultKubeconfigKey,
+				ClusterDomain:    "east.domain",
+			},
+		}},
+	})
+
+	local, err := c.cluster(context.TODO(), "")
+	if err != nil {
+		t.Fatalf("cluster: unexpected error %v", err)
+	}
+	if local.coreClient != coreClient {
+		t.Errorf("cluster: expected the local cluster to use the broker clients")
+	}
+
+	east, err := c.cluster(context.TODO(), "east")
+	if err != nil {
+		t.Fatalf("cluster: unexpected error %v", err)
+	}
+	if east.coreClient == coreClient || east.chartClient == nil {
+		t.Errorf("cluster: expected dedicated clients for the target cluster")
+	}
+	if provider := east.providers["redis"].(RedisProvider); provider.clusterDomain != "east.domain" {
+		t.Errorf("cluster: expected the providers to use the domain %q, actual %q", "east.domain", provider.clusterDomain)
+	}
+
+	cached, err := c.cluster(context.TODO(), "east")
+	if err != nil || cached != east {
+		t.Errorf("cluster: expected the cached cluster, actual %v (%v)", cached, err)
+	}
+
+	secret.ResourceVersion = "2"
+	secret.Data = map[string][]byte{}
+	if _, err := coreClient.CoreV1().Secrets("minibroker").Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := c.cluster(context.TODO(), "east"); err == nil {
+		t.Errorf("cluster: expected an error for a kubeconfig secret without the kubeconfig key")
+	}
+
+	if _, err := c.cluster(context.TODO(), "north"); err == nil {
+		t.Errorf("cluster: expected an error for an unknown cluster")
+	}
+}
+
+func TestGetTargetNamespace(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "east-kubeconfig",
+			Namespace:       "minibroker",
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{DefaultKubeconfigKey: []byte(testKubeConfig)},
+	}
+	local := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}}
+	remote := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "team-a",
+		Labels: map[string]string{"cluster": "east"},
+	}}
+	c := NewClient(context.TODO(), helm.NewDefaultClient(), fake.NewSimpleClientset(secret, local), ClientOptions{
+		Namespace: "minibroker",
+		Clusters: &ClustersConfig{Clusters: []ClusterConfig{
+			{Name: "east", KubeconfigSecret: "east-kubeconfig", KubeconfigKey: DefaultKubeconfigKey, Plans: []string{"redis-5-0-7"}},
+		}},
+	})
+	c.clusterCache["east"] = &cluster{
+		name:            "east",
+		coreClient:      fake.NewSimpleClientset(remote),
+		resourceVersion: "1",
+	}
+
+	tests := []struct {
+		planID    string
+		requested string
+		expected  string
+	}{
+		{"mysql-8-0-20", "", ""},
+		{"redis-5-0-7", "", "east"},
+		{"mysql-8-0-20", "east", "east"},
+	}
+	for _, tt := range tests {
+		ns, err := c.GetTargetNamespace(context.TODO(), tt.planID, tt.requested, "team-a")
+		if err != nil {
+			t.Errorf("GetTargetNamespace(%s, %s): unexpected error %v", tt.planID, tt.requested, err)
+		} else if ns.Labels["cluster"] != tt.expected {
+			t.Errorf("GetTargetNamespace(%s, %s): expected the namespace of cluster %q, actual %v", tt.planID, tt.requested, tt.expected, ns)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConditionsKey is the instance configmap key holding the instance status conditions.
+const ConditionsKey = "status-conditions"
+
+// ConditionType is the type of an instance status condition.
+type ConditionType string
+
+// The instance status condition types.
+const (
+	// ConditionCredentialsDrifted reports whether the credentials of a binding differ from the
+	// snapshot stored when it was bound.
+	ConditionCredentialsDrifted ConditionType = "CredentialsDrifted"
+)
+
+// ConditionStatus is the status of an instance status condition.
+type ConditionStatus string
+
+// The instance status condition statuses.
+const (
+	ConditionTrue  ConditionStatus = "True"
+	ConditionFalse ConditionStatus = "False"
+)
+
+// InstanceCondition is a status condition of an instance, modeled after the Kubernetes API
+// conditions.
+type InstanceCondition struct {
+	Type               ConditionType   `json:"type"`
+	Status             ConditionStatus `json:"status"`
+	Reason             string          `json:"reason,omitempty"`
+	Message            string          `json:"message,omitempty"`
+	LastTransitionTime time.Time       `json:"lastTransitionTime"`
+}
+
+// instanceConditions decodes the status conditions from the instance configmap.
+func instanceConditions(config *corev1.ConfigMap) ([]InstanceCondition, error) {
+	conditions := make([]InstanceCondition, 0)
+	conditionsJSON, ok := config.Data[ConditionsKey]
+	if !ok {
+		return conditions, nil
+	}
+	if err := json.Unmarshal([]byte(conditionsJSON), &conditions); err != nil {
+		return nil, errors.Wrapf(err, "could not decode the conditions of instance %q", config.Name)
+	}
+	return conditions, nil
+}
+
+// setInstanceCondition sets a status condition on the instance configmap. The transition time is
+// only updated when the status changes, and the configmap is left untouched if the condition did
+// not change.
+func (c *Client) setInstanceCondition(ctx context.Context, config *corev1.ConfigMap, condition InstanceCondition) error {
+	conditions, err := instanceConditions(config)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for i, existing := range conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		found = true
+		if existing.Status == condition.Status &&
+			existing.Reason == condition.Reason &&
+			existing.Message == condition.Message {
+			return nil
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = time.Now().UTC()
+		}
+		conditions[i] = condition
+	}
+	if !found {
+		condition.LastTransitionTime = time.Now().UTC()
+		conditions = append(conditions, condition)
+	}
+
+	conditionsJSON, err := json.Marshal(conditions)
+	if err != nil {
+		return err
+	}
+	return c.updateConfigMap(ctx, config.Name, map[string]interface{}{
+		ConditionsKey: string(conditionsJSON),
+	})
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// BindingDriftKeyPrefix is the instance configmap key prefix for the credential keys of a binding
+// which differ from its snapshot, as found by the last live fetch.
+const BindingDriftKeyPrefix = "binding-drift-"
+
+// liveBinding resolves the credentials of a binding against the current services and secrets of
+// the instance instead of returning the snapshot stored at bind time. Differences from the snapshot
+// are reported through the credentials drift metric and the CredentialsDrifted instance condition.
+func (c *Client) liveBinding(
+	ctx context.Context,
+	config *corev1.ConfigMap,
+	bindingID string,
+	snapshot *osb.GetBindingResponse,
+) (*osb.GetBindingResponse, error) {
+	instanceID := config.Name
+	serviceID := config.Data[ServiceKey]
+
+	var provisionParams *ProvisionParams
+	if err := json.Unmarshal([]byte(config.Data[ProvisionParamsKey]), &provisionParams); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshall provision parameters for instance %q", instanceID)
+	}
+
+	target, err := c.instanceCluster(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	chartSecrets, creds, err := c.resolveCredentials(
+		ctx,
+		target,
+		instanceID,
+		serviceID,
+		config.Data[ReleaseNamespaceKey],
+		NewBindParams(snapshot.Parameters),
+		provisionParams,
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not resolve the live credentials of binding %q", bindingID)
+	}
+
+	// The passwords of the binding users are only kept in the snapshot.
+	_, hasBindingUser, err := currentBindingUser(config, bindingID)
+	if err != nil {
+		return nil, err
+	}
+	if hasBindingUser {
+		username, _ := snapshot.Credentials["username"].(string)
+		password, _ := snapshot.Credentials["password"].(string)
+		creds, err = withBindingUser(creds, &BindingUser{Username: username, Password: password})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	live := c.allowedChartSecrets(serviceID, provisionParams, chartSecrets, hasBindingUser)
+	for k, v := range creds {
+		live[k] = v
+	}
+	liveCredentials, err := normalizeCredentials(live)
+	if err != nil {
+		return nil, err
+	}
+
+	c.recordCredentialsDrift(ctx, config, bindingID, driftedKeys(snapshot.Credentials, liveCredentials))
+
+	response := *snapshot
+	response.Credentials = liveCredentials
+	return &response, nil
+}
+
+// recordCredentialsDrift records the drifted keys of a binding and updates the CredentialsDrifted
+// condition from the drift of all the bindings, so a binding matching its snapshot does not hide
+// the drift of another. The drift metric only counts the bindings becoming drifted.
+func (c *Client) recordCredentialsDrift(ctx context.Context, config *corev1.ConfigMap, bindingID string, driftedKeys []string) {
+	instanceID := config.Name
+	logger := contextLogger(ctx).WithValues("instanceID", instanceID, "bindingID", bindingID)
+	drifted := strings.Join(driftedKeys, ", ")
+	driftKey := BindingDriftKeyPrefix + bindingID
+	if previous := config.Data[driftKey]; drifted != previous {
+		var value interface{}
+		if drifted != "" {
+			value = drifted
+		}
+		if previous == "" {
+			logger.V(2).Info("credentials drifted", "keys", drifted)
+			credentialsDriftTotal.WithLabelValues(config.Data[ServiceKey]).Inc()
+		}
+		if err := c.updateConfigMap(ctx, instanceID, map[string]interface{}{driftKey: value}); err != nil {
+			logger.V(2).Info("failed to record the credentials drift", "error", err)
+		} else if drifted == "" {
+			delete(config.Data, driftKey)
+		} else {
+			config.Data[driftKey] = drifted
+		}
+	}
+	if err := c.setInstanceCondition(ctx, config, credentialsDriftCondition(config)); err != nil {
+		logger.V(2).Info("failed to set the credentials drift condition", "error", err)
+	}
+}
+
+// credentialsDriftCondition builds the CredentialsDrifted condition from the drift recorded for each
+// binding of the instance.
+func credentialsDriftCondition(config *corev1.ConfigMap) InstanceCondition {
+	messages := make([]string, 0)
+	for key, drifted := range config.Data {
+		if strings.HasPrefix(key, BindingDriftKeyPrefix) && drifted != "" {
+			bindingID := strings.TrimPrefix(key, BindingDriftKeyPrefix)
+			messages = append(messages, fmt.Sprintf("binding %q: %s changed since bind", bindingID, drifted))
+		}
+	}
+	if len(messages) == 0 {
+		return InstanceCondition{
+			Type:   ConditionCredentialsDrifted,
+			Status: ConditionFalse,
+			Reason: "CredentialsMatch",
+		}
+	}
+	sort.Strings(messages)
+	return InstanceCondition{
+		Type:    ConditionCredentialsDrifted,
+		Status:  ConditionTrue,
+		Reason:  "CredentialsChanged",
+		Message: strings.Join(messages, "; "),
+	}
+}
+
+// allowedChartSecrets returns the chart secrets that may be included in the binding credentials of
+// the service. Only the keys declared by the provider for the provisioning parameters, or the
+// generic keys for charts without a provider, are allowed, unless the passthrough mode is enabled.
+// No chart secret is allowed for the bindings with a dedicated database user, even in the
+// passthrough mode, as the chart secrets hold the administrator credentials.
+func (c *Client) allowedChartSecrets(serviceID string, provisionParams *ProvisionParams, chartSecrets Object, hasBindingUser bool) Object {
+	allowed := make(Object)
+	if hasBindingUser {
+		return allowed
+	}
+	if c.secretKeysPassthrough {
+		for k, v := range chartSecrets {
+			allowed[k] = v
+		}
+		return allowed
+	}
+
+	keys := c.genericSecretKeys
+	if provider, ok := c.providers[serviceID]; ok {
+		keys = provider.SecretKeys(provisionParams)
+	}
+	for _, key := range keys {
+		if value, ok := chartSecrets[key]; ok {
+			allowed[key] = value
+		}
+	}
+	return allowed
+}
+
+// normalizeCredentials round-trips the credentials through JSON so they can be compared with a
+// decoded snapshot, e.g. ports become float64 values.
+func normalizeCredentials(creds Object) (map[string]interface{}, error) {
+	credsJSON, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+	var normalized map[string]interface{}
+	if err := json.Unmarshal(credsJSON, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// driftedKeys returns the sorted keys whose values differ between the snapshot and the live
+// credentials. Values are never returned, so the result is safe to log.
+func driftedKeys(snapshot, live map[string]interface{}) []string {
+	keys := make(map[string]struct{})
+	for k := range snapshot {
+		keys[k] = struct{}{}
+	}
+	for k := range live {
+		keys[k] = struct{}{}
+	}
+
+	drifted := make([]string, 0)
+	for k := range keys {
+		if !reflect.DeepEqual(snapshot[k], live[k]) {
+			drifted = append(drifted, k)
+		}
+	}
+	sort.Strings(drifted)
+	return drifted
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDriftedKeys(t *testing.T) {
+	snapshot := map[string]interface{}{
+		"host":     "db.namespace.svc.cluster.local",
+		"port":     float64(5432),
+		"password": "old",
+		"removed":  "value",
+	}
+	live, err := normalizeCredentials(Object{
+		"host":     "db.namespace.svc.cluster.local",
+		"port":     int32(5433),
+		"password": "new",
+		"added":    "value",
+	})
+	if err != nil {
+		t.Fatalf("normalizeCredentials: unexpected error %v", err)
+	}
+
+	actual := driftedKeys(snapshot, live)
+	expected := []string{"added", "password", "port", "removed"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("driftedKeys: expected %v, actual %v", expected, actual)
+	}
+
+	if drifted := driftedKeys(snapshot, snapshot); len(drifted) != 0 {
+		t.Errorf("driftedKeys: expected no drift, actual %v", drifted)
+	}
+}
+
+func TestSetInstanceCondition(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance",
+			Namespace: "minibroker",
+		},
+		Data: map[string]string{},
+	}
+	coreClient := fake.NewSimpleClientset(config)
+	c := &Client{namespace: "minibroker", coreClient: coreClient}
+
+	getConditions := func() []InstanceCondition {
+		current, err := coreClient.CoreV1().ConfigMaps("minibroker").Get(context.TODO(), "instance", metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		conditions, err := instanceConditions(current)
+		if err != nil {
+			t.Fatalf("instanceConditions: unexpected error %v", err)
+		}
+		return conditions
+	}
+
+	drifted := InstanceCondition{Type: ConditionCredentialsDrifted, Status: ConditionTrue, Reason: "CredentialsChanged"}
+	if err := c.setInstanceCondition(context.TODO(), config, drifted); err != nil {
+		t.Fatalf("setInstanceCondition: unexpected error %v", err)
+	}
+	conditions := getConditions()
+	if len(conditions) != 1 || conditions[0].Status != ConditionTrue || conditions[0].LastTransitionTime.IsZero() {
+		t.Errorf("setInstanceCondition: expected a single True condition, actual %v", conditions)
+	}
+
+	current, _ := coreClient.CoreV1().ConfigMaps("minibroker").Get(context.TODO(), "instance", metav1.GetOptions{})
+	matched := InstanceCondition{Type: ConditionCredentialsDrifted, Status: ConditionFalse, Reason: "CredentialsMatch"}
+	if err := c.setInstanceCondition(context.TODO(), current, matched); err != nil {
+		t.Fatalf("setInstanceCondition: unexpected error %v", err)
+	}
+	conditions = getConditions()
+	if len(conditions) != 1 || conditions[0].Status != ConditionFalse {
+		t.Errorf("setInstanceCondition: expected a single False condition, actual %v", conditions)
+	}
+}
+
+func TestAllowedChartSecrets(t *testing.T) {
+	chartSecrets := Object{
+		"rabbitmq-password":      "password",
+		"rabbitmq-erlang-cookie": "cookie",
+		"tls.key":                "key",
+	}
+	providers := map[string]Provider{
+		"rabbitmq": RabbitmqProvider{},
+	}
+
+	tests := []struct {
+		client         *Client
+		serviceID      string
+		hasBindingUser bool
+		expected       Object
+	}{
+		{
+			&Client{providers: providers},
+			"rabbitmq",
+			false,
+			Object{"rabbitmq-password": "password"},
+		},
+		{
+			&Client{providers: providers},
+			"rabbitmq",
+			true,
+			Object{},
+		},
+		{
+			&Client{providers: providers, genericSecretKeys: []string{"tls.key"}},
+			"generic",
+			false,
+			Object{"tls.key": "key"},
+		},
+		{
+			&Client{providers: providers},
+			"generic",
+			false,
+			Object{},
+		},
+		{
+			&Client{providers: providers, secretKeysPassthrough: true},
+			"rabbitmq",
+			false,
+			chartSecrets,
+		},
+		{
+			&Client{providers: providers, secretKeysPassthrough: true},
+			"rabbitmq",
+			true,
+			Object{},
+		},
+	}
+
+	for _, tt := range tests {
+		actual := tt.client.allowedChartSecrets(tt.serviceID, NewProvisionParams(nil), chartSecrets, tt.hasBindingUser)
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("allowedChartSecrets(%s, %v): expected %v, actual %v", tt.serviceID, tt.hasBindingUser, tt.expected, actual)
+		}
+	}
+}
+
+func TestProviderSecretKeys(t *testing.T) {
+	tests := []struct {
+		provider Provider
+		params   map[string]interface{}
+		expected []string
+	}{
+		{MySQLProvider{}, nil, []string{"mysql-root-password"}},
+		{MySQLProvider{}, map[string]interface{}{"mysqlUser": "app"}, []string{"mysql-password"}},
+		{MariadbProvider{}, nil, []string{"mariadb-root-password"}},
+		{MariadbProvider{}, map[string]interface{}{"db": map[string]interface{}{"user": "app"}}, []string{"mariadb-password"}},
+		{MongodbProvider{}, nil, []string{"mongodb-root-password"}},
+		{MongodbProvider{}, map[string]interface{}{"mongodbUsername": "app"}, []string{"mongodb-password"}},
+		{PostgresProvider{}, nil, []string{"postgresql-password", "postgres-password"}},
+		{PostgresProvider{}, map[string]interface{}{"postgresqlUsername": "app"}, []string{"postgresql-password", "postgres-password"}},
+		{
+			PostgresProvider{},
+			map[string]interface{}{"postgresqlUsername": "app", "postgresqlPostgresPassword": "secret"},
+			[]string{"postgresql-postgres-password"},
+		},
+	}
+
+	for _, tt := range tests {
+		actual := tt.provider.SecretKeys(NewProvisionParams(tt.params))
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("%T.SecretKeys(%v): expected %v, actual %v", tt.provider, tt.params, tt.expected, actual)
+		}
+	}
+}
+
+func TestAllowedChartSecretsNonRootUser(t *testing.T) {
+	chartSecrets := Object{
+		"mysql-root-password": "root",
+		"mysql-password":      "password",
+	}
+	c := &Client{providers: map[string]Provider{"mysql": MySQLProvider{}}}
+	params := NewProvisionParams(map[string]interface{}{"mysqlUser": "app"})
+
+	actual := c.allowedChartSecrets("mysql", params, chartSecrets, false)
+	if _, ok := actual["mysql-root-password"]; ok {
+		t.Errorf("allowedChartSecrets: expected no mysql-root-password for a non-root user, actual %v", actual)
+	}
+	if expected := (Object{"mysql-password": "password"}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("allowedChartSecrets: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestRecordCredentialsDrift(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "drift",
+			Namespace: "minibroker",
+		},
+		Data: map[string]string{ServiceKey: "drift-service"},
+	}
+	coreClient := fake.NewSimpleClientset(config)
+	c := &Client{namespace: "minibroker", coreClient: coreClient}
+	drifts := credentialsDriftTotal.WithLabelValues("drift-service")
+
+	record := func(bindingID string, drifted ...string) InstanceCondition {
+		current, err := c.getConfigMap(context.TODO(), "drift")
+		if err != nil {
+			t.Fatalf("getConfigMap: unexpected error %v", err)
+		}
+		c.recordCredentialsDrift(context.TODO(), current, bindingID, drifted)
+		current, _ = c.getConfigMap(context.TODO(), "drift")
+		conditions, err := instanceConditions(current)
+		if err != nil || len(conditions) != 1 {
+			t.Fatalf("recordCredentialsDrift: expected a single condition, actual %v (%v)", conditions, err)
+		}
+		return conditions[0]
+	}
+
+	before := testutil.ToFloat64(drifts)
+	condition := record("a", "password")
+	if condition.Status != ConditionTrue || condition.Message != `binding "a": password changed since bind` {
+		t.Errorf("recordCredentialsDrift: unexpected condition %+v", condition)
+	}
+	record("a", "password")
+	if count := testutil.ToFloat64(drifts) - before; count != 1 {
+		t.Errorf("recordCredentialsDrift: expected the drift to be counted once, actual %v", count)
+	}
+
+	// A binding matching its snapshot does not hide the drift of another.
+	condition = record("b")
+	if condition.Status != ConditionTrue || condition.Message != `binding "a": password changed since bind` {
+		t.Errorf("recordCredentialsDrift: unexpected condition %+v", condition)
+	}
+
+	condition = record("a")
+	if condition.Status != ConditionFalse || condition.Reason != "CredentialsMatch" {
+		t.Errorf("recordCredentialsDrift: unexpected condition %+v", condition)
+	}
+	current, _ := c.getConfigMap(context.TODO(), "drift")
+	if _, ok := current.Data[BindingDriftKeyPrefix+"a"]; ok {
+		t.Errorf("recordCredentialsDrift: expected the drift of binding a to be cleared")
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// The classes of the operation failures reported in the last operation descriptions.
+const (
+	FailureChartNotFound    = "chart_not_found"
+	FailureChartDownload    = "chart_download"
+	FailureChartRender      = "chart_render"
+	FailureReadinessTimeout = "readiness_timeout"
+	FailureQuotaExceeded    = "quota_exceeded"
+	FailureImagePull        = "image_pull"
+)
+
+// maxDiagnosedPods is the number of pods named in a readiness failure summary.
+const maxDiagnosedPods = 3
+
+// failureMessages are the user-safe explanations of the failure classes. They never include the
+// underlying error, which may leak the broker internals.
+var failureMessages = map[string]string{
+	FailureChartNotFound:    "the chart of the plan is not available in the chart repository",
+	FailureChartDownload:    "the chart could not be downloaded from the chart repository",
+	FailureChartRender:      "the chart could not be rendered with the provided parameters",
+	FailureReadinessTimeout: "the service did not become ready in time",
+	FailureQuotaExceeded:    "the resource quota of the namespace was exceeded",
+	FailureImagePull:        "a container image of the service could not be pulled",
+	"timeout":               "the operation timed out",
+}
+
+// defaultFailureMessage explains the failures without a user-safe explanation.
+const defaultFailureMessage = "an internal error occurred, the broker logs have the details"
+
+// imagePullReasons are the container waiting reasons of the image pull failures.
+var imagePullReasons = map[string]bool{
+	"ErrImagePull":     true,
+	"ImagePullBackOff": true,
+	"InvalidImageName": true,
+}
+
+// failureClass returns the class of the chart and quota failures, or an empty string.
+func failureClass(err error) string {
+	switch {
+	case errors.Is(err, helm.ErrChartNotFound):
+		return FailureChartNotFound
+	case errors.Is(err, helm.ErrChartDownload):
+		return FailureChartDownload
+	case errors.Is(err, helm.ErrChartRender):
+		return FailureChartRender
+	case errors.Is(err, helm.ErrReleaseTimeout):
+		return FailureReadinessTimeout
+	case strings.Contains(err.Error(), "exceeded quota"):
+		return FailureQuotaExceeded
+	}
+	return ""
+}
+
+// failureDescription returns the user-safe description of a failed operation, following the
+// summary of the operation. When the release did not become ready, the pods of the release are
+// inspected and summarized, e.g. "pod db-mysql-0 ImagePullBackOff".
+func failureDescription(ctx context.Context, target *cluster, summary string, err error) string {
+	class := ErrorClass(err)
+	var pods string
+	var helmErr *helm.Error
+	if class == FailureReadinessTimeout && target != nil && errors.As(err, &helmErr) {
+		var imagePull bool
+		pods, imagePull = releaseDiagnostics(ctx, target.coreClient, helmErr.Namespace, helmErr.Release)
+		if imagePull {
+			class = FailureImagePull
+		}
+	}
+
+	message, ok := failureMessages[class]
+	if !ok {
+		message = defaultFailureMessage
+	}
+	description := summary + ": " + message
+	if pods != "" {
+		description += " (" + pods + ")"
+	}
+	return description
+}
+
+// releaseSelectors returns the label selectors matching the resources of a release, in the order
+// they are tried: the label of the older charts, then the recommended instance label.
+func releaseSelectors(releaseName string) []string {
+	return []string{
+		labels.SelectorFromSet(map[string]string{ReleaseLabel: releaseName}).String(),
+		labels.SelectorFromSet(map[string]string{"app.kubernetes.io/instance": releaseName}).String(),
+	}
+}
+
+// releaseDiagnostics summarizes the pods of a release which are not ready with the reason they are
+// waiting for, or the reason of their latest warning event. It also reports whether a pod is
+// failing to pull its image. The diagnostics are best effort: the errors are ignored.
+func releaseDiagnostics(ctx context.Context, coreClient kubernetes.Interface, namespace, releaseName string) (string, bool) {
+	var pods []corev1.Pod
+	for _, selector := range releaseSelectors(releaseName) {
+		list, err := coreClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err == nil && len(list.Items) > 0 {
+			pods = list.Items
+			break
+		}
+	}
+	if len(pods) == 0 {
+		return "", false
+	}
+
+	warnings := make(map[string]corev1.Event)
+	events, err := coreClient.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		// The description is still built from the pod states without the events.
+		contextLogger(ctx).V(4).Info("failed to list the release events", "namespace", namespace, "release", releaseName, "error", err)
+	} else {
+		for _, event := range events.Items {
+			if event.Type != corev1.EventTypeWarning || event.InvolvedObject.Kind != "Pod" {
+				continue
+			}
+			latest, ok := warnings[event.InvolvedObject.Name]
+			if !ok || latest.LastTimestamp.Before(&event.LastTimestamp) {
+				warnings[event.InvolvedObject.Name] = event
+			}
+		}
+	}
+
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	var diagnosed []string
+	imagePull := false
+	for _, pod := range pods {
+		if podReady(&pod) {
+			continue
+		}
+		reason := podWaitingReason(&pod)
+		if imagePullReasons[reason] {
+			imagePull = true
+		}
+		if reason == "" {
+			if event, ok := warnings[pod.Name]; ok {
+				reason = event.Reason
+			} else {
+				reason = string(pod.Status.Phase)
+			}
+		}
+		diagnosed = append(diagnosed, fmt.Sprintf("pod %s %s", pod.Name, reason))
+	}
+	if len(diagnosed) > maxDiagnosedPods {
+		more := len(diagnosed) - maxDiagnosedPods
+		diagnosed = append(diagnosed[:maxDiagnosedPods], fmt.Sprintf("%d more", more))
+	}
+	return strings.Join(diagnosed, ", "), imagePull
+}
+
+// podReady returns whether the pod has the Ready condition.
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podWaitingReason returns the reason of the first container of the pod waiting or terminated in
+// error, e.g. ImagePullBackOff or CrashLoopBackOff.
+func podWaitingReason(pod *corev1.Pod) string {
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "PodInitializing" && waiting.Reason != "ContainerCreating" {
+			return waiting.Reason
+		}
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 && terminated.Reason != "" {
+			return terminated.Reason
+		}
+	}
+	return ""
+}
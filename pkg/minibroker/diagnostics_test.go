@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestFailureClass(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{err: &helm.Error{Class: helm.ErrChartNotFound, Err: fmt.Errorf("failed to get chart")}, expected: FailureChartNotFound},
+		{err: &helm.Error{Class: helm.ErrChartDownload, Err: fmt.Errorf("failed to install chart")}, expected: FailureChartDownload},
+		{err: &helm.Error{Class: helm.ErrChartRender, Err: fmt.Errorf("failed to install chart")}, expected: FailureChartRender},
+		{err: &helm.Error{Class: helm.ErrReleaseTimeout, Err: fmt.Errorf("failed to install chart")}, expected: FailureReadinessTimeout},
+		{
+			err:      apierrors.NewForbidden(corev1.Resource("pods"), "db-0", fmt.Errorf("exceeded quota: compute, requested: cpu=2")),
+			expected: FailureQuotaExceeded,
+		},
+		{err: apierrors.NewForbidden(corev1.Resource("pods"), "db-0", fmt.Errorf("denied")), expected: "forbidden"},
+	}
+	for _, tc := range cases {
+		if actual := ErrorClass(tc.err); actual != tc.expected {
+			t.Errorf("ErrorClass(%v): expected %q, actual %q", tc.err, tc.expected, actual)
+		}
+	}
+}
+
+func newDiagnosedPod(name string, ready bool, waiting string, phase corev1.PodPhase) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "team-a",
+			Labels:    map[string]string{ReleaseLabel: "db-mysql"},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: status}}
+	if waiting != "" {
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "mysql",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: waiting, Message: "secret details"}},
+		}}
+	}
+	return pod
+}
+
+func TestFailureDescription(t *testing.T) {
+	timeout := &helm.Error{
+		Class:     helm.ErrReleaseTimeout,
+		Release:   "db-mysql",
+		Namespace: "team-a",
+		Err:       fmt.Errorf("failed to install chart: timed out waiting for the condition"),
+	}
+	scheduling := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "db-mysql-1.1", Namespace: "team-a"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "db-mysql-1"},
+		Type:           corev1.EventTypeWarning,
+		Reason:         "FailedScheduling",
+	}
+
+	cases := []struct {
+		name         string
+		objects      []runtime.Object
+		forbidEvents bool
+		err          error
+		expected     string
+	}{
+		{
+			name: "image pull",
+			objects: []runtime.Object{
+				newDiagnosedPod("db-mysql-0", false, "ImagePullBackOff", corev1.PodPending),
+				newDiagnosedPod("db-mysql-1", false, "", corev1.PodPending),
+				newDiagnosedPod("db-mysql-2", true, "", corev1.PodRunning),
+				scheduling,
+			},
+			err: timeout,
+			expected: `service instance "db" failed to provision: a container image of the service could not be pulled ` +
+				`(pod db-mysql-0 ImagePullBackOff, pod db-mysql-1 FailedScheduling)`,
+		},
+		{
+			name: "image pull without access to the events",
+			objects: []runtime.Object{
+				newDiagnosedPod("db-mysql-0", false, "ImagePullBackOff", corev1.PodPending),
+				newDiagnosedPod("db-mysql-1", false, "", corev1.PodPending),
+				scheduling,
+			},
+			forbidEvents: true,
+			err:          timeout,
+			expected: `service instance "db" failed to provision: a container image of the service could not be pulled ` +
+				`(pod db-mysql-0 ImagePullBackOff, pod db-mysql-1 Pending)`,
+		},
+		{
+			name:     "readiness timeout",
+			objects:  []runtime.Object{newDiagnosedPod("db-mysql-0", false, "CrashLoopBackOff", corev1.PodRunning)},
+			err:      timeout,
+			expected: `service instance "db" failed to provision: the service did not become ready in time (pod db-mysql-0 CrashLoopBackOff)`,
+		},
+		{
+			name:     "readiness timeout without pods",
+			err:      timeout,
+			expected: `service instance "db" failed to provision: the service did not become ready in time`,
+		},
+		{
+			name:     "internal error",
+			err:      fmt.Errorf("could not update the instance configmap: secret details"),
+			expected: `service instance "db" failed to provision: an internal error occurred, the broker logs have the details`,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			coreClient := fake.NewSimpleClientset(tc.objects...)
+			if tc.forbidEvents {
+				coreClient.PrependReactor("list", "events", func(k8stesting.Action) (bool, runtime.Object, error) {
+					return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "events"}, "", fmt.Errorf("denied"))
+				})
+			}
+			target := &cluster{coreClient: coreClient}
+			actual := failureDescription(context.Background(), target, `service instance "db" failed to provision`, tc.err)
+			if actual != tc.expected {
+				t.Errorf("expected %q, actual %q", tc.expected, actual)
+			}
+			if strings.Contains(actual, "secret details") {
+				t.Errorf("expected a description without the error details, actual %q", actual)
+			}
+		})
+	}
+}
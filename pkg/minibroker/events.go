@@ -0,0 +1,83 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// EventComponent is the source component of the Kubernetes Events recorded by Minibroker.
+const EventComponent = "minibroker"
+
+// The reasons of the Kubernetes Events recorded for the instance and binding lifecycle.
+const (
+	ReasonProvisioning      = "Provisioning"
+	ReasonProvisioned       = "Provisioned"
+	ReasonProvisionFailed   = "ProvisionFailed"
+	ReasonDeprovisioning    = "Deprovisioning"
+	ReasonDeprovisioned     = "Deprovisioned"
+	ReasonDeprovisionFailed = "DeprovisionFailed"
+	ReasonBinding           = "Binding"
+	ReasonBound             = "Bound"
+	ReasonBindFailed        = "BindFailed"
+	ReasonUnbound           = "Unbound"
+	ReasonUnbindFailed      = "UnbindFailed"
+	// ReasonCredentialsRotationFailed reports the bindings whose credentials failed to rotate on
+	// the request of the RotateCredentialsAnnotation.
+	ReasonCredentialsRotationFailed = "CredentialsRotationFailed"
+)
+
+// NewEventRecorder creates an EventRecorder writing the Events through the clientset. The
+// broadcaster stops when the context is done.
+func NewEventRecorder(ctx context.Context, coreClient kubernetes.Interface) record.EventRecorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: coreClient.CoreV1().Events("")})
+	go func() {
+		<-ctx.Done()
+		broadcaster.Shutdown()
+	}()
+	return broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: EventComponent})
+}
+
+// recordEvent records an Event on the instance configmap and, when the release is in the cluster
+// Minibroker runs in, on the release namespace. The Events of the releases in other clusters are
+// only recorded on the configmap.
+func (c *Client) recordEvent(config *corev1.ConfigMap, namespace, eventType, reason, messageFmt string, args ...interface{}) {
+	if c.recorder == nil || config == nil {
+		return
+	}
+	c.recorder.Eventf(config, eventType, reason, messageFmt, args...)
+	if namespace != "" && config.Data[ClusterKey] == "" {
+		c.recorder.Eventf(namespaceReference(namespace), eventType, reason, messageFmt, args...)
+	}
+}
+
+// namespaceReference references a namespace, recording its Events in the namespace itself.
+func namespaceReference(namespace string) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Namespace",
+		Name:       namespace,
+		Namespace:  namespace,
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func drainEvents(recorder *record.FakeRecorder) []string {
+	var events []string
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestRecordEvent(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	c := &Client{recorder: recorder}
+	local := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "minibroker"}}
+	remote := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "minibroker"},
+		Data:       map[string]string{ClusterKey: "edge"},
+	}
+
+	c.recordEvent(local, "team-a", corev1.EventTypeWarning, ReasonProvisionFailed,
+		"Failed to provision instance %q: %v", "db", errors.New("chart not found"))
+	expected := `Warning ProvisionFailed Failed to provision instance "db": chart not found`
+	events := drainEvents(recorder)
+	if len(events) != 2 || events[0] != expected || events[1] != expected {
+		t.Errorf("expected the event on the configmap and the namespace, actual %q", events)
+	}
+
+	c.recordEvent(remote, "team-a", corev1.EventTypeNormal, ReasonProvisioned, "Provisioned")
+	if events := drainEvents(recorder); len(events) != 1 {
+		t.Errorf("expected the event on the configmap only for a remote release, actual %q", events)
+	}
+
+	c.recordEvent(local, "", corev1.EventTypeNormal, ReasonProvisioned, "Provisioned")
+	if events := drainEvents(recorder); len(events) != 1 {
+		t.Errorf("expected the event on the configmap only without a namespace, actual %q", events)
+	}
+
+	(&Client{}).recordEvent(local, "team-a", corev1.EventTypeNormal, ReasonProvisioned, "Provisioned")
+}
+
+func TestUnbindEvents(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "minibroker"},
+		Data: map[string]string{
+			ServiceKey:          "redis",
+			ReleaseNamespaceKey: "team-a",
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	c := &Client{
+		namespace:  "minibroker",
+		coreClient: fake.NewSimpleClientset(config),
+		recorder:   recorder,
+	}
+
+	if err := c.Unbind(context.Background(), "db", "app"); err != nil {
+		t.Fatalf("Unbind: unexpected error: %v", err)
+	}
+	expected := `Normal Unbound Unbound "app" from instance "db"`
+	events := drainEvents(recorder)
+	if len(events) != 2 || events[0] != expected || events[1] != expected {
+		t.Errorf("expected %q on the configmap and the namespace, actual %q", expected, events)
+	}
+
+	if err := c.Unbind(context.Background(), "missing", "app"); err == nil {
+		t.Fatal("Unbind: expected an error for a missing instance")
+	}
+	if events := drainEvents(recorder); len(events) != 0 {
+		t.Errorf("expected no events without an instance, actual %q", events)
+	}
+}
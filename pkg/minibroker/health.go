@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/health"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// readinessConfigMapName is the name of the configmap created in dry-run mode for checking the
+// state store is writable.
+const readinessConfigMapName = "minibroker-readiness-check"
+
+// requiredAccess returns the access to the config namespace the broker needs for keeping its state
+// and, when target clusters are configured, for reading their kubeconfig Secrets.
+func (c *Client) requiredAccess() []authorizationv1.ResourceAttributes {
+	access := []authorizationv1.ResourceAttributes{
+		{Resource: "configmaps", Verb: "get"},
+		{Resource: "configmaps", Verb: "list"},
+		{Resource: "configmaps", Verb: "create"},
+		{Resource: "configmaps", Verb: "update"},
+		{Resource: "configmaps", Verb: "delete"},
+	}
+	if len(c.clusters) > 0 {
+		access = append(access, authorizationv1.ResourceAttributes{Resource: "secrets", Verb: "get"})
+	}
+	return access
+}
+
+// ReadinessChecks returns the checks of the dependencies the client needs for serving requests: the
+// chart index loaded and not older than maxIndexAge (when positive), the Kubernetes API reachable
+// with the required access and the state store writable.
+func (c *Client) ReadinessChecks(maxIndexAge time.Duration) []health.Check {
+	return []health.Check{
+		{Name: "chart-index", Run: func(context.Context) error {
+			return c.checkChartIndex(maxIndexAge)
+		}},
+		{Name: "kubernetes-api", Run: c.checkKubernetesAccess},
+		{Name: "state-store", Run: c.checkStateStore},
+	}
+}
+
+func (c *Client) checkChartIndex(maxAge time.Duration) error {
+	loadedAt := c.helm.IndexLoadedAt()
+	if loadedAt.IsZero() {
+		return fmt.Errorf("the chart index is not loaded")
+	}
+	if age := time.Since(loadedAt); maxAge > 0 && age > maxAge {
+		return fmt.Errorf("the chart index was loaded %s ago, more than %s", age.Round(time.Second), maxAge)
+	}
+	return nil
+}
+
+func (c *Client) checkKubernetesAccess(ctx context.Context) error {
+	for _, attributes := range c.requiredAccess() {
+		attributes.Namespace = c.namespace
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
+		}
+		result, err := c.coreClient.AuthorizationV1().
+			SelfSubjectAccessReviews().
+			Create(ctx, review, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to reach the Kubernetes API: %w", err)
+		}
+		if !result.Status.Allowed {
+			return fmt.Errorf("not allowed to %s %s in namespace %q",
+				attributes.Verb, attributes.Resource, c.namespace)
+		}
+	}
+	return nil
+}
+
+func (c *Client) checkStateStore(ctx context.Context) error {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      readinessConfigMapName,
+			Namespace: c.namespace,
+		},
+	}
+	_, err := c.coreClient.CoreV1().
+		ConfigMaps(c.namespace).
+		Create(ctx, config, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil {
+		return fmt.Errorf("the state store is not writable: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	"github.com/kubernetes-sigs/minibroker/pkg/helm/mocks"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"helm.sh/helm/v3/pkg/repo"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func runReadinessCheck(t *testing.T, c *Client, name string) error {
+	t.Helper()
+	for _, check := range c.ReadinessChecks(0) {
+		if check.Name == name {
+			return check.Run(context.Background())
+		}
+	}
+	t.Fatalf("readiness check %q not found", name)
+	return nil
+}
+
+func TestReadinessChartIndex(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repoClient := mocks.NewMockRepositoryInitializeDownloadLoader(ctrl)
+	repoClient.EXPECT().Initialize(gomock.Any(), gomock.Any()).Return(&repo.ChartRepository{}, nil)
+	repoClient.EXPECT().DownloadIndex(gomock.Any()).Return("index.yaml", nil)
+	repoClient.EXPECT().Load("index.yaml").Return(repo.NewIndexFile(), nil)
+
+	c := &Client{helm: helm.NewClient(log.NewNoop(), repoClient, nil)}
+	if err := runReadinessCheck(t, c, "chart-index"); err == nil {
+		t.Fatalf("expected the check to fail before the index is loaded")
+	}
+	if err := c.Init(""); err != nil {
+		t.Fatalf("Init: unexpected error %v", err)
+	}
+	if err := runReadinessCheck(t, c, "chart-index"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+	if err := c.checkChartIndex(1); err == nil {
+		t.Errorf("expected the check to fail with a stale index")
+	}
+}
+
+func TestReadinessKubernetesAPI(t *testing.T) {
+	// The reviews allow the access granted by the namespaced Role of the chart without clusters.
+	newClient := func(clusters map[string]ClusterConfig, reviewed *[]string) *Client {
+		coreClient := fake.NewSimpleClientset()
+		coreClient.PrependReactor("create", "selfsubjectaccessreviews",
+			func(action k8stesting.Action) (bool, runtime.Object, error) {
+				review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+				attributes := review.Spec.ResourceAttributes
+				if attributes.Namespace != "minibroker" {
+					t.Errorf("unexpected namespace %q", attributes.Namespace)
+				}
+				*reviewed = append(*reviewed, attributes.Verb+" "+attributes.Resource)
+				review.Status.Allowed = attributes.Resource == "configmaps"
+				return true, review, nil
+			})
+		return &Client{coreClient: coreClient, namespace: "minibroker", clusters: clusters}
+	}
+
+	var reviewed []string
+	if err := runReadinessCheck(t, newClient(nil, &reviewed), "kubernetes-api"); err != nil {
+		t.Errorf("expected the namespaced access to be sufficient without clusters, got %v", err)
+	}
+	if len(reviewed) != 5 {
+		t.Errorf("expected 5 reviews, got %v", reviewed)
+	}
+
+	reviewed = nil
+	clusters := map[string]ClusterConfig{"remote": {Name: "remote"}}
+	err := runReadinessCheck(t, newClient(clusters, &reviewed), "kubernetes-api")
+	if err == nil || !strings.Contains(err.Error(), "not allowed to get secrets") {
+		t.Errorf("expected a denied access error, got %v", err)
+	}
+	if len(reviewed) != 6 {
+		t.Errorf("expected 6 reviews, got %v", reviewed)
+	}
+}
+
+func TestReadinessStateStore(t *testing.T) {
+	c := &Client{coreClient: fake.NewSimpleClientset(), namespace: "minibroker"}
+	if err := runReadinessCheck(t, c, "state-store"); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	coreClient := fake.NewSimpleClientset()
+	coreClient.PrependReactor("create", "configmaps",
+		func(action k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apierrors.NewForbidden(
+				corev1.Resource("configmaps"), readinessConfigMapName, errors.New("denied"))
+		})
+	c = &Client{coreClient: coreClient, namespace: "minibroker"}
+	if err := runReadinessCheck(t, c, "state-store"); err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("expected a forbidden error, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// OriginatingIdentityKey is the instance configmap key holding the identity of the platform user
+	// who requested the provisioning.
+	OriginatingIdentityKey = "originating-identity"
+	// BindingIdentityKeyPrefix is the instance configmap key prefix for the identities of the
+	// platform users who requested the bindings.
+	BindingIdentityKeyPrefix = "binding-identity-"
+)
+
+// Identity is the platform user on whose behalf an OSB request is made, decoded from the
+// X-Broker-API-Originating-Identity header.
+type Identity struct {
+	// The platform the user belongs to, e.g. kubernetes or cloudfoundry.
+	Platform string `json:"platform"`
+	// The Kubernetes user name.
+	Username string `json:"username,omitempty"`
+	// The Kubernetes user UID.
+	UID string `json:"uid,omitempty"`
+	// The Kubernetes user groups.
+	Groups []string `json:"groups,omitempty"`
+	// The Kubernetes user extra attributes.
+	Extra map[string][]string `json:"extra,omitempty"`
+	// The Cloud Foundry user GUID.
+	UserID string `json:"userID,omitempty"`
+	// The identity value as sent by the platform.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// String returns the user name or ID of the identity, prefixed with its platform.
+func (i *Identity) String() string {
+	if i == nil {
+		return ""
+	}
+	switch {
+	case i.Username != "":
+		return i.Platform + ":" + i.Username
+	case i.UserID != "":
+		return i.Platform + ":" + i.UserID
+	default:
+		return i.Platform
+	}
+}
+
+// identityData returns the configmap data recording the identity under the given key, or nothing
+// when there is no identity.
+func identityData(key string, identity *Identity) (map[string]interface{}, error) {
+	if identity == nil {
+		return map[string]interface{}{}, nil
+	}
+	identityJSON, err := json.Marshal(identity)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not marshall the identity %s", identity)
+	}
+	return map[string]interface{}{key: string(identityJSON)}, nil
+}
+
+// recordedIdentity decodes the identity recorded in the configmap under the given key, or returns
+// nil when there is none.
+func recordedIdentity(ctx context.Context, config *corev1.ConfigMap, key string) *Identity {
+	identityJSON, ok := config.Data[key]
+	if !ok {
+		return nil
+	}
+	identity := &Identity{}
+	if err := json.Unmarshal([]byte(identityJSON), identity); err != nil {
+		contextLogger(ctx).V(2).Info("failed to decode the identity of instance", "instanceID", config.Name, "key", key, "error", err)
+		return nil
+	}
+	return identity
+}
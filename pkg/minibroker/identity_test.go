@@ -0,0 +1,50 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecordedIdentity(t *testing.T) {
+	identity := &Identity{Platform: "kubernetes", Username: "jane", Groups: []string{"admins"}}
+
+	data, err := identityData(OriginatingIdentityKey, identity)
+	if err != nil {
+		t.Fatalf("identityData: unexpected error %v", err)
+	}
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance"},
+		Data:       map[string]string{OriginatingIdentityKey: data[OriginatingIdentityKey].(string)},
+	}
+	if actual := instanceSummary(context.TODO(), config).Identity; !reflect.DeepEqual(actual, identity) {
+		t.Errorf("expected the recorded identity %+v, actual %+v", identity, actual)
+	}
+
+	if data, _ := identityData(OriginatingIdentityKey, nil); len(data) != 0 {
+		t.Errorf("expected no data without an identity, actual %v", data)
+	}
+	config.Data[OriginatingIdentityKey] = "jane"
+	if actual := recordedIdentity(context.TODO(), config, OriginatingIdentityKey); actual != nil {
+		t.Errorf("expected no identity for a malformed record, actual %+v", actual)
+	}
+}
@@ -0,0 +1,213 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// InstanceNamespaceKey is the instance configmap key recording the namespace generated for the
+	// instance in the namespace-per-instance mode.
+	InstanceNamespaceKey = "instance-namespace"
+	// PlatformNamespaceLabel labels the generated instance namespaces with the platform namespace
+	// owning the instance.
+	PlatformNamespaceLabel = "minibroker.io/platform-namespace"
+	// ManagedByLabel labels the resources managed by Minibroker.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+
+	// DefaultInstanceNamespacePrefix is the prefix of the generated instance namespace names.
+	DefaultInstanceNamespacePrefix = "minibroker-"
+
+	isolationResourceName = "minibroker"
+	managedByValue        = "minibroker"
+	maxNamespaceNameLen   = 63
+	// namespaceNameLabel is the label the API server sets on every namespace with its name.
+	namespaceNameLabel = "kubernetes.io/metadata.name"
+)
+
+var invalidNamespaceNameChars = regexp.MustCompile(`[^a-z0-9-]`)
+
+// IsolationConfig is the configuration of the namespace-per-instance mode, where each instance is
+// installed into a generated namespace.
+type IsolationConfig struct {
+	// The prefix of the generated namespace names. Defaults to DefaultInstanceNamespacePrefix.
+	NamespacePrefix string `json:"namespacePrefix,omitempty"`
+	// The ResourceQuota created in each generated namespace, if any.
+	ResourceQuota *corev1.ResourceQuotaSpec `json:"resourceQuota,omitempty"`
+	// The LimitRange created in each generated namespace, if any.
+	LimitRange *corev1.LimitRangeSpec `json:"limitRange,omitempty"`
+}
+
+// LoadIsolationConfig parses the namespace-per-instance configuration from raw YAML.
+func LoadIsolationConfig(data []byte) (*IsolationConfig, error) {
+	config := &IsolationConfig{}
+	if err := yaml.UnmarshalStrict(data, config, yaml.DisallowUnknownFields); err != nil {
+		return nil, fmt.Errorf("failed to load the isolation configuration: %w", err)
+	}
+	if config.NamespacePrefix == "" {
+		config.NamespacePrefix = DefaultInstanceNamespacePrefix
+	}
+	return config, nil
+}
+
+// instanceNamespaceName builds a valid namespace name for the instance.
+func (ic *IsolationConfig) instanceNamespaceName(instanceID string) string {
+	name := ic.NamespacePrefix + invalidNamespaceNameChars.ReplaceAllString(strings.ToLower(instanceID), "-")
+	if len(name) > maxNamespaceNameLen {
+		name = name[:maxNamespaceNameLen]
+	}
+	return strings.Trim(name, "-")
+}
+
+// instanceNamespaceObjects builds the namespace generated for an instance owned by the platform
+// namespace, together with the ResourceQuota, LimitRange and NetworkPolicy created in it.
+func (ic *IsolationConfig) instanceNamespaceObjects(
+	instanceID,
+	platformNamespace string,
+) (*corev1.Namespace, *corev1.ResourceQuota, *corev1.LimitRange, *networkingv1.NetworkPolicy) {
+	name := ic.instanceNamespaceName(instanceID)
+	objectLabels := map[string]string{
+		InstanceLabel:  instanceID,
+		ManagedByLabel: managedByValue,
+	}
+	objectMeta := metav1.ObjectMeta{
+		Name:      isolationResourceName,
+		Namespace: name,
+		Labels:    objectLabels,
+	}
+
+	namespaceLabels := map[string]string{PlatformNamespaceLabel: platformNamespace}
+	for k, v := range objectLabels {
+		namespaceLabels[k] = v
+	}
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: namespaceLabels,
+		},
+	}
+
+	var quota *corev1.ResourceQuota
+	if ic.ResourceQuota != nil {
+		quota = &corev1.ResourceQuota{ObjectMeta: objectMeta, Spec: *ic.ResourceQuota}
+	}
+	var limitRange *corev1.LimitRange
+	if ic.LimitRange != nil {
+		limitRange = &corev1.LimitRange{ObjectMeta: objectMeta, Spec: *ic.LimitRange}
+	}
+
+	// The ingress is denied by default, except from the pods of the instance namespace itself and
+	// from the platform namespace owning the instance.
+	policy := &networkingv1.NetworkPolicy{
+		ObjectMeta: objectMeta,
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress},
+			Ingress: []networkingv1.NetworkPolicyIngressRule{{
+				From: []networkingv1.NetworkPolicyPeer{
+					{PodSelector: &metav1.LabelSelector{}},
+					{NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{namespaceNameLabel: platformNamespace},
+					}},
+				},
+			}},
+		},
+	}
+
+	return namespace, quota, limitRange, policy
+}
+
+// createInstanceNamespace creates the namespace generated for the instance in the target cluster,
+// recording it in the instance configmap before any of its resources are created so deprovisioning
+// can clean it up.
+func (c *Client) createInstanceNamespace(ctx context.Context, target *cluster, instanceID, platformNamespace string) (string, error) {
+	namespace, quota, limitRange, policy := c.isolation.instanceNamespaceObjects(instanceID, platformNamespace)
+
+	err := c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+		InstanceNamespaceKey: namespace.Name,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the namespace of instance %q: %w", instanceID, err)
+	}
+
+	_, err = target.coreClient.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		existing, getErr := target.coreClient.CoreV1().Namespaces().Get(ctx, namespace.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return "", fmt.Errorf("failed to create namespace %q: %w", namespace.Name, getErr)
+		}
+		if existing.Labels[InstanceLabel] != instanceID {
+			return "", fmt.Errorf("failed to create namespace %q: the namespace exists and does not belong to instance %q", namespace.Name, instanceID)
+		}
+	} else if err != nil {
+		return "", fmt.Errorf("failed to create namespace %q: %w", namespace.Name, err)
+	}
+
+	if quota != nil {
+		_, err := target.coreClient.CoreV1().ResourceQuotas(namespace.Name).Create(ctx, quota, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create the resource quota of namespace %q: %w", namespace.Name, err)
+		}
+	}
+	if limitRange != nil {
+		_, err := target.coreClient.CoreV1().LimitRanges(namespace.Name).Create(ctx, limitRange, metav1.CreateOptions{})
+		if err != nil && !apierrors.IsAlreadyExists(err) {
+			return "", fmt.Errorf("failed to create the limit range of namespace %q: %w", namespace.Name, err)
+		}
+	}
+	_, err = target.coreClient.NetworkingV1().NetworkPolicies(namespace.Name).Create(ctx, policy, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return "", fmt.Errorf("failed to create the network policy of namespace %q: %w", namespace.Name, err)
+	}
+
+	contextLogger(ctx).V(3).Info("created instance namespace", "instanceID", instanceID, "namespace", namespace.Name)
+	return namespace.Name, nil
+}
+
+// deleteInstanceNamespace deletes the namespace generated for an instance, together with the
+// persistent volume claims left behind by the release.
+func deleteInstanceNamespace(ctx context.Context, coreClient kubernetes.Interface, namespace string) error {
+	claims, err := coreClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete the persistent volume claims of namespace %q: %w", namespace, err)
+	}
+	for _, claim := range claims.Items {
+		err := coreClient.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, claim.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete persistent volume claim %s/%s: %w", namespace, claim.Name, err)
+		}
+	}
+
+	err = coreClient.CoreV1().Namespaces().Delete(ctx, namespace, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete namespace %q: %w", namespace, err)
+	}
+
+	contextLogger(ctx).V(3).Info("deleted instance namespace", "namespace", namespace)
+	return nil
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadIsolationConfig(t *testing.T) {
+	config, err := LoadIsolationConfig([]byte(`
+resourceQuota:
+  hard:
+    requests.storage: 20Gi
+limitRange:
+  limits:
+  - type: Container
+    default:
+      memory: 512Mi
+`))
+	if err != nil {
+		t.Fatalf("LoadIsolationConfig: unexpected error %v", err)
+	}
+	if config.NamespacePrefix != DefaultInstanceNamespacePrefix {
+		t.Errorf("LoadIsolationConfig: expected prefix %q, actual %q", DefaultInstanceNamespacePrefix, config.NamespacePrefix)
+	}
+	storage := config.ResourceQuota.Hard[corev1.ResourceRequestsStorage]
+	if storage.Cmp(resource.MustParse("20Gi")) != 0 {
+		t.Errorf("LoadIsolationConfig: expected a 20Gi storage quota, actual %v", storage.String())
+	}
+	if len(config.LimitRange.Limits) != 1 {
+		t.Errorf("LoadIsolationConfig: expected a limit, actual %v", config.LimitRange.Limits)
+	}
+
+	if _, err := LoadIsolationConfig([]byte("unknown: true")); err == nil {
+		t.Errorf("LoadIsolationConfig: expected an error for an unknown field")
+	}
+}
+
+func TestInstanceNamespaceName(t *testing.T) {
+	config := &IsolationConfig{NamespacePrefix: "mb-"}
+
+	if name := config.instanceNamespaceName("A1B2_c3"); name != "mb-a1b2-c3" {
+		t.Errorf("instanceNamespaceName: expected %q, actual %q", "mb-a1b2-c3", name)
+	}
+	if name := config.instanceNamespaceName(strings.Repeat("x", 100)); len(name) != maxNamespaceNameLen {
+		t.Errorf("instanceNamespaceName: expected %d characters, actual %d", maxNamespaceNameLen, len(name))
+	}
+}
+
+func TestInstanceNamespace(t *testing.T) {
+	ctx := context.TODO()
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "instance", Namespace: "minibroker"},
+		Data:       map[string]string{},
+	}
+	coreClient := fake.NewSimpleClientset(config)
+	c := NewClient(context.TODO(), helm.NewDefaultClient(), coreClient, ClientOptions{
+		Namespace: "minibroker",
+		Isolation: &IsolationConfig{
+			NamespacePrefix: DefaultInstanceNamespacePrefix,
+			ResourceQuota: &corev1.ResourceQuotaSpec{
+				Hard: corev1.ResourceList{corev1.ResourcePods: resource.MustParse("10")},
+			},
+		},
+	})
+	target, _ := c.cluster(context.TODO(), "")
+
+	namespace, err := c.createInstanceNamespace(context.TODO(), target, "instance", "team-a")
+	if err != nil {
+		t.Fatalf("createInstanceNamespace: unexpected error %v", err)
+	}
+	if namespace != "minibroker-instance" {
+		t.Errorf("createInstanceNamespace: expected namespace %q, actual %q", "minibroker-instance", namespace)
+	}
+
+	created, err := coreClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if created.Labels[PlatformNamespaceLabel] != "team-a" || created.Labels[InstanceLabel] != "instance" {
+		t.Errorf("createInstanceNamespace: unexpected labels %v", created.Labels)
+	}
+	if _, err := coreClient.CoreV1().ResourceQuotas(namespace).Get(ctx, isolationResourceName, metav1.GetOptions{}); err != nil {
+		t.Errorf("createInstanceNamespace: expected a resource quota, got %v", err)
+	}
+	if _, err := coreClient.CoreV1().LimitRanges(namespace).Get(ctx, isolationResourceName, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("createInstanceNamespace: expected no limit range, got %v", err)
+	}
+	policy, err := coreClient.NetworkingV1().NetworkPolicies(namespace).Get(ctx, isolationResourceName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("createInstanceNamespace: expected a network policy, got %v", err)
+	}
+	if len(policy.Spec.Ingress) != 1 || len(policy.Spec.Ingress[0].From) != 2 {
+		t.Errorf("createInstanceNamespace: unexpected ingress rules %v", policy.Spec.Ingress)
+	}
+
+	current, _ := c.getConfigMap(context.TODO(), "instance")
+	if current.Data[InstanceNamespaceKey] != namespace {
+		t.Errorf("createInstanceNamespace: expected the namespace to be recorded, actual %v", current.Data)
+	}
+
+	// Retries of a failed provisioning reuse the namespace.
+	if _, err := c.createInstanceNamespace(context.TODO(), target, "instance", "team-a"); err != nil {
+		t.Errorf("createInstanceNamespace: unexpected error on retry %v", err)
+	}
+
+	pvc := &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: namespace}}
+	if _, err := coreClient.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, pvc, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := deleteInstanceNamespace(context.TODO(), coreClient, namespace); err != nil {
+		t.Fatalf("deleteInstanceNamespace: unexpected error %v", err)
+	}
+	if _, err := coreClient.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("deleteInstanceNamespace: expected the namespace to be deleted, got %v", err)
+	}
+	if _, err := coreClient.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, "data", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("deleteInstanceNamespace: expected the claims to be deleted, got %v", err)
+	}
+	if err := deleteInstanceNamespace(context.TODO(), coreClient, namespace); err != nil {
+		t.Errorf("deleteInstanceNamespace: unexpected error for a deleted namespace %v", err)
+	}
+}
@@ -51,16 +51,9 @@ func (p MariadbProvider) Bind(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database name: %w", err)
 	}
-	user, err := provisionParams.DigStringOr("db.user", rootMariadbUsername)
+	user, passwordKey, err := p.bindUser(provisionParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get username: %w", err)
-	}
-
-	var passwordKey string
-	if user == rootMariadbUsername {
-		passwordKey = "mariadb-root-password"
-	} else {
-		passwordKey = "mariadb-password"
+		return nil, err
 	}
 	password, err := chartSecrets.DigString(passwordKey)
 	if err != nil {
@@ -84,3 +77,54 @@ func (p MariadbProvider) Bind(
 
 	return creds, nil
 }
+
+// SecretKeys satisfies Provider.
+func (p MariadbProvider) SecretKeys(provisionParams *ProvisionParams) []string {
+	_, passwordKey, err := p.bindUser(provisionParams)
+	if err != nil {
+		return nil
+	}
+	return []string{passwordKey}
+}
+
+// bindUser returns the user of the bindings and the chart secret key of its password.
+func (p MariadbProvider) bindUser(provisionParams *ProvisionParams) (string, string, error) {
+	user, err := provisionParams.DigStringOr("db.user", rootMariadbUsername)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get username: %w", err)
+	}
+	if user == rootMariadbUsername {
+		return user, "mariadb-root-password", nil
+	}
+	return user, "mariadb-password", nil
+}
+
+// CreateUserJob satisfies UserProvider.
+func (p MariadbProvider) CreateUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	script, err := mysqlCreateUserScript(creds, user)
+	if err != nil {
+		return nil, err
+	}
+	return p.userJob(creds, chartSecrets, script)
+}
+
+// DropUserJob satisfies UserProvider.
+func (p MariadbProvider) DropUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	return p.userJob(creds, chartSecrets, mysqlDropUserScript(user))
+}
+
+func (p MariadbProvider) userJob(creds Object, chartSecrets Object, script string) (*UserJob, error) {
+	adminPassword, err := chartSecrets.DigString("mariadb-root-password")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin password: %w", err)
+	}
+	return mysqlUserJob(creds, rootMariadbUsername, adminPassword, script, []string{"mariadb"})
+}
@@ -0,0 +1,244 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const metricsNamespace = "minibroker"
+
+// The operation types of the operation metrics.
+const (
+	OperationProvision   = "provision"
+	OperationDeprovision = "deprovision"
+	OperationBind        = "bind"
+	OperationUnbind      = "unbind"
+)
+
+// The outcomes of the operation metrics.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+var (
+	credentialsDriftTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "binding_credentials_drift_total",
+			Help:      "The number of times the live credentials of a binding started differing from the stored snapshot.",
+		},
+		[]string{"service"},
+	)
+	helmInstallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "helm_install_duration_seconds",
+			Help:      "The duration of the Helm release installs.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"service", "plan"},
+	)
+	helmUninstallDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "helm_uninstall_duration_seconds",
+			Help:      "The duration of the Helm release uninstalls.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		},
+		[]string{"service", "plan"},
+	)
+	operationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "operations_total",
+			Help:      "The number of completed operations by type, outcome and error class.",
+		},
+		[]string{"operation", "outcome", "error_class"},
+	)
+	operationsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "operations_in_flight",
+			Help:      "The number of asynchronous operations in progress.",
+		},
+		[]string{"operation"},
+	)
+	provisioningProgress = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "provisioning_progress_ratio",
+			Help:      "The fraction of the pods ready and the persistent volume claims bound of the instances being provisioned.",
+		},
+		[]string{"instance", "service", "plan"},
+	)
+)
+
+// Collectors returns the Prometheus collectors of the minibroker client.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		credentialsDriftTotal,
+		helmInstallDuration,
+		helmUninstallDuration,
+		operationsTotal,
+		operationsInFlight,
+		provisioningProgress,
+	}
+}
+
+// observeOperation counts a completed operation with its outcome.
+func observeOperation(operation string, err error) {
+	outcome := OutcomeSucceeded
+	if err != nil {
+		outcome = OutcomeFailed
+	}
+	operationsTotal.WithLabelValues(operation, outcome, ErrorClass(err)).Inc()
+}
+
+// trackAsyncOperation counts an asynchronous operation as in flight until the returned function is
+// called.
+func trackAsyncOperation(operation string) func() {
+	gauge := operationsInFlight.WithLabelValues(operation)
+	gauge.Inc()
+	return gauge.Dec
+}
+
+// ErrorClass classifies an operation error into a small set of classes suitable for metric labels:
+// the failure class of the chart and quota errors, the OSB error code or status of the broker
+// errors, the reason of the Kubernetes API errors, "timeout" or "internal". It is empty for a nil
+// error.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+	if class := failureClass(err); class != "" {
+		return class
+	}
+	var httpErr osb.HTTPStatusCodeError
+	if errors.As(err, &httpErr) {
+		if httpErr.ErrorMessage != nil && *httpErr.ErrorMessage != "" {
+			return snakeCase(*httpErr.ErrorMessage)
+		}
+		return snakeCase(http.StatusText(httpErr.StatusCode))
+	}
+	var statusErr *apierrors.StatusError
+	if errors.As(err, &statusErr) {
+		if reason := statusErr.Status().Reason; reason != "" {
+			return snakeCase(string(reason))
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, wait.ErrWaitTimeout) {
+		return "timeout"
+	}
+	return "internal"
+}
+
+// snakeCase converts "NotFound", "Not Found" and "ConcurrencyError" style names to snake case.
+func snakeCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == ' ' || r == '-':
+			b.WriteByte('_')
+		case r >= 'A' && r <= 'Z':
+			if i > 0 && s[i-1] != ' ' && s[i-1] != '-' {
+				b.WriteByte('_')
+			}
+			b.WriteRune(r - 'A' + 'a')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var (
+	instancesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "instances"),
+		"The number of service instances by service, plan and namespace.",
+		[]string{"service", "plan", "namespace"}, nil,
+	)
+	bindingsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "bindings"),
+		"The number of service bindings by service, plan and namespace.",
+		[]string{"service", "plan", "namespace"}, nil,
+	)
+	chartIndexAgeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "chart_index_age_seconds"),
+		"The time since the chart repository index was last loaded.",
+		nil, nil,
+	)
+)
+
+// inventoryCollector reports the instance and binding counts from the instance configmaps and the
+// chart index age on every scrape.
+type inventoryCollector struct {
+	client *Client
+}
+
+var _ prometheus.Collector = &inventoryCollector{}
+
+// InventoryCollector returns the Prometheus collector of the instance and binding counts and of
+// the chart index age.
+func (c *Client) InventoryCollector() prometheus.Collector {
+	return &inventoryCollector{client: c}
+}
+
+func (ic *inventoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- instancesDesc
+	ch <- bindingsDesc
+	ch <- chartIndexAgeDesc
+}
+
+func (ic *inventoryCollector) Collect(ch chan<- prometheus.Metric) {
+	if loadedAt := ic.client.helm.IndexLoadedAt(); !loadedAt.IsZero() {
+		ch <- prometheus.MustNewConstMetric(
+			chartIndexAgeDesc, prometheus.GaugeValue, time.Since(loadedAt).Seconds())
+	}
+
+	ctx := context.Background()
+	summaries, err := ic.client.ListInstances(ctx)
+	if err != nil {
+		contextLogger(ctx).V(2).Info("failed to collect the inventory metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(instancesDesc, err)
+		return
+	}
+	type key struct{ service, plan, namespace string }
+	instances := make(map[key]int)
+	bindings := make(map[key]int)
+	for _, summary := range summaries {
+		k := key{summary.ServiceID, summary.PlanID, summary.PlatformNamespace}
+		instances[k]++
+		bindings[k] += summary.Bindings
+	}
+	for k, count := range instances {
+		ch <- prometheus.MustNewConstMetric(
+			instancesDesc, prometheus.GaugeValue, float64(count), k.service, k.plan, k.namespace)
+		ch <- prometheus.MustNewConstMetric(
+			bindingsDesc, prometheus.GaugeValue, float64(bindings[k]), k.service, k.plan, k.namespace)
+	}
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	"github.com/pkg/errors"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestErrorClass(t *testing.T) {
+	cases := []struct {
+		err      error
+		expected string
+	}{
+		{err: nil, expected: ""},
+		{err: osb.HTTPStatusCodeError{StatusCode: http.StatusGone}, expected: "gone"},
+		{
+			err: osb.HTTPStatusCodeError{
+				StatusCode:   http.StatusConflict,
+				ErrorMessage: strPtr(ConcurrencyErrorMessage),
+			},
+			expected: "concurrency_error",
+		},
+		{
+			err:      errors.Wrap(apierrors.NewNotFound(corev1.Resource("configmaps"), "db"), "could not get"),
+			expected: "not_found",
+		},
+		{err: fmt.Errorf("failed to run user job: %w", wait.ErrWaitTimeout), expected: "timeout"},
+		{err: context.DeadlineExceeded, expected: "timeout"},
+		{err: fmt.Errorf("failed to install chart: boom"), expected: "internal"},
+	}
+	for _, tc := range cases {
+		if actual := ErrorClass(tc.err); actual != tc.expected {
+			t.Errorf("ErrorClass(%v): expected %q, actual %q", tc.err, tc.expected, actual)
+		}
+	}
+}
+
+func TestObserveOperation(t *testing.T) {
+	succeeded := operationsTotal.WithLabelValues(OperationUnbind, OutcomeSucceeded, "")
+	gone := operationsTotal.WithLabelValues(OperationUnbind, OutcomeFailed, "gone")
+	before := testutil.ToFloat64(succeeded)
+	beforeGone := testutil.ToFloat64(gone)
+
+	c := &Client{namespace: "minibroker", coreClient: fake.NewSimpleClientset()}
+	if err := c.Unbind(context.Background(), "missing", "binding"); err == nil {
+		t.Fatalf("Unbind: expected an error for a missing instance")
+	}
+	observeOperation(OperationUnbind, nil)
+
+	if actual := testutil.ToFloat64(succeeded) - before; actual != 1 {
+		t.Errorf("expected 1 succeeded unbind, actual %v", actual)
+	}
+	if actual := testutil.ToFloat64(gone) - beforeGone; actual != 1 {
+		t.Errorf("expected 1 failed unbind, actual %v", actual)
+	}
+
+	inFlight := operationsInFlight.WithLabelValues(OperationBind)
+	done := trackAsyncOperation(OperationBind)
+	if actual := testutil.ToFloat64(inFlight); actual != 1 {
+		t.Errorf("expected 1 bind in flight, actual %v", actual)
+	}
+	done()
+	if actual := testutil.ToFloat64(inFlight); actual != 0 {
+		t.Errorf("expected no bind in flight, actual %v", actual)
+	}
+}
+
+func TestInventoryCollector(t *testing.T) {
+	newConfig := func(name, namespace string, bindings ...string) *corev1.ConfigMap {
+		config := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "minibroker",
+				Labels: map[string]string{
+					ServiceKey:             "redis",
+					PlanKey:                "redis-5-0-7",
+					PlatformNamespaceLabel: namespace,
+				},
+			},
+			Data: map[string]string{ServiceKey: "redis", PlanKey: "redis-5-0-7"},
+		}
+		for _, binding := range bindings {
+			config.Data[BindingStateKeyPrefix+binding] = `{"state":"succeeded"}`
+		}
+		return config
+	}
+	coreClient := fake.NewSimpleClientset(
+		newConfig("first", "team-a", "one", "two"),
+		newConfig("second", "team-a", "three"),
+		newConfig("third", "team-b"),
+	)
+	c := &Client{namespace: "minibroker", coreClient: coreClient, helm: helm.NewDefaultClient()}
+
+	expected := `
+# HELP minibroker_bindings The number of service bindings by service, plan and namespace.
+# TYPE minibroker_bindings gauge
+minibroker_bindings{namespace="team-a",plan="redis-5-0-7",service="redis"} 3
+minibroker_bindings{namespace="team-b",plan="redis-5-0-7",service="redis"} 0
+# HELP minibroker_instances The number of service instances by service, plan and namespace.
+# TYPE minibroker_instances gauge
+minibroker_instances{namespace="team-a",plan="redis-5-0-7",service="redis"} 2
+minibroker_instances{namespace="team-b",plan="redis-5-0-7",service="redis"} 1
+`
+	err := testutil.CollectAndCompare(c.InventoryCollector(), strings.NewReader(expected))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -24,11 +24,17 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Masterminds/semver"
+	"github.com/go-logr/logr"
 	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/kubernetes-sigs/minibroker/pkg/tracing"
 	"github.com/pkg/errors"
 	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	"go.opentelemetry.io/otel/attribute"
 	"helm.sh/helm/v3/pkg/repo"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -37,8 +43,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	klog "k8s.io/klog/v2"
+	"k8s.io/client-go/tools/record"
 )
 
 const (
@@ -47,8 +52,11 @@ const (
 	PlanKey             = "plan-id"
 	ProvisionParamsKey  = "provision-params"
 	ReleaseNamespaceKey = "release-namespace"
-	HeritageLabel       = "heritage"
-	ReleaseLabel        = "release"
+	// PlatformContextKey is the instance configmap key holding the OSB context of the provisioning
+	// request.
+	PlatformContextKey = "platform-context"
+	HeritageLabel      = "heritage"
+	ReleaseLabel       = "release"
 )
 
 // ConfigMap keys for tracking the last operation
@@ -83,43 +91,93 @@ type Client struct {
 	coreClient                kubernetes.Interface
 	providers                 map[string]Provider
 	serviceCatalogEnabledOnly bool
+	// perBindingUsers enables the creation of a dedicated database user for each binding.
+	perBindingUsers bool
+	userJobTimeout  time.Duration
+	// rotationGracePeriod is how long a rotated binding user remains valid.
+	rotationGracePeriod time.Duration
+	// liveCredentials enables resolving the binding credentials on every fetch instead of returning
+	// the snapshot stored at bind time.
+	liveCredentials bool
+	// projectBindingSecrets enables writing the binding credentials as Secrets following the Service
+	// Binding for Kubernetes specification.
+	projectBindingSecrets bool
+	// secretKeysPassthrough includes all the chart secret keys in the binding credentials instead of
+	// only the keys allowed by the providers.
+	secretKeysPassthrough bool
+	// genericSecretKeys are the chart secret keys allowed in the binding credentials of the charts
+	// without a provider.
+	genericSecretKeys []string
+	// clusters are the target clusters releases can be installed into, by name.
+	clusters map[string]ClusterConfig
+	// clusterNames are the names of the target clusters in the configuration order.
+	clusterNames  []string
+	clusterCache  map[string]*cluster
+	clustersMutex sync.Mutex
+	// isolation enables installing each instance into a generated namespace when set.
+	isolation *IsolationConfig
+	// recorder records the Kubernetes Events of the instance and binding lifecycle when set.
+	recorder record.EventRecorder
+	// progressInterval is how often the progress of the asynchronous provisions is reported.
+	progressInterval time.Duration
 }
 
-func NewClient(
-	namespace string,
-	serviceCatalogEnabledOnly bool,
-	clusterDomain string,
-) *Client {
-	klog.V(5).Infof("minibroker: initializing a new client")
-	hb := hostBuilder{clusterDomain}
-	return &Client{
-		helm:                      helm.NewDefaultClient(),
-		coreClient:                loadInClusterClient(),
-		namespace:                 namespace,
-		serviceCatalogEnabledOnly: serviceCatalogEnabledOnly,
-		providers: map[string]Provider{
-			"mysql":      MySQLProvider{hb},
-			"mariadb":    MariadbProvider{hb},
-			"postgresql": PostgresProvider{hb},
-			"mongodb":    MongodbProvider{hb},
-			"redis":      RedisProvider{hb},
-			"rabbitmq":   RabbitmqProvider{hb},
-		},
-	}
+// ClientOptions are the options of the Client.
+type ClientOptions struct {
+	// The namespace where the instance configmaps are stored.
+	Namespace                 string
+	ServiceCatalogEnabledOnly bool
+	// The k8s cluster domain used for building the service hosts.
+	ClusterDomain string
+	// Whether a dedicated database user is created for each binding.
+	PerBindingUsers bool
+	// How long a rotated binding user remains valid.
+	RotationGracePeriod time.Duration
+	// Whether the binding credentials are resolved on every fetch.
+	LiveCredentials bool
+	// Whether all the chart secret keys are included in the binding credentials.
+	SecretKeysPassthrough bool
+	// The chart secret keys allowed in the binding credentials of the charts without a provider.
+	GenericSecretKeys []string
+	// Whether the binding credentials are projected into Secrets.
+	ProjectBindingSecrets bool
+	// The target clusters releases can be installed into besides the cluster Minibroker runs in.
+	Clusters *ClustersConfig
+	// The namespace-per-instance configuration. Instances share the requested namespaces when nil.
+	Isolation *IsolationConfig
+	// The recorder of the lifecycle Events. No Events are recorded when nil.
+	EventRecorder record.EventRecorder
 }
 
-func loadInClusterClient() kubernetes.Interface {
-	config, err := rest.InClusterConfig()
-	if err != nil {
-		panic(err)
+// NewClient creates a new Client operating on the given Helm client and k8s clientset.
+func NewClient(ctx context.Context, helmClient *helm.Client, coreClient kubernetes.Interface, o ClientOptions) *Client {
+	contextLogger(ctx).V(5).Info("initializing a new client")
+	c := &Client{
+		helm:                      helmClient,
+		coreClient:                coreClient,
+		namespace:                 o.Namespace,
+		serviceCatalogEnabledOnly: o.ServiceCatalogEnabledOnly,
+		perBindingUsers:           o.PerBindingUsers,
+		userJobTimeout:            defaultUserJobTimeout,
+		rotationGracePeriod:       o.RotationGracePeriod,
+		liveCredentials:           o.LiveCredentials,
+		secretKeysPassthrough:     o.SecretKeysPassthrough,
+		genericSecretKeys:         o.GenericSecretKeys,
+		projectBindingSecrets:     o.ProjectBindingSecrets,
+		providers:                 newProviders(o.ClusterDomain),
+		clusters:                  make(map[string]ClusterConfig),
+		clusterCache:              make(map[string]*cluster),
+		isolation:                 o.Isolation,
+		recorder:                  o.EventRecorder,
+		progressInterval:          defaultProgressInterval,
 	}
-
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		panic(err)
+	if o.Clusters != nil {
+		for _, cluster := range o.Clusters.Clusters {
+			c.clusters[cluster.Name] = cluster
+			c.clusterNames = append(c.clusterNames, cluster.Name)
+		}
 	}
-
-	return clientset
+	return c
 }
 
 func (c *Client) Init(repoURL string) error {
@@ -178,9 +236,14 @@ func generateOperationName(prefix string) string {
 	return fmt.Sprintf("%s%x", prefix, rand.Int31())
 }
 
-func (c *Client) getConfigMap(instanceID string) (*corev1.ConfigMap, error) {
+// contextLogger returns the structured logger of the context, carrying its correlation ID.
+func contextLogger(ctx context.Context) logr.Logger {
+	return log.FromContext(ctx).WithName("minibroker")
+}
+
+func (c *Client) getConfigMap(ctx context.Context, instanceID string) (*corev1.ConfigMap, error) {
 	configMapInterface := c.coreClient.CoreV1().ConfigMaps(c.namespace)
-	config, err := configMapInterface.Get(context.TODO(), instanceID, metav1.GetOptions{})
+	config, err := configMapInterface.Get(ctx, instanceID, metav1.GetOptions{})
 	if err != nil {
 		// Do not wrap the error to keep apierrors.IsNotFound() working correctly
 		return nil, err
@@ -192,11 +255,14 @@ func (c *Client) getConfigMap(instanceID string) (*corev1.ConfigMap, error) {
 // expected that the config map already exists.
 // Each value in data may be either a string (in which case it is set), or nil
 // (in which case it is removed); any other value will panic.
-func (c *Client) updateConfigMap(instanceID string, data map[string]interface{}) error {
-	config, err := c.getConfigMap(instanceID)
+func (c *Client) updateConfigMap(ctx context.Context, instanceID string, data map[string]interface{}) error {
+	config, err := c.getConfigMap(ctx, instanceID)
 	if err != nil {
 		return err
 	}
+	if err := recordOperations(ctx, config, data); err != nil {
+		return err
+	}
 	for name, value := range data {
 		if value == nil {
 			delete(config.Data, name)
@@ -208,15 +274,16 @@ func (c *Client) updateConfigMap(instanceID string, data map[string]interface{})
 	}
 
 	configMapInterface := c.coreClient.CoreV1().ConfigMaps(c.namespace)
-	_, err = configMapInterface.Update(context.TODO(), config, metav1.UpdateOptions{})
+	_, err = configMapInterface.Update(ctx, config, metav1.UpdateOptions{})
 	if err != nil {
 		return errors.Wrapf(err, "Failed to update config for instance %q", instanceID)
 	}
 	return nil
 }
 
-func (c *Client) ListServices() ([]osb.Service, error) {
-	klog.V(4).Infof("minibroker: listing services")
+func (c *Client) ListServices(ctx context.Context) ([]osb.Service, error) {
+	logger := contextLogger(ctx)
+	logger.V(4).Info("listing services")
 
 	var services []osb.Service
 
@@ -233,8 +300,10 @@ func (c *Client) ListServices() ([]osb.Service, error) {
 			Name:        chart,
 			Description: "Helm Chart for " + chart,
 			Bindable:    true,
-			Plans:       make([]osb.Plan, 0, len(chartVersions)),
-			Tags:        tags,
+			// GetBinding serves the binding fetch.
+			BindingsRetrievable: true,
+			Plans:               make([]osb.Plan, 0, len(chartVersions)),
+			Tags:                tags,
 		}
 		appVersions := map[string]*repo.ChartVersion{}
 		for _, chartVersion := range chartVersions {
@@ -244,7 +313,7 @@ func (c *Client) ListServices() ([]osb.Service, error) {
 
 			curV, err := semver.NewVersion(chartVersion.Version)
 			if err != nil {
-				klog.V(4).Infof("minibroker: skipping %s@%s because %q is not a valid semver", chart, chartVersion.AppVersion, chartVersion.Version)
+				logger.V(4).Info("skipping the chart version because it is not a valid semver", "chart", chart, "appVersion", chartVersion.AppVersion, "version", chartVersion.Version)
 				continue
 			}
 
@@ -256,7 +325,7 @@ func (c *Client) ListServices() ([]osb.Service, error) {
 				if curV.GreaterThan(maxV) {
 					appVersions[chartVersion.AppVersion] = chartVersion
 				} else {
-					klog.V(4).Infof("minibroker: skipping %s@%s because %s < %s", chart, chartVersion.AppVersion, curV, maxV)
+					logger.V(4).Info("skipping the chart version because a greater one exists", "chart", chart, "appVersion", chartVersion.AppVersion, "version", curV.String(), "maxVersion", maxV.String())
 					continue
 				}
 			}
@@ -282,23 +351,66 @@ func (c *Client) ListServices() ([]osb.Service, error) {
 		services = append(services, svc)
 	}
 
-	klog.V(4).Infof("minibroker: listed services")
+	logger.V(4).Info("listed services", "count", len(services))
 
 	return services, nil
 }
 
+// ProvisionRequest is a request for provisioning a service instance.
+type ProvisionRequest struct {
+	InstanceID string
+	ServiceID  string
+	PlanID     string
+	// The namespace the release is installed into.
+	Namespace string
+	// The target cluster by name; when empty, the cluster configured for the plan, if any, or the
+	// cluster Minibroker runs in is used.
+	Cluster string
+	// The OSB context of the request, recorded on the instance for auditing.
+	Context map[string]interface{}
+	// The platform user making the request, recorded on the instance for auditing.
+	Identity          *Identity
+	AcceptsIncomplete bool
+	Params            *ProvisionParams
+}
+
 // Provision a new service instance.  Returns the async operation key (if
 // acceptsIncomplete is set).
-func (c *Client) Provision(instanceID, serviceID, planID, namespace string, acceptsIncomplete bool, provisionParams *ProvisionParams) (string, error) {
-	klog.V(3).Infof("minibroker: provisioning intance %q, service %q, namespace %q, params %v", instanceID, serviceID, namespace, provisionParams)
-	ctx := context.TODO()
+func (c *Client) Provision(ctx context.Context, request *ProvisionRequest) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "minibroker.Client.Provision",
+		attribute.String("instance.id", request.InstanceID),
+		attribute.String("service.id", request.ServiceID),
+		attribute.String("plan.id", request.PlanID),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	instanceID := request.InstanceID
+	serviceID := request.ServiceID
+	planID := request.PlanID
+	namespace := request.Namespace
+	cluster := request.Cluster
+	platformContext := request.Context
+	acceptsIncomplete := request.AcceptsIncomplete
+	provisionParams := request.Params
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("provisioning instance", "serviceID", serviceID, "planID", planID, "namespace", namespace, "cluster", cluster, "params", provisionParams)
+
+	clusterName, err := c.selectCluster(planID, cluster)
+	if err != nil {
+		return "", err
+	}
+	target, err := c.cluster(ctx, clusterName)
+	if err != nil {
+		return "", err
+	}
 
 	chartName := serviceID
 	// The way I'm turning charts into plans is not reversible
 	chartVersion := strings.Replace(planID, serviceID+"-", "", 1)
 	chartVersion = strings.Replace(chartVersion, "-", ".", -1)
 
-	klog.V(4).Infof("minibroker: persisting the provisioning parameters")
+	logger.V(4).Info("persisting the provisioning parameters")
 	paramsJSON, err := json.Marshal(provisionParams)
 	if err != nil {
 		return "", errors.Wrapf(err, "could not marshall provisioning parameters %v", provisionParams)
@@ -308,8 +420,9 @@ func (c *Client) Provision(instanceID, serviceID, planID, namespace string, acce
 			Name:      instanceID,
 			Namespace: c.namespace,
 			Labels: map[string]string{
-				ServiceKey: serviceID,
-				PlanKey:    planID,
+				ServiceKey:             serviceID,
+				PlanKey:                planID,
+				PlatformNamespaceLabel: namespace,
 			},
 		},
 		Data: map[string]string{
@@ -318,8 +431,25 @@ func (c *Client) Provision(instanceID, serviceID, planID, namespace string, acce
 			PlanKey:            planID,
 		},
 	}
+	if clusterName != "" {
+		config.Data[ClusterKey] = clusterName
+	}
+	if len(platformContext) > 0 {
+		contextJSON, err := json.Marshal(platformContext)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not marshall the context %v", platformContext)
+		}
+		config.Data[PlatformContextKey] = string(contextJSON)
+	}
+	if request.Identity != nil {
+		identityJSON, err := json.Marshal(request.Identity)
+		if err != nil {
+			return "", errors.Wrapf(err, "could not marshall the identity %s", request.Identity)
+		}
+		config.Data[OriginatingIdentityKey] = string(identityJSON)
+	}
 
-	_, err = c.coreClient.CoreV1().
+	created, err := c.coreClient.CoreV1().
 		ConfigMaps(config.Namespace).
 		Create(ctx, &config, metav1.CreateOptions{})
 	if err != nil {
@@ -333,10 +463,12 @@ func (c *Client) Provision(instanceID, serviceID, planID, namespace string, acce
 		}
 		return "", errors.Wrapf(err, "could not persist the instance configmap for %q", instanceID)
 	}
+	c.recordEvent(created, namespace, corev1.EventTypeNormal, ReasonProvisioning,
+		"Provisioning instance %q of service %q, plan %q", instanceID, serviceID, planID)
 
 	if acceptsIncomplete {
 		operationKey := generateOperationName(OperationPrefixProvision)
-		err = c.updateConfigMap(instanceID, map[string]interface{}{
+		err = c.updateConfigMap(ctx, instanceID, map[string]interface{}{
 			OperationStateKey:       string(osb.StateInProgress),
 			OperationNameKey:        operationKey,
 			OperationDescriptionKey: fmt.Sprintf("provisioning service instance %q", instanceID),
@@ -344,28 +476,41 @@ func (c *Client) Provision(instanceID, serviceID, planID, namespace string, acce
 		if err != nil {
 			return "", errors.Wrapf(err, "Failed to set operation key when provisioning instance %q", instanceID)
 		}
+		done := trackAsyncOperation(OperationProvision)
+		asyncCtx, asyncSpan := tracing.StartAsync(ctx, "minibroker.provision.async",
+			attribute.String("instance.id", instanceID),
+			attribute.String("operation.key", operationKey),
+		)
+		asyncCtx = log.Inherit(asyncCtx, ctx)
 		go func() {
-			err = c.provisionSynchronously(instanceID, namespace, serviceID, planID, chartName, chartVersion, provisionParams)
+			defer done()
+			// The description reports the progress of the release while the install waits for it.
+			progress := c.newProgressWatcher(asyncCtx, target, instanceID, serviceID, planID)
+			err := c.provisionSynchronously(helm.WithReleaseNotifier(asyncCtx, progress.watch), target, created, namespace, serviceID, planID, chartName, chartVersion, provisionParams)
+			progress.stop()
+			tracing.End(asyncSpan, err)
 			if err == nil {
-				err = c.updateConfigMap(instanceID, map[string]interface{}{
+				err = c.updateConfigMap(asyncCtx, instanceID, map[string]interface{}{
 					OperationStateKey:       string(osb.StateSucceeded),
 					OperationDescriptionKey: fmt.Sprintf("service instance %q provisioned", instanceID),
 				})
 			} else {
-				klog.V(2).Infof("minibroker: failed to provision %q: %v", instanceID, err)
-				err = c.updateConfigMap(instanceID, map[string]interface{}{
+				logger.V(2).Info("failed to provision", "operation", operationKey, "class", ErrorClass(err), "error", err)
+				description := failureDescription(asyncCtx, target,
+					fmt.Sprintf("service instance %q failed to provision", instanceID), err)
+				err = c.updateConfigMap(asyncCtx, instanceID, map[string]interface{}{
 					OperationStateKey:       string(osb.StateFailed),
-					OperationDescriptionKey: fmt.Sprintf("service instance %q failed to provision", instanceID),
+					OperationDescriptionKey: description,
 				})
 				if err != nil {
-					klog.V(2).Infof("minibroker: failed to provision %q: could not update operation state when provisioning asynchronously: %v", instanceID, err)
+					logger.V(2).Info("could not update the operation state when provisioning asynchronously", "operation", operationKey, "error", err)
 				}
 			}
 		}()
 		return operationKey, nil
 	}
 
-	err = c.provisionSynchronously(instanceID, namespace, serviceID, planID, chartName, chartVersion, provisionParams)
+	err = c.provisionSynchronously(ctx, target, created, namespace, serviceID, planID, chartName, chartVersion, provisionParams)
 	if err != nil {
 		return "", err
 	}
@@ -374,63 +519,100 @@ func (c *Client) Provision(instanceID, serviceID, planID, namespace string, acce
 }
 
 // provisionSynchronously will provision the service instance synchronously.
-func (c *Client) provisionSynchronously(instanceID, namespace, serviceID, planID, chartName, chartVersion string, provisionParams *ProvisionParams) error {
-	klog.V(3).Infof("minibroker: provisioning %s/%s using helm chart %s@%s", serviceID, planID, chartName, chartVersion)
+func (c *Client) provisionSynchronously(ctx context.Context, target *cluster, config *corev1.ConfigMap, namespace, serviceID, planID, chartName, chartVersion string, provisionParams *ProvisionParams) (err error) {
+	ctx, span := tracing.Start(ctx, "minibroker.provision",
+		attribute.String("chart.name", chartName),
+		attribute.String("chart.version", chartVersion),
+	)
+	instanceID := config.Name
+	defer func() {
+		observeOperation(OperationProvision, err)
+		tracing.End(span, err)
+		if err != nil {
+			c.recordEvent(config, namespace, corev1.EventTypeWarning, ReasonProvisionFailed,
+				"Failed to provision instance %q: %v", instanceID, err)
+		}
+	}()
+	logger := contextLogger(ctx).WithValues("chart", chartName, "chartVersion", chartVersion)
+	logger.V(3).Info("provisioning using helm chart", "serviceID", serviceID, "planID", planID)
 
 	chartDef, err := c.helm.GetChart(chartName, chartVersion)
 	if err != nil {
 		return err
 	}
 
-	release, err := c.helm.ChartClient().Install(chartDef, namespace, provisionParams.Object)
+	if c.isolation != nil {
+		// The requested namespace becomes the owner of the generated one.
+		if namespace, err = c.createInstanceNamespace(ctx, target, instanceID, namespace); err != nil {
+			return err
+		}
+	}
+
+	installStart := time.Now()
+	release, err := target.chartClient.Install(ctx, chartDef, namespace, provisionParams.Object)
+	helmInstallDuration.WithLabelValues(serviceID, planID).Observe(time.Since(installStart).Seconds())
 	if err != nil {
 		return err
 	}
 
 	// Store any required metadata necessary for bind and deprovision as labels on the resources itself
-	klog.V(3).Infof("minibroker: labeling chart resources with instance %q", instanceID)
+	if err := labelReleaseResources(ctx, target.coreClient, instanceID, release.Name, namespace); err != nil {
+		return err
+	}
+
+	err = c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+		ReleaseLabel:        release.Name,
+		ReleaseNamespaceKey: release.Namespace,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "could not update the instance configmap for %q", instanceID)
+	}
+
+	logger.V(4).Info("provisioned", "release", release.Name, "releaseVersion", release.Version)
+	c.recordEvent(config, namespace, corev1.EventTypeNormal, ReasonProvisioned,
+		"Provisioned instance %q as release %q", instanceID, release.Name)
+
+	return nil
+}
+
+// labelReleaseResources labels the services and secrets of the release with the instance ID.
+func labelReleaseResources(ctx context.Context, coreClient kubernetes.Interface, instanceID, releaseName, namespace string) (err error) {
+	ctx, span := tracing.Start(ctx, "minibroker.labelReleaseResources",
+		attribute.String("release.name", releaseName),
+		attribute.String("release.namespace", namespace),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	contextLogger(ctx).V(3).Info("labeling chart resources", "release", releaseName, "namespace", namespace)
 	filterByRelease := metav1.ListOptions{
 		LabelSelector: labels.SelectorFromSet(map[string]string{
-			ReleaseLabel: release.Name,
+			ReleaseLabel: releaseName,
 		}).String(),
 	}
-	services, err := c.coreClient.CoreV1().Services(namespace).List(context.TODO(), filterByRelease)
+	services, err := coreClient.CoreV1().Services(namespace).List(ctx, filterByRelease)
 	if err != nil {
 		return err
 	}
 	for _, service := range services.Items {
-		err := c.labelService(service, instanceID)
+		err := labelService(ctx, coreClient, service, instanceID)
 		if err != nil {
 			return err
 		}
 	}
-	secrets, err := c.coreClient.CoreV1().Secrets(namespace).List(context.TODO(), filterByRelease)
+	secrets, err := coreClient.CoreV1().Secrets(namespace).List(ctx, filterByRelease)
 	if err != nil {
 		return err
 	}
 	for _, secret := range secrets.Items {
-		err := c.labelSecret(secret, instanceID)
+		err := labelSecret(ctx, coreClient, secret, instanceID)
 		if err != nil {
 			return err
 		}
 	}
-
-	err = c.updateConfigMap(instanceID, map[string]interface{}{
-		ReleaseLabel:        release.Name,
-		ReleaseNamespaceKey: release.Namespace,
-	})
-	if err != nil {
-		return errors.Wrapf(err, "could not update the instance configmap for %q", instanceID)
-	}
-
-	klog.V(4).Infof("minibroker: provisioned %v@%v (%v@%v)",
-		chartName, chartVersion, release.Name, release.Version)
-
 	return nil
 }
 
-func (c *Client) labelService(service corev1.Service, instanceID string) error {
-	ctx := context.TODO()
+func labelService(ctx context.Context, coreClient kubernetes.Interface, service corev1.Service, instanceID string) error {
 
 	labeledService := service.DeepCopy()
 	labeledService.Labels[InstanceLabel] = instanceID
@@ -448,7 +630,7 @@ func (c *Client) labelService(service corev1.Service, instanceID string) error {
 		return err
 	}
 
-	_, err = c.coreClient.CoreV1().
+	_, err = coreClient.CoreV1().
 		Services(service.Namespace).
 		Patch(ctx, service.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
 	if err != nil {
@@ -458,8 +640,7 @@ func (c *Client) labelService(service corev1.Service, instanceID string) error {
 	return nil
 }
 
-func (c *Client) labelSecret(secret corev1.Secret, instanceID string) error {
-	ctx := context.TODO()
+func labelSecret(ctx context.Context, coreClient kubernetes.Interface, secret corev1.Secret, instanceID string) error {
 
 	labeledSecret := secret.DeepCopy()
 	labeledSecret.Labels[InstanceLabel] = instanceID
@@ -477,7 +658,7 @@ func (c *Client) labelSecret(secret corev1.Secret, instanceID string) error {
 		return err
 	}
 
-	_, err = c.coreClient.CoreV1().
+	_, err = coreClient.CoreV1().
 		Secrets(secret.Namespace).
 		Patch(ctx, secret.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
 	if err != nil {
@@ -487,11 +668,39 @@ func (c *Client) labelSecret(secret corev1.Secret, instanceID string) error {
 	return nil
 }
 
+// BindRequest is a request for binding a service instance.
+type BindRequest struct {
+	InstanceID string
+	ServiceID  string
+	BindingID  string
+	// The namespace where the binding Secret is projected when enabled; the release namespace is
+	// used when it is empty.
+	Namespace string
+	// The platform user making the request, recorded on the binding for auditing.
+	Identity          *Identity
+	AcceptsIncomplete bool
+	Params            *BindParams
+}
+
 // Bind the given service instance (of the given service) asynchronously; the
 // binding operation key is returned.
-func (c *Client) Bind(instanceID, serviceID, bindingID string, acceptsIncomplete bool, bindParams *BindParams) (string, error) {
-	klog.V(3).Infof("minibroker: binding instance %q, service %q, binding %q, binding params %v", instanceID, serviceID, bindingID, bindParams)
-	config, err := c.getConfigMap(instanceID)
+func (c *Client) Bind(ctx context.Context, request *BindRequest) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "minibroker.Client.Bind",
+		attribute.String("instance.id", request.InstanceID),
+		attribute.String("binding.id", request.BindingID),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	instanceID := request.InstanceID
+	serviceID := request.ServiceID
+	bindingID := request.BindingID
+	namespace := request.Namespace
+	acceptsIncomplete := request.AcceptsIncomplete
+	bindParams := request.Params
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID, "bindingID", bindingID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("binding instance", "serviceID", serviceID, "namespace", namespace, "params", bindParams)
+	config, err := c.getConfigMap(ctx, instanceID)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			msg := fmt.Sprintf("could not find configmap %s/%s", c.namespace, instanceID)
@@ -502,9 +711,24 @@ func (c *Client) Bind(instanceID, serviceID, bindingID string, acceptsIncomplete
 		}
 		return "", err
 	}
+	target, err := c.instanceCluster(ctx, config)
+	if err != nil {
+		return "", err
+	}
+	releaseName := config.Data[ReleaseLabel]
 	releaseNamespace := config.Data[ReleaseNamespaceKey]
 	rawProvisionParams := config.Data[ProvisionParamsKey]
 	operationName := generateOperationName(OperationPrefixBind)
+	if namespace == "" {
+		namespace = releaseNamespace
+	}
+
+	if err := c.validateBindingRole(serviceID, bindParams); err != nil {
+		return "", osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusBadRequest,
+			Description: strPtr(err.Error()),
+		}
+	}
 
 	var provisionParams *ProvisionParams
 	err = json.Unmarshal([]byte(rawProvisionParams), &provisionParams)
@@ -512,35 +736,68 @@ func (c *Client) Bind(instanceID, serviceID, bindingID string, acceptsIncomplete
 		return "", errors.Wrapf(err, "could not unmarshall provision parameters for instance %q", instanceID)
 	}
 
+	identity, err := identityData(BindingIdentityKeyPrefix+bindingID, request.Identity)
+	if err != nil {
+		return "", err
+	}
+	if len(identity) > 0 {
+		if err := c.updateConfigMap(ctx, instanceID, identity); err != nil {
+			return "", err
+		}
+	}
+
+	c.recordEvent(config, releaseNamespace, corev1.EventTypeNormal, ReasonBinding,
+		"Binding %q to instance %q", bindingID, instanceID)
+
 	if acceptsIncomplete {
-		klog.V(3).Infof("minibroker: initializing asynchronous binding %q", bindingID)
+		logger.V(3).Info("initializing asynchronous binding")
+		done := trackAsyncOperation(OperationBind)
+		asyncCtx, asyncSpan := tracing.StartAsync(ctx, "minibroker.bind.async",
+			attribute.String("instance.id", instanceID),
+			attribute.String("binding.id", bindingID),
+		)
+		asyncCtx = log.Inherit(asyncCtx, ctx)
 		go func() {
-			_ = c.bindSynchronously(
-				instanceID,
+			defer done()
+			err := c.bindSynchronously(
+				asyncCtx,
+				target,
+				config,
 				serviceID,
 				bindingID,
+				releaseName,
 				releaseNamespace,
+				namespace,
 				bindParams,
 				provisionParams,
 			)
-			klog.V(3).Infof("minibroker: asynchronously bound instance %q, service %q, binding %q", instanceID, serviceID, bindingID)
+			tracing.End(asyncSpan, err)
+			if err != nil {
+				logger.V(2).Info("failed to bind asynchronously", "operation", operationName, "class", ErrorClass(err), "error", err)
+				return
+			}
+			logger.V(3).Info("asynchronously bound instance", "serviceID", serviceID)
 		}()
 		return operationName, nil
 	}
 
-	klog.V(3).Infof("minibroker: initializing synchronous binding %q", bindingID)
+	logger.V(3).Info("initializing synchronous binding")
 	if err := c.bindSynchronously(
-		instanceID,
+		ctx,
+		target,
+		config,
 		serviceID,
 		bindingID,
+		releaseName,
 		releaseNamespace,
+		namespace,
 		bindParams,
 		provisionParams,
 	); err != nil {
 		return "", err
 	}
 
-	klog.V(3).Infof("minibroker: synchronously bound instance %q, service %q, binding %q", instanceID, serviceID, bindingID)
+	logger.V(3).Info("synchronously bound instance", "serviceID", serviceID)
 
 	return "", nil
 }
@@ -549,67 +806,77 @@ func (c *Client) Bind(instanceID, serviceID, bindingID string, acceptsIncomplete
 // results are only reported via the service instance configmap (under the
 // appropriate key for the binding) for lookup by LastBindingOperationState().
 func (c *Client) bindSynchronously(
-	instanceID,
+	ctx context.Context,
+	target *cluster,
+	config *corev1.ConfigMap,
 	serviceID,
 	bindingID,
-	releaseNamespace string,
+	releaseName,
+	releaseNamespace,
+	namespace string,
 	bindParams *BindParams,
 	provisionParams *ProvisionParams,
 ) error {
-	ctx := context.TODO()
-
+	ctx, span := tracing.Start(ctx, "minibroker.bind")
+	logger := contextLogger(ctx)
+	instanceID := config.Name
 	// Wrap most of the code in an inner function to simplify error handling
 	err := func() error {
-		filterByInstance := metav1.ListOptions{
-			LabelSelector: labels.SelectorFromSet(map[string]string{
-				InstanceLabel: instanceID,
-			}).String(),
-		}
-
-		services, err := c.coreClient.CoreV1().
-			Services(releaseNamespace).
-			List(ctx, filterByInstance)
+		chartSecrets, creds, err := c.resolveCredentials(
+			ctx,
+			target,
+			instanceID,
+			serviceID,
+			releaseNamespace,
+			bindParams,
+			provisionParams,
+		)
 		if err != nil {
 			return err
 		}
-		if len(services.Items) == 0 {
-			return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
-		}
 
-		secrets, err := c.coreClient.CoreV1().
-			Secrets(releaseNamespace).
-			List(ctx, filterByInstance)
-		if err != nil {
-			return err
-		}
-		if len(secrets.Items) == 0 {
-			return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
-		}
-
-		data := make(Object)
-		for _, secret := range secrets.Items {
-			for key, value := range secret.Data {
-				data[key] = string(value)
+		userProvider, hasBindingUser := c.userProvider(serviceID)
+		if hasBindingUser {
+			role, found, err := bindingRole(bindParams)
+			if err != nil {
+				return err
+			}
+			if !found {
+				role = BindingRoleReadWrite
+			}
+			user, err := newBindingUser(bindingID, role, 0)
+			if err != nil {
+				return err
+			}
+			job, err := userProvider.CreateUserJob(creds, chartSecrets, user)
+			if err != nil {
+				return errors.Wrapf(err, "unable to create the binding user for instance %s", instanceID)
+			}
+			logger.V(3).Info("creating the binding user", "user", user.Username)
+			if err := c.runUserJob(ctx, target, releaseNamespace, releaseName, userJobName("create", user), job); err != nil {
+				return errors.Wrapf(err, "unable to create the binding user for instance %s", instanceID)
+			}
+			if creds, err = withBindingUser(creds, user); err != nil {
+				return err
 			}
-		}
 
-		// Apply additional provisioning logic for Service Catalog Enabled services
-		provider, ok := c.providers[serviceID]
-		if ok {
-			creds, err := provider.Bind(
-				services.Items,
-				bindParams,
-				provisionParams,
-				data,
-			)
+			userJSON, err := json.Marshal(user)
 			if err != nil {
-				return errors.Wrapf(err, "unable to bind instance %s", instanceID)
+				return err
 			}
-			for k, v := range creds {
-				data[k] = v
+			err = c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+				(BindingUserKeyPrefix + bindingID): string(userJSON),
+			})
+			if err != nil {
+				return err
 			}
 		}
 
+		data := c.allowedChartSecrets(serviceID, provisionParams, chartSecrets, hasBindingUser)
+		for k, v := range creds {
+			data[k] = v
+		}
+
 		// Record the result for later fetching
 		bindingResponse := osb.GetBindingResponse{
 			Credentials: data,
@@ -620,27 +887,37 @@ func (c *Client) bindSynchronously(
 			return err
 		}
 
-		err = c.updateConfigMap(instanceID, map[string]interface{}{
+		err = c.updateConfigMap(ctx, instanceID, map[string]interface{}{
 			(BindingKeyPrefix + bindingID): string(bindingResponseJSON),
 		})
 		if err != nil {
 			return err
 		}
 
+		if c.projectBindingSecrets {
+			return c.projectBindingSecret(ctx, instanceID, serviceID, bindingID, namespace, data)
+		}
+
 		return nil
 	}()
 
+	observeOperation(OperationBind, err)
+	tracing.End(span, err)
 	operationState := osb.LastOperationResponse{}
 	if err == nil {
 		operationState.State = osb.StateSucceeded
+		c.recordEvent(config, releaseNamespace, corev1.EventTypeNormal, ReasonBound,
+			"Bound %q to instance %q", bindingID, instanceID)
 	} else {
-		klog.V(2).Infof("minibroker: error binding instance %q: %v", instanceID, err)
+		logger.V(2).Info("failed to bind instance", "class", ErrorClass(err), "error", err)
+		c.recordEvent(config, releaseNamespace, corev1.EventTypeWarning, ReasonBindFailed,
+			"Failed to bind %q to instance %q: %v", bindingID, instanceID, err)
 		operationState.State = osb.StateFailed
-		operationState.Description = strPtr(fmt.Sprintf("Failed to bind instance %q", instanceID))
+		operationState.Description = strPtr(failureDescription(ctx, target, fmt.Sprintf("Failed to bind instance %q", instanceID), err))
 	}
 	operationStateJSON, marshalError := json.Marshal(operationState)
 	if marshalError != nil {
-		klog.V(2).Infof("minibroker: error serializing bind operation state: %v", marshalError)
+		logger.V(2).Info("failed to serialize the bind operation state", "error", marshalError)
 		if err != nil {
 			return err
 		}
@@ -649,9 +926,9 @@ func (c *Client) bindSynchronously(
 	updates := map[string]interface{}{
 		(BindingStateKeyPrefix + bindingID): string(operationStateJSON),
 	}
-	updateError := c.updateConfigMap(instanceID, updates)
+	updateError := c.updateConfigMap(ctx, instanceID, updates)
 	if updateError != nil {
-		klog.V(2).Infof("minibroker: error updating bind status: %v", marshalError)
+		logger.V(2).Info("failed to update the bind operation state", "error", updateError)
 		if err != nil {
 			return err
 		}
@@ -660,28 +937,232 @@ func (c *Client) bindSynchronously(
 	return nil
 }
 
+// resolveCredentials looks up the services and secrets labeled with the instance and returns the
+// chart secrets together with the credentials built by the service provider, if any.
+func (c *Client) resolveCredentials(
+	ctx context.Context,
+	target *cluster,
+	instanceID,
+	serviceID,
+	releaseNamespace string,
+	bindParams *BindParams,
+	provisionParams *ProvisionParams,
+) (Object, Object, error) {
+	filterByInstance := metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			InstanceLabel: instanceID,
+		}).String(),
+	}
+
+	services, err := target.coreClient.CoreV1().
+		Services(releaseNamespace).
+		List(ctx, filterByInstance)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(services.Items) == 0 {
+		return nil, nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+
+	secrets, err := target.coreClient.CoreV1().
+		Secrets(releaseNamespace).
+		List(ctx, filterByInstance)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(secrets.Items) == 0 {
+		return nil, nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+
+	chartSecrets := make(Object)
+	for _, secret := range secrets.Items {
+		for key, value := range secret.Data {
+			chartSecrets[key] = string(value)
+		}
+	}
+
+	creds := make(Object)
+	// Apply additional provisioning logic for Service Catalog Enabled services
+	provider, ok := target.providers[serviceID]
+	if ok {
+		creds, err = provider.Bind(
+			services.Items,
+			bindParams,
+			provisionParams,
+			chartSecrets,
+		)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "unable to bind instance %s", instanceID)
+		}
+	}
+
+	return chartSecrets, creds, nil
+}
+
+// userProvider returns the UserProvider for the service when per-binding users are enabled.
+func (c *Client) userProvider(serviceID string) (UserProvider, bool) {
+	if !c.perBindingUsers {
+		return nil, false
+	}
+	userProvider, ok := c.providers[serviceID].(UserProvider)
+	return userProvider, ok
+}
+
+// validateBindingRole validates the role binding parameter, ensuring it is only used with services
+// supporting per-binding users.
+func (c *Client) validateBindingRole(serviceID string, bindParams *BindParams) error {
+	_, found, err := bindingRole(bindParams)
+	if err != nil {
+		return err
+	}
+	if _, ok := c.userProvider(serviceID); found && !ok {
+		return fmt.Errorf("the %q parameter is not supported by service %q", BindingRoleParam, serviceID)
+	}
+	return nil
+}
+
 // Unbind a previously-bound instance binding.
-func (c *Client) Unbind(instanceID, bindingID string) error {
-	klog.V(3).Infof("minibroker: unbinding instance %q binding %q", instanceID, bindingID)
+func (c *Client) Unbind(ctx context.Context, instanceID, bindingID string) (err error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID, "bindingID", bindingID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("unbinding instance")
+	ctx, span := tracing.Start(ctx, "minibroker.Client.Unbind",
+		attribute.String("instance.id", instanceID),
+		attribute.String("binding.id", bindingID),
+	)
+	defer func() {
+		observeOperation(OperationUnbind, err)
+		tracing.End(span, err)
+	}()
 
-	// The only clean up we need to do is to remove the binding information.
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return osb.HTTPStatusCodeError{StatusCode: http.StatusGone}
+		}
+		return err
+	}
+	defer func() {
+		if err != nil {
+			c.recordEvent(config, config.Data[ReleaseNamespaceKey], corev1.EventTypeWarning, ReasonUnbindFailed,
+				"Failed to unbind %q from instance %q: %v", bindingID, instanceID, err)
+			return
+		}
+		c.recordEvent(config, config.Data[ReleaseNamespaceKey], corev1.EventTypeNormal, ReasonUnbound,
+			"Unbound %q from instance %q", bindingID, instanceID)
+	}()
+
+	if err := c.dropBindingUser(ctx, config, bindingID); err != nil {
+		return err
+	}
+	if err := c.deleteBindingSecret(ctx, config, bindingID); err != nil {
+		return err
+	}
+
+	// The remaining clean up is to remove the binding information.
 	data := map[string]interface{}{
-		(BindingStateKeyPrefix + bindingID): nil,
-		(BindingKeyPrefix + bindingID):      nil,
+		(BindingStateKeyPrefix + bindingID):    nil,
+		(BindingKeyPrefix + bindingID):         nil,
+		(BindingUserKeyPrefix + bindingID):     nil,
+		(BindingSecretKeyPrefix + bindingID):   nil,
+		(BindingIdentityKeyPrefix + bindingID): nil,
+		(BindingDriftKeyPrefix + bindingID):    nil,
 	}
-	if err := c.updateConfigMap(instanceID, data); err != nil {
+	if err := c.updateConfigMap(ctx, instanceID, data); err != nil {
 		return err
 	}
 
-	klog.V(3).Infof("minibroker: unbound instance %q binding %q", instanceID, bindingID)
+	logger.V(3).Info("unbound instance")
 
 	return nil
 }
 
-func (c *Client) GetBinding(instanceID, bindingID string) (*osb.GetBindingResponse, error) {
-	klog.V(3).Infof("minibroker: getting instance %q binding %q", instanceID, bindingID)
+// dropBindingUser drops the dedicated database user of the binding, if one was created, together
+// with the users retired by credential rotations that are still in their grace period.
+func (c *Client) dropBindingUser(ctx context.Context, config *corev1.ConfigMap, bindingID string) error {
+	retiredUsers, err := retiredBindingUsers(config, bindingID)
+	if err != nil {
+		return err
+	}
+	for key, retired := range retiredUsers {
+		if err := c.dropUser(ctx, config, bindingID, &retired.BindingUser); err != nil {
+			return err
+		}
+		if err := c.updateConfigMap(ctx, config.Name, map[string]interface{}{key: nil}); err != nil {
+			return err
+		}
+	}
+
+	user, ok, err := currentBindingUser(config, bindingID)
+	if err != nil || !ok {
+		return err
+	}
+	return c.dropUser(ctx, config, bindingID, user)
+}
 
-	config, err := c.getConfigMap(instanceID)
+// dropUser runs the Job dropping a binding user of the instance described by config.
+func (c *Client) dropUser(ctx context.Context, config *corev1.ConfigMap, bindingID string, user *BindingUser) error {
+	instanceID := config.Name
+	serviceID := config.Data[ServiceKey]
+	userProvider, ok := c.userProvider(serviceID)
+	if !ok {
+		return errors.Errorf("could not drop user %q: service %q does not support binding users", user.Username, serviceID)
+	}
+
+	var provisionParams *ProvisionParams
+	if err := json.Unmarshal([]byte(config.Data[ProvisionParamsKey]), &provisionParams); err != nil {
+		return errors.Wrapf(err, "could not unmarshall provision parameters for instance %q", instanceID)
+	}
+
+	target, err := c.instanceCluster(ctx, config)
+	if err != nil {
+		return err
+	}
+	releaseNamespace := config.Data[ReleaseNamespaceKey]
+	chartSecrets, creds, err := c.resolveCredentials(
+		ctx,
+		target,
+		instanceID,
+		serviceID,
+		releaseNamespace,
+		NewBindParams(nil),
+		provisionParams,
+	)
+	if err != nil {
+		return err
+	}
+
+	job, err := userProvider.DropUserJob(creds, chartSecrets, user)
+	if err != nil {
+		return errors.Wrapf(err, "could not drop user %q", user.Username)
+	}
+	contextLogger(ctx).V(3).Info("dropping the binding user", "bindingID", bindingID, "user", user.Username)
+	if err := c.runUserJob(ctx, target, releaseNamespace, config.Data[ReleaseLabel], userJobName("drop", user), job); err != nil {
+		return errors.Wrapf(err, "could not drop user %q", user.Username)
+	}
+
+	return nil
+}
+
+// currentBindingUser returns the user record of the binding stored in the instance configmap.
+func currentBindingUser(config *corev1.ConfigMap, bindingID string) (*BindingUser, bool, error) {
+	userJSON, ok := config.Data[BindingUserKeyPrefix+bindingID]
+	if !ok {
+		return nil, false, nil
+	}
+	var user *BindingUser
+	if err := json.Unmarshal([]byte(userJSON), &user); err != nil {
+		return nil, false, errors.Wrapf(err, "could not unmarshall the user of binding %q", bindingID)
+	}
+	return user, true, nil
+}
+
+func (c *Client) GetBinding(ctx context.Context, instanceID, bindingID string) (*osb.GetBindingResponse, error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID, "bindingID", bindingID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("getting binding")
+
+	config, err := c.getConfigMap(ctx, instanceID)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
@@ -692,21 +1173,34 @@ func (c *Client) GetBinding(instanceID, bindingID string) (*osb.GetBindingRespon
 	if !ok {
 		return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
 	}
+	// The binding data is recorded before the binding operation completes.
+	if err := bindingFetchable(config, bindingID); err != nil {
+		return nil, err
+	}
 	var data *osb.GetBindingResponse
 	err = json.Unmarshal([]byte(jsonData), &data)
 	if err != nil {
 		return nil, errors.Wrapf(err, "Could not decode binding data")
 	}
 
-	klog.V(3).Infof("minibroker: got instance %q binding %q", instanceID, bindingID)
+	if c.liveCredentials {
+		data, err = c.liveBinding(ctx, config, bindingID, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	logger.V(3).Info("got binding")
 
 	return data, nil
 }
 
-func (c *Client) Deprovision(instanceID string, acceptsIncomplete bool) (string, error) {
-	klog.V(3).Infof("minibroker: deprovisioning instance %q", instanceID)
-
-	ctx := context.TODO()
+func (c *Client) Deprovision(ctx context.Context, instanceID string, acceptsIncomplete bool) (_ string, err error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("deprovisioning instance")
+	ctx, span := tracing.Start(ctx, "minibroker.Client.Deprovision", attribute.String("instance.id", instanceID))
+	defer func() { tracing.End(span, err) }()
 
 	config, err := c.coreClient.CoreV1().
 		ConfigMaps(c.namespace).
@@ -717,21 +1211,26 @@ func (c *Client) Deprovision(instanceID string, acceptsIncomplete bool) (string,
 		}
 		return "", err
 	}
-	release := config.Data[ReleaseLabel]
-	namespace := config.Data[ReleaseNamespaceKey]
+	target, err := c.instanceCluster(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	c.recordEvent(config, config.Data[ReleaseNamespaceKey], corev1.EventTypeNormal, ReasonDeprovisioning,
+		"Deprovisioning instance %q", instanceID)
 
 	if !acceptsIncomplete {
-		klog.V(3).Infof("minibroker: synchronously deprovisioning instance %q", instanceID)
-		if err := c.deprovisionSynchronously(instanceID, release, namespace); err != nil {
+		logger.V(3).Info("synchronously deprovisioning instance")
+		if err := c.deprovisionSynchronously(ctx, target, config); err != nil {
 			return "", err
 		}
-		klog.V(3).Infof("minibroker: synchronously deprovisioned instance %q", instanceID)
+		logger.V(3).Info("synchronously deprovisioned instance")
 		return "", nil
 	}
 
-	klog.V(3).Infof("minibroker: asynchronously deprovisioning instance %q", instanceID)
+	logger.V(3).Info("asynchronously deprovisioning instance")
 	operationKey := generateOperationName(OperationPrefixDeprovision)
-	err = c.updateConfigMap(instanceID, map[string]interface{}{
+	err = c.updateConfigMap(ctx, instanceID, map[string]interface{}{
 		OperationStateKey:       string(osb.StateInProgress),
 		OperationNameKey:        operationKey,
 		OperationDescriptionKey: fmt.Sprintf("deprovisioning service instance %q", instanceID),
@@ -739,33 +1238,77 @@ func (c *Client) Deprovision(instanceID string, acceptsIncomplete bool) (string,
 	if err != nil {
 		return "", errors.Wrapf(err, "Failed to set operation key when deprovisioning instance %s", instanceID)
 	}
+	done := trackAsyncOperation(OperationDeprovision)
+	asyncCtx, asyncSpan := tracing.StartAsync(ctx, "minibroker.deprovision.async",
+		attribute.String("instance.id", instanceID),
+		attribute.String("operation.key", operationKey),
+	)
+	asyncCtx = log.Inherit(asyncCtx, ctx)
 	go func() {
-		err = c.deprovisionSynchronously(instanceID, release, namespace)
+		defer done()
+		err := c.deprovisionSynchronously(asyncCtx, target, config)
+		tracing.End(asyncSpan, err)
 		if err == nil {
 			// After deprovisioning, there is no config map to update
+			logger.V(3).Info("asynchronously deprovisioned instance", "operation", operationKey)
 			return
 		}
-		klog.V(2).Infof("minibroker: failed to deprovision %q: %v", instanceID, err)
-		err = c.updateConfigMap(instanceID, map[string]interface{}{
+		logger.V(2).Info("failed to deprovision", "operation", operationKey, "class", ErrorClass(err), "error", err)
+		description := failureDescription(asyncCtx, target,
+			fmt.Sprintf("service instance %q failed to deprovision", instanceID), err)
+		err = c.updateConfigMap(asyncCtx, instanceID, map[string]interface{}{
 			OperationStateKey:       string(osb.StateFailed),
-			OperationDescriptionKey: fmt.Sprintf("service instance %q failed to deprovision", instanceID),
+			OperationDescriptionKey: description,
 		})
 		if err != nil {
-			klog.V(2).Infof("minibroker: could not update operation state when deprovisioning asynchronously: %v", err)
+			logger.V(2).Info("could not update the operation state when deprovisioning asynchronously", "operation", operationKey, "error", err)
 		}
-		klog.V(3).Infof("minibroker: asynchronously deprovisioned instance %q", instanceID)
 	}()
 	return operationKey, nil
 }
 
-func (c *Client) deprovisionSynchronously(instanceID, releaseName, namespace string) error {
-	ctx := context.TODO()
+// deprovisionSynchronously uninstalls the release of the instance and deletes its records. The
+// namespace generated for the instance, if any, is deleted along with the persistent volume claims
+// left behind by the release.
+func (c *Client) deprovisionSynchronously(ctx context.Context, target *cluster, config *corev1.ConfigMap) (err error) {
+	ctx, span := tracing.Start(ctx, "minibroker.deprovision")
+	instanceID := config.Name
+	releaseName := config.Data[ReleaseLabel]
+	namespace := config.Data[ReleaseNamespaceKey]
+	instanceNamespace := config.Data[InstanceNamespaceKey]
+	defer func() {
+		observeOperation(OperationDeprovision, err)
+		tracing.End(span, err)
+		if err != nil {
+			c.recordEvent(config, namespace, corev1.EventTypeWarning, ReasonDeprovisionFailed,
+				"Failed to deprovision instance %q: %v", instanceID, err)
+			return
+		}
+		// The generated namespace of the instance is gone with its release.
+		eventNamespace := namespace
+		if instanceNamespace != "" {
+			eventNamespace = ""
+		}
+		c.recordEvent(config, eventNamespace, corev1.EventTypeNormal, ReasonDeprovisioned,
+			"Deprovisioned instance %q", instanceID)
+	}()
 
-	if err := c.helm.ChartClient().Uninstall(releaseName, namespace); err != nil {
+	uninstallStart := time.Now()
+	err = target.chartClient.Uninstall(ctx, releaseName, namespace)
+	helmUninstallDuration.
+		WithLabelValues(config.Data[ServiceKey], config.Data[PlanKey]).
+		Observe(time.Since(uninstallStart).Seconds())
+	if err != nil {
 		return errors.Wrapf(err, "could not uninstall release %s", releaseName)
 	}
 
-	err := c.coreClient.CoreV1().
+	if instanceNamespace != "" {
+		if err := deleteInstanceNamespace(ctx, target.coreClient, instanceNamespace); err != nil {
+			return err
+		}
+	}
+
+	err = c.coreClient.CoreV1().
 		ConfigMaps(c.namespace).
 		Delete(ctx, instanceID, metav1.DeleteOptions{})
 	if err != nil {
@@ -777,25 +1320,20 @@ func (c *Client) deprovisionSynchronously(instanceID, releaseName, namespace str
 
 // LastOperationState returns the status of the last asynchronous operation. TODO(f0rmiga): This
 // deserves some polimorphism.
-func (c *Client) LastOperationState(instanceID string, operationKey *osb.OperationKey) (*osb.LastOperationResponse, error) {
-	ctx := context.TODO()
-
+func (c *Client) LastOperationState(ctx context.Context, instanceID string, operationKey *osb.OperationKey) (*osb.LastOperationResponse, error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID))
+	logger := contextLogger(ctx)
 	if operationKey != nil {
-		klog.V(4).Infof("minibroker: getting last operation state for instance %q using key %q", instanceID, *operationKey)
-	} else {
-		klog.V(4).Infof("minibroker: getting last operation state for instance %q without key", instanceID)
+		logger = logger.WithValues("operation", *operationKey)
 	}
+	logger.V(4).Info("getting last operation state")
 
 	config, err := c.coreClient.CoreV1().
 		ConfigMaps(c.namespace).
 		Get(ctx, instanceID, metav1.GetOptions{})
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			if operationKey != nil {
-				klog.V(5).Infof("minibroker: missing instance %q while getting last operation state using key %q", instanceID, *operationKey)
-			} else {
-				klog.V(5).Infof("minibroker: missing instance %q while getting last operation state without key", instanceID)
-			}
+			logger.V(5).Info("missing instance while getting last operation state")
 			return nil, osb.HTTPStatusCodeError{
 				StatusCode: http.StatusGone,
 			}
@@ -805,11 +1343,7 @@ func (c *Client) LastOperationState(instanceID string, operationKey *osb.Operati
 
 	if operationKey != nil && config.Data[OperationNameKey] != string(*operationKey) {
 		// Got unexpected operation key.
-		if operationKey != nil {
-			klog.V(4).Infof("minibroker: failed to get last operation state for instance %q using key %q", instanceID, *operationKey)
-		} else {
-			klog.V(4).Infof("minibroker: failed to get last operation state for instance %q without key", instanceID)
-		}
+		logger.V(4).Info("failed to get last operation state", "currentOperation", config.Data[OperationNameKey])
 		return nil, osb.HTTPStatusCodeError{
 			StatusCode:   http.StatusBadRequest,
 			ErrorMessage: strPtr(ConcurrencyErrorMessage),
@@ -823,11 +1357,7 @@ func (c *Client) LastOperationState(instanceID string, operationKey *osb.Operati
 		Description: &description,
 	}
 
-	if operationKey != nil {
-		klog.V(4).Infof("minibroker: got last operation state for instance %q using key %q", instanceID, *operationKey)
-	} else {
-		klog.V(4).Infof("minibroker: got last operation state for instance %q without key", instanceID)
-	}
+	logger.V(4).Info("got last operation state", "state", response.State)
 
 	return response, nil
 }
@@ -840,12 +1370,14 @@ func strPtr(value string) *string {
 	return &value
 }
 
-func (c *Client) LastBindingOperationState(instanceID, bindingID string) (*osb.LastOperationResponse, error) {
-	klog.V(4).Infof("minibroker: getting last binding %q operation state for instance %q", bindingID, instanceID)
-	config, err := c.getConfigMap(instanceID)
+func (c *Client) LastBindingOperationState(ctx context.Context, instanceID, bindingID string) (*osb.LastOperationResponse, error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID, "bindingID", bindingID))
+	logger := contextLogger(ctx)
+	logger.V(4).Info("getting last binding operation state")
+	config, err := c.getConfigMap(ctx, instanceID)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			klog.V(5).Infof("minibroker: missing instance %q while getting last binding %q operation state", instanceID, bindingID)
+			logger.V(5).Info("missing instance while getting last binding operation state")
 			return nil, osb.HTTPStatusCodeError{
 				StatusCode: http.StatusGone,
 			}
@@ -854,7 +1386,7 @@ func (c *Client) LastBindingOperationState(instanceID, bindingID string) (*osb.L
 
 	stateJSON, ok := config.Data[BindingStateKeyPrefix+bindingID]
 	if !ok {
-		klog.V(5).Infof("minibroker: missing binding %q for instance %q while getting last binding operation state", bindingID, instanceID)
+		logger.V(5).Info("missing binding while getting last binding operation state")
 		return nil, osb.HTTPStatusCodeError{
 			StatusCode: http.StatusGone,
 		}
@@ -866,6 +1398,6 @@ func (c *Client) LastBindingOperationState(instanceID, bindingID string) (*osb.L
 		return nil, errors.Wrapf(err, "Error unmarshalling binding state %s", stateJSON)
 	}
 
-	klog.V(4).Infof("minibroker: got last binding %q operation state for instance %q", bindingID, instanceID)
+	logger.V(4).Info("got last binding operation state", "state", response.State)
 	return response, nil
 }
@@ -17,10 +17,13 @@ limitations under the License.
 package minibroker
 
 import (
+	"context"
 	"testing"
 
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/repo"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestHasTag(t *testing.T) {
@@ -102,3 +105,27 @@ func TestGetTagIntersection(t *testing.T) {
 		}
 	}
 }
+
+func TestNewClient(t *testing.T) {
+	helmClient := helm.NewDefaultClient()
+	coreClient := fake.NewSimpleClientset()
+
+	c := NewClient(context.TODO(), helmClient, coreClient, ClientOptions{
+		Namespace:     "minibroker",
+		ClusterDomain: "example.local",
+	})
+
+	if c.helm != helmClient {
+		t.Errorf("NewClient: expected the injected Helm client")
+	}
+	if c.coreClient != coreClient {
+		t.Errorf("NewClient: expected the injected k8s client")
+	}
+	if c.namespace != "minibroker" {
+		t.Errorf("NewClient: expected namespace %q, actual %q", "minibroker", c.namespace)
+	}
+	provider, ok := c.providers["mysql"].(MySQLProvider)
+	if !ok || provider.hostBuilder.clusterDomain != "example.local" {
+		t.Errorf("NewClient: expected the providers to use the cluster domain %q", "example.local")
+	}
+}
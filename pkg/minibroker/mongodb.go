@@ -51,16 +51,9 @@ func (p MongodbProvider) Bind(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database name: %w", err)
 	}
-	user, err := provisionParams.DigStringOr("mongodbUsername", rootMongodbUsername)
+	user, passwordKey, err := p.bindUser(provisionParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get username: %w", err)
-	}
-
-	var passwordKey string
-	if user == rootMongodbUsername {
-		passwordKey = "mongodb-root-password"
-	} else {
-		passwordKey = "mongodb-password"
+		return nil, err
 	}
 	password, err := chartSecrets.DigString(passwordKey)
 	if err != nil {
@@ -84,3 +77,111 @@ func (p MongodbProvider) Bind(
 
 	return creds, nil
 }
+
+// SecretKeys satisfies Provider.
+func (p MongodbProvider) SecretKeys(provisionParams *ProvisionParams) []string {
+	_, passwordKey, err := p.bindUser(provisionParams)
+	if err != nil {
+		return nil
+	}
+	return []string{passwordKey}
+}
+
+// bindUser returns the user of the bindings and the chart secret key of its password.
+func (p MongodbProvider) bindUser(provisionParams *ProvisionParams) (string, string, error) {
+	user, err := provisionParams.DigStringOr("mongodbUsername", rootMongodbUsername)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get username: %w", err)
+	}
+	if user == rootMongodbUsername {
+		return user, "mongodb-root-password", nil
+	}
+	return user, "mongodb-password", nil
+}
+
+// CreateUserJob satisfies UserProvider.
+func (p MongodbProvider) CreateUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	database, err := mongodbUserDatabase(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles string
+	switch user.Role {
+	case BindingRoleReadOnly:
+		roles = fmt.Sprintf(`[{role: "read", db: %s}]`, quoteJSString(database))
+	case BindingRoleReadWrite:
+		roles = fmt.Sprintf(`[{role: "readWrite", db: %s}]`, quoteJSString(database))
+	case BindingRoleAdmin:
+		roles = fmt.Sprintf(`[{role: "dbOwner", db: %s}, {role: "root", db: "admin"}]`, quoteJSString(database))
+	default:
+		return nil, fmt.Errorf("unsupported role %q", user.Role)
+	}
+
+	// The user may exist when a previous attempt failed after creating it, so it is updated.
+	script := fmt.Sprintf(
+		"var userDB = db.getSiblingDB(%[1]s); "+
+			"if (userDB.getUser(%[2]s)) { userDB.updateUser(%[2]s, {pwd: %[3]s, roles: %[4]s}) } "+
+			"else { userDB.createUser({user: %[2]s, pwd: %[3]s, roles: %[4]s}) }",
+		quoteJSString(database),
+		quoteJSString(user.Username),
+		quoteJSString(user.Password),
+		roles,
+	)
+	return p.userJob(creds, chartSecrets, script)
+}
+
+// DropUserJob satisfies UserProvider.
+func (p MongodbProvider) DropUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	database, err := mongodbUserDatabase(creds)
+	if err != nil {
+		return nil, err
+	}
+	script := fmt.Sprintf(
+		"db.getSiblingDB(%s).dropUser(%s)",
+		quoteJSString(database),
+		quoteJSString(user.Username),
+	)
+	return p.userJob(creds, chartSecrets, script)
+}
+
+func (p MongodbProvider) userJob(creds Object, chartSecrets Object, script string) (*UserJob, error) {
+	adminPassword, err := chartSecrets.DigString("mongodb-root-password")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin password: %w", err)
+	}
+	env, err := userJobEnv(creds, rootMongodbUsername, adminPassword, script)
+	if err != nil {
+		return nil, err
+	}
+	return &UserJob{
+		Command: []string{
+			"sh", "-c",
+			`mongo --quiet --host "$MB_HOST" --port "$MB_PORT" -u "$MB_ADMIN_USER" ` +
+				`-p "$MB_ADMIN_PASSWORD" --authenticationDatabase admin --eval "$MB_SCRIPT"`,
+		},
+		Env:          env,
+		ClientImages: []string{"mongodb", "mongo"},
+	}, nil
+}
+
+// mongodbUserDatabase returns the database the binding users are created in. The users are
+// created in the bound database so it is also their authentication database.
+func mongodbUserDatabase(creds Object) (string, error) {
+	database, err := creds.DigStringOr("database", "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get database name: %w", err)
+	}
+	if database == "" {
+		database = "admin"
+	}
+	return database, nil
+}
@@ -19,6 +19,7 @@ package minibroker
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -51,16 +52,9 @@ func (p MySQLProvider) Bind(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database name: %w", err)
 	}
-	user, err := provisionParams.DigStringOr("mysqlUser", rootMysqlUsername)
+	user, passwordKey, err := p.bindUser(provisionParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get username: %w", err)
-	}
-
-	var passwordKey string
-	if user == rootMysqlUsername {
-		passwordKey = "mysql-root-password"
-	} else {
-		passwordKey = "mysql-password"
+		return nil, err
 	}
 	password, err := chartSecrets.DigString(passwordKey)
 	if err != nil {
@@ -84,3 +78,114 @@ func (p MySQLProvider) Bind(
 
 	return creds, nil
 }
+
+// SecretKeys satisfies Provider.
+func (p MySQLProvider) SecretKeys(provisionParams *ProvisionParams) []string {
+	_, passwordKey, err := p.bindUser(provisionParams)
+	if err != nil {
+		return nil
+	}
+	return []string{passwordKey}
+}
+
+// bindUser returns the user of the bindings and the chart secret key of its password.
+func (p MySQLProvider) bindUser(provisionParams *ProvisionParams) (string, string, error) {
+	user, err := provisionParams.DigStringOr("mysqlUser", rootMysqlUsername)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get username: %w", err)
+	}
+	if user == rootMysqlUsername {
+		return user, "mysql-root-password", nil
+	}
+	return user, "mysql-password", nil
+}
+
+// CreateUserJob satisfies UserProvider.
+func (p MySQLProvider) CreateUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	script, err := mysqlCreateUserScript(creds, user)
+	if err != nil {
+		return nil, err
+	}
+	return p.userJob(creds, chartSecrets, script)
+}
+
+// DropUserJob satisfies UserProvider.
+func (p MySQLProvider) DropUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	return p.userJob(creds, chartSecrets, mysqlDropUserScript(user))
+}
+
+func (p MySQLProvider) userJob(creds Object, chartSecrets Object, script string) (*UserJob, error) {
+	adminPassword, err := chartSecrets.DigString("mysql-root-password")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin password: %w", err)
+	}
+	return mysqlUserJob(creds, rootMysqlUsername, adminPassword, script, []string{"mysql"})
+}
+
+// mysqlCreateUserScript builds the statements for creating a binding user. It is shared by the
+// MySQL and MariaDB providers.
+func mysqlCreateUserScript(creds Object, user *BindingUser) (string, error) {
+	database, err := creds.DigStringOr("database", "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get database name: %w", err)
+	}
+	scope := "*.*"
+	if database != "" {
+		scope = quoteMySQLIdent(database) + ".*"
+	}
+	account := quoteSQLString(user.Username) + "@'%'"
+
+	// The user may exist when a previous attempt failed after creating it, so its password is reset.
+	password := quoteSQLString(user.Password)
+	statements := []string{
+		fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY %s;", account, password),
+		fmt.Sprintf("ALTER USER %s IDENTIFIED BY %s;", account, password),
+	}
+	switch user.Role {
+	case BindingRoleReadOnly:
+		statements = append(statements, fmt.Sprintf("GRANT SELECT, SHOW VIEW ON %s TO %s;", scope, account))
+	case BindingRoleReadWrite:
+		statements = append(statements, fmt.Sprintf(
+			"GRANT SELECT, INSERT, UPDATE, DELETE, CREATE, DROP, INDEX, ALTER, "+
+				"CREATE TEMPORARY TABLES, LOCK TABLES, CREATE VIEW, SHOW VIEW, EXECUTE ON %s TO %s;",
+			scope, account))
+	case BindingRoleAdmin:
+		statements = append(statements, fmt.Sprintf("GRANT ALL PRIVILEGES ON %s TO %s WITH GRANT OPTION;", scope, account))
+	default:
+		return "", fmt.Errorf("unsupported role %q", user.Role)
+	}
+	statements = append(statements, "FLUSH PRIVILEGES;")
+
+	return strings.Join(statements, " "), nil
+}
+
+// mysqlDropUserScript builds the statements for dropping a binding user. It is shared by the MySQL
+// and MariaDB providers.
+func mysqlDropUserScript(user *BindingUser) string {
+	return fmt.Sprintf("DROP USER IF EXISTS %s@'%%'; FLUSH PRIVILEGES;", quoteSQLString(user.Username))
+}
+
+// mysqlUserJob builds the UserJob running a script with the mysql client. It is shared by the MySQL
+// and MariaDB providers.
+func mysqlUserJob(creds Object, adminUser, adminPassword, script string, clientImages []string) (*UserJob, error) {
+	env, err := userJobEnv(creds, adminUser, adminPassword, script)
+	if err != nil {
+		return nil, err
+	}
+	return &UserJob{
+		Command: []string{
+			"sh", "-c",
+			`MYSQL_PWD="$MB_ADMIN_PASSWORD" mysql -h "$MB_HOST" -P "$MB_PORT" -u "$MB_ADMIN_USER" -e "$MB_SCRIPT"`,
+		},
+		Env:          env,
+		ClientImages: clientImages,
+	}, nil
+}
@@ -19,6 +19,7 @@ package minibroker
 import (
 	"fmt"
 	"net/url"
+	"strings"
 
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
@@ -55,27 +56,11 @@ func (p PostgresProvider) Bind(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get database name: %w", err)
 	}
-	user, err := provisionParams.DigStringAltOr(
-		// Some older chart versions use postgresUsername instead of postgresqlUsername.
-		[]string{"postgresqlUsername", "postgresUsername"},
-		defaultPostgresqlUsername,
-	)
+	user, passwordKeys, err := p.bindUser(provisionParams)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get username: %w", err)
+		return nil, err
 	}
-
-	var passwordKey, altPasswordKey string
-	// postgresql-postgres-password is used when postgresqlPostgresPassword is set and
-	// postgresqlUsername is not 'postgres'.
-	if _, ok := provisionParams.Dig("postgresqlPostgresPassword"); ok && user != defaultPostgresqlUsername {
-		passwordKey = "postgresql-postgres-password"
-	} else {
-		passwordKey = "postgresql-password"
-		// Chart versions <2.0 use postgres-password instead of postgresql-password.
-		// See https://github.com/kubernetes-sigs/minibroker/issues/17
-		altPasswordKey = "postgres-password"
-	}
-	password, err := chartSecrets.DigStringAlt([]string{passwordKey, altPasswordKey})
+	password, err := chartSecrets.DigStringAlt(passwordKeys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get password: %w", err)
 	}
@@ -97,3 +82,156 @@ func (p PostgresProvider) Bind(
 
 	return creds, nil
 }
+
+// SecretKeys satisfies Provider.
+func (p PostgresProvider) SecretKeys(provisionParams *ProvisionParams) []string {
+	_, passwordKeys, err := p.bindUser(provisionParams)
+	if err != nil {
+		return nil
+	}
+	return passwordKeys
+}
+
+// bindUser returns the user of the bindings and the chart secret keys its password may be stored
+// under.
+func (p PostgresProvider) bindUser(provisionParams *ProvisionParams) (string, []string, error) {
+	user, err := provisionParams.DigStringAltOr(
+		// Some older chart versions use postgresUsername instead of postgresqlUsername.
+		[]string{"postgresqlUsername", "postgresUsername"},
+		defaultPostgresqlUsername,
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get username: %w", err)
+	}
+	// postgresql-postgres-password is used when postgresqlPostgresPassword is set and
+	// postgresqlUsername is not 'postgres'.
+	if _, ok := provisionParams.Dig("postgresqlPostgresPassword"); ok && user != defaultPostgresqlUsername {
+		return user, []string{"postgresql-postgres-password"}, nil
+	}
+	// Chart versions <2.0 use postgres-password instead of postgresql-password.
+	// See https://github.com/kubernetes-sigs/minibroker/issues/17
+	return user, []string{"postgresql-password", "postgres-password"}, nil
+}
+
+// CreateUserJob satisfies UserProvider.
+func (p PostgresProvider) CreateUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	database, err := creds.DigStringOr("database", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+	if database == "" {
+		database = defaultPostgresqlUsername
+	}
+	username := quotePostgresIdent(user.Username)
+	db := quotePostgresIdent(database)
+
+	// The role may exist when a previous attempt failed after creating it, so its password is reset.
+	password := quoteSQLString(user.Password)
+	statements := []string{
+		fmt.Sprintf(
+			"DO $$ BEGIN IF EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN "+
+				"ALTER ROLE %s LOGIN PASSWORD %s; "+
+				"ELSE CREATE ROLE %s LOGIN PASSWORD %s; "+
+				"END IF; END $$;",
+			quoteSQLString(user.Username), username, password, username, password,
+		),
+		fmt.Sprintf("GRANT CONNECT ON DATABASE %s TO %s;", db, username),
+		fmt.Sprintf("GRANT USAGE ON SCHEMA public TO %s;", username),
+	}
+	switch user.Role {
+	case BindingRoleReadOnly:
+		statements = append(statements,
+			fmt.Sprintf("GRANT SELECT ON ALL TABLES IN SCHEMA public TO %s;", username),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT ON TABLES TO %s;", username),
+		)
+	case BindingRoleReadWrite:
+		statements = append(statements,
+			fmt.Sprintf("GRANT CREATE ON SCHEMA public TO %s;", username),
+			fmt.Sprintf("GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO %s;", username),
+			fmt.Sprintf("GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO %s;", username),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO %s;", username),
+			fmt.Sprintf("ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO %s;", username),
+		)
+	case BindingRoleAdmin:
+		statements = append(statements,
+			fmt.Sprintf("GRANT ALL PRIVILEGES ON DATABASE %s TO %s;", db, username),
+			fmt.Sprintf("ALTER ROLE %s CREATEDB CREATEROLE;", username),
+		)
+	default:
+		return nil, fmt.Errorf("unsupported role %q", user.Role)
+	}
+
+	return p.userJob(creds, chartSecrets, database, strings.Join(statements, " "))
+}
+
+// DropUserJob satisfies UserProvider.
+func (p PostgresProvider) DropUserJob(
+	creds Object,
+	chartSecrets Object,
+	user *BindingUser,
+) (*UserJob, error) {
+	database, err := creds.DigStringOr("database", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+	if database == "" {
+		database = defaultPostgresqlUsername
+	}
+	username := quotePostgresIdent(user.Username)
+
+	// Objects owned by the binding user are handed over to the administrator so the role can be
+	// dropped without losing data.
+	script := fmt.Sprintf(
+		"DO $$ BEGIN IF EXISTS (SELECT FROM pg_roles WHERE rolname = %s) THEN "+
+			"EXECUTE 'REASSIGN OWNED BY %s TO CURRENT_USER'; "+
+			"EXECUTE 'DROP OWNED BY %s'; "+
+			"EXECUTE 'DROP ROLE %s'; "+
+			"END IF; END $$;",
+		quoteSQLString(user.Username),
+		strings.Replace(username, "'", "''", -1),
+		strings.Replace(username, "'", "''", -1),
+		strings.Replace(username, "'", "''", -1),
+	)
+
+	return p.userJob(creds, chartSecrets, database, script)
+}
+
+func (p PostgresProvider) userJob(
+	creds Object,
+	chartSecrets Object,
+	database string,
+	script string,
+) (*UserJob, error) {
+	adminUser := defaultPostgresqlUsername
+	adminPassword, err := chartSecrets.DigString("postgresql-postgres-password")
+	if err == ErrDigNotFound {
+		// Without a dedicated postgres password, the bound user is the database owner.
+		if adminUser, err = creds.DigString("username"); err != nil {
+			return nil, fmt.Errorf("failed to get admin username: %w", err)
+		}
+		adminPassword, err = creds.DigString("password")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get admin password: %w", err)
+	}
+
+	env, err := userJobEnv(creds, adminUser, adminPassword, script)
+	if err != nil {
+		return nil, err
+	}
+	env[userJobEnvDatabase] = database
+
+	return &UserJob{
+		Command: []string{
+			"sh", "-c",
+			`PGPASSWORD="$MB_ADMIN_PASSWORD" psql -v ON_ERROR_STOP=1 ` +
+				`-h "$MB_HOST" -p "$MB_PORT" -U "$MB_ADMIN_USER" -d "$MB_DATABASE" -c "$MB_SCRIPT"`,
+		},
+		Env:          env,
+		ClientImages: []string{"postgresql", "postgres"},
+	}, nil
+}
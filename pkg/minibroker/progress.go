@@ -0,0 +1,199 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultProgressInterval is how often the workloads of a release being installed are inspected.
+const defaultProgressInterval = 10 * time.Second
+
+// releaseProgress is the readiness of the workloads of a release.
+type releaseProgress struct {
+	readyPods   int32
+	desiredPods int32
+	boundClaims int
+	claims      int
+}
+
+// String summarizes the progress, e.g. "2/3 pods ready, PVC bound". It is empty when the release
+// has no workloads yet.
+func (p releaseProgress) String() string {
+	var parts []string
+	if p.desiredPods > 0 {
+		parts = append(parts, fmt.Sprintf("%d/%d pods ready", p.readyPods, p.desiredPods))
+	}
+	switch {
+	case p.claims == 1 && p.boundClaims == 1:
+		parts = append(parts, "PVC bound")
+	case p.claims == 1:
+		parts = append(parts, "PVC pending")
+	case p.claims > 1:
+		parts = append(parts, fmt.Sprintf("%d/%d PVCs bound", p.boundClaims, p.claims))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ratio returns the fraction of the pods ready and the claims bound.
+func (p releaseProgress) ratio() float64 {
+	total := float64(p.desiredPods) + float64(p.claims)
+	if total == 0 {
+		return 0
+	}
+	return (float64(p.readyPods) + float64(p.boundClaims)) / total
+}
+
+// getReleaseProgress inspects the StatefulSets, Deployments and PersistentVolumeClaims of a
+// release.
+func getReleaseProgress(ctx context.Context, coreClient kubernetes.Interface, namespace, releaseName string) (releaseProgress, error) {
+	var progress releaseProgress
+	for _, selector := range releaseSelectors(releaseName) {
+		options := metav1.ListOptions{LabelSelector: selector}
+		statefulSets, err := coreClient.AppsV1().StatefulSets(namespace).List(ctx, options)
+		if err != nil {
+			return progress, err
+		}
+		deployments, err := coreClient.AppsV1().Deployments(namespace).List(ctx, options)
+		if err != nil {
+			return progress, err
+		}
+		claims, err := coreClient.CoreV1().PersistentVolumeClaims(namespace).List(ctx, options)
+		if err != nil {
+			return progress, err
+		}
+
+		for _, statefulSet := range statefulSets.Items {
+			progress.readyPods += statefulSet.Status.ReadyReplicas
+			progress.desiredPods += desiredReplicas(statefulSet.Spec.Replicas)
+		}
+		for _, deployment := range deployments.Items {
+			progress.readyPods += deployment.Status.ReadyReplicas
+			progress.desiredPods += desiredReplicas(deployment.Spec.Replicas)
+		}
+		for _, claim := range claims.Items {
+			progress.claims++
+			if claim.Status.Phase == corev1.ClaimBound {
+				progress.boundClaims++
+			}
+		}
+		if progress.desiredPods > 0 || progress.claims > 0 {
+			break
+		}
+	}
+	return progress, nil
+}
+
+// desiredReplicas returns the replicas of a workload, which default to 1.
+func desiredReplicas(replicas *int32) int32 {
+	if replicas == nil {
+		return 1
+	}
+	return *replicas
+}
+
+// progressWatcher writes the progress of a release being installed into the operation description
+// of its instance and into the provisioning progress metric.
+type progressWatcher struct {
+	c          *Client
+	target     *cluster
+	instanceID string
+	serviceID  string
+	planID     string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// newProgressWatcher creates a progressWatcher for an instance. Its watch method is the release
+// notifier of the Helm install.
+func (c *Client) newProgressWatcher(ctx context.Context, target *cluster, instanceID, serviceID, planID string) *progressWatcher {
+	ctx, cancel := context.WithCancel(ctx)
+	return &progressWatcher{
+		c:          c,
+		target:     target,
+		instanceID: instanceID,
+		serviceID:  serviceID,
+		planID:     planID,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// watch starts watching the release until the watcher is stopped.
+func (w *progressWatcher) watch(releaseName, namespace string) {
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		w.run(releaseName, namespace)
+	}()
+}
+
+func (w *progressWatcher) run(releaseName, namespace string) {
+	logger := contextLogger(w.ctx).WithValues("release", releaseName, "namespace", namespace)
+	interval := w.c.progressInterval
+	if interval <= 0 {
+		interval = defaultProgressInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last string
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		progress, err := getReleaseProgress(w.ctx, w.target.coreClient, namespace, releaseName)
+		if err != nil {
+			logger.V(4).Info("failed to get the release progress", "error", err)
+			continue
+		}
+		provisioningProgress.WithLabelValues(w.instanceID, w.serviceID, w.planID).Set(progress.ratio())
+		summary := progress.String()
+		if summary == "" || summary == last {
+			continue
+		}
+		last = summary
+		logger.V(4).Info("release progress", "progress", summary)
+		err = w.c.updateConfigMap(w.ctx, w.instanceID, map[string]interface{}{
+			OperationDescriptionKey: fmt.Sprintf("provisioning service instance %q: %s", w.instanceID, summary),
+		})
+		if err != nil {
+			logger.V(4).Info("failed to update the operation description", "error", err)
+		}
+	}
+}
+
+// stop stops watching and waits for the last description update, so it cannot overwrite the final
+// state of the operation.
+func (w *progressWatcher) stop() {
+	w.cancel()
+	w.wg.Wait()
+	provisioningProgress.DeleteLabelValues(w.instanceID, w.serviceID, w.planID)
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func newProgressObjects(label string) []runtime.Object {
+	meta := func(name string) metav1.ObjectMeta {
+		return metav1.ObjectMeta{Name: name, Namespace: "team-a", Labels: map[string]string{label: "db-mysql"}}
+	}
+	return []runtime.Object{
+		&appsv1.StatefulSet{
+			ObjectMeta: meta("db-mysql"),
+			Spec:       appsv1.StatefulSetSpec{Replicas: int32Ptr(2)},
+			Status:     appsv1.StatefulSetStatus{ReadyReplicas: 1},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: meta("db-mysql-metrics"),
+			Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: meta("data-db-mysql-0"),
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+		},
+	}
+}
+
+func TestGetReleaseProgress(t *testing.T) {
+	cases := []struct {
+		name     string
+		objects  []runtime.Object
+		expected string
+		ratio    float64
+	}{
+		{name: "release label", objects: newProgressObjects(ReleaseLabel), expected: "2/3 pods ready, PVC bound", ratio: 0.75},
+		{name: "instance label", objects: newProgressObjects("app.kubernetes.io/instance"), expected: "2/3 pods ready, PVC bound", ratio: 0.75},
+		{name: "no workloads", expected: "", ratio: 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			coreClient := fake.NewSimpleClientset(tc.objects...)
+			progress, err := getReleaseProgress(context.Background(), coreClient, "team-a", "db-mysql")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual := progress.String(); actual != tc.expected {
+				t.Errorf("expected %q, actual %q", tc.expected, actual)
+			}
+			if actual := progress.ratio(); actual != tc.ratio {
+				t.Errorf("expected ratio %v, actual %v", tc.ratio, actual)
+			}
+		})
+	}
+
+	claims := releaseProgress{claims: 3, boundClaims: 1}
+	if actual := claims.String(); actual != "1/3 PVCs bound" {
+		t.Errorf("expected the bound claims, actual %q", actual)
+	}
+}
+
+func TestProgressWatcher(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "minibroker"},
+		Data:       map[string]string{OperationDescriptionKey: `provisioning service instance "db"`},
+	}
+	objects := append(newProgressObjects(ReleaseLabel), config)
+	c := &Client{
+		namespace:        "minibroker",
+		coreClient:       fake.NewSimpleClientset(objects...),
+		progressInterval: 10 * time.Millisecond,
+	}
+
+	watcher := c.newProgressWatcher(context.Background(), &cluster{coreClient: c.coreClient}, "db", "mysql", "mysql-5-7")
+	watcher.watch("db-mysql", "team-a")
+	expected := `provisioning service instance "db": 2/3 pods ready, PVC bound`
+	var actual string
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		updated, err := c.getConfigMap(context.TODO(), "db")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if actual = updated.Data[OperationDescriptionKey]; actual == expected {
+			break
+		}
+	}
+	if actual != expected {
+		t.Errorf("expected the description %q, actual %q", expected, actual)
+	}
+	if ratio := testutil.ToFloat64(provisioningProgress.WithLabelValues("db", "mysql", "mysql-5-7")); ratio != 0.75 {
+		t.Errorf("expected the progress ratio 0.75, actual %v", ratio)
+	}
+
+	watcher.stop()
+	if err := testutil.CollectAndCompare(provisioningProgress, strings.NewReader("")); err != nil {
+		t.Errorf("expected the progress metric to be deleted: %v", err)
+	}
+}
@@ -31,6 +31,18 @@ type Provider interface {
 		provisionParams *ProvisionParams,
 		chartSecrets Object,
 	) (Object, error)
+	// SecretKeys returns the chart secret keys allowed in the binding credentials, besides the
+	// credentials returned by Bind: the keys Bind reads for the provisioning parameters, so the
+	// administrator password is not exposed to the bindings of another user.
+	SecretKeys(provisionParams *ProvisionParams) []string
+}
+
+// UserProvider is implemented by the providers able to manage a dedicated database user per
+// binding. The returned jobs run with the image of the release, using the credentials returned by
+// Provider.Bind and the chart secrets for connecting as an administrator.
+type UserProvider interface {
+	CreateUserJob(creds Object, chartSecrets Object, user *BindingUser) (*UserJob, error)
+	DropUserJob(creds Object, chartSecrets Object, user *BindingUser) (*UserJob, error)
 }
 
 // Object is a wrapper around map[string]interface{} that implements methods for helping with
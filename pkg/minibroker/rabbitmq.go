@@ -81,3 +81,10 @@ func (p RabbitmqProvider) Bind(
 
 	return creds, nil
 }
+
+// SecretKeys satisfies Provider.
+func (p RabbitmqProvider) SecretKeys(_ *ProvisionParams) []string {
+	return []string{
+		"rabbitmq-password",
+	}
+}
@@ -73,3 +73,10 @@ func (p RedisProvider) Bind(
 
 	return creds, nil
 }
+
+// SecretKeys satisfies Provider.
+func (p RedisProvider) SecretKeys(_ *ProvisionParams) []string {
+	return []string{
+		"redis-password",
+	}
+}
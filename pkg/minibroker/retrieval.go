@@ -0,0 +1,118 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/pkg/errors"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// FetchedInstance is a service instance as returned by the OSB instance fetch.
+type FetchedInstance struct {
+	ServiceID string
+	PlanID    string
+	// The parameters of the provisioning request, as stored; they are not redacted.
+	Parameters map[string]interface{}
+}
+
+// FetchInstance returns the service, plan and parameters of an instance. As the OSB spec requires,
+// an instance being provisioned is reported as missing, and one being deprovisioned with a
+// concurrency error.
+func (c *Client) FetchInstance(ctx context.Context, instanceID string) (*FetchedInstance, error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("fetching instance")
+
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+		}
+		return nil, errors.Wrapf(err, "failed to get service instance %q data", instanceID)
+	}
+	if err := instanceFetchable(config); err != nil {
+		return nil, err
+	}
+
+	var provisionParams *ProvisionParams
+	if err := json.Unmarshal([]byte(config.Data[ProvisionParamsKey]), &provisionParams); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshall provision parameters for instance %q", instanceID)
+	}
+	instance := &FetchedInstance{
+		ServiceID: config.Data[ServiceKey],
+		PlanID:    config.Data[PlanKey],
+	}
+	if provisionParams != nil {
+		instance.Parameters = provisionParams.Object
+	}
+
+	logger.V(3).Info("fetched instance")
+
+	return instance, nil
+}
+
+// instanceFetchable returns the OSB error for fetching an instance which is not provisioned: 404
+// while it is provisioned or when it failed to provision, 422 while it is deprovisioned.
+func instanceFetchable(config *corev1.ConfigMap) error {
+	state := config.Data[OperationStateKey]
+	provisioning := strings.HasPrefix(config.Data[OperationNameKey], OperationPrefixProvision)
+	switch {
+	case state == string(osb.StateInProgress) && provisioning,
+		config.Data[ReleaseLabel] == "" && state != string(osb.StateFailed):
+		return osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusNotFound,
+			Description: strPtr(fmt.Sprintf("service instance %q is being provisioned", config.Name)),
+		}
+	case config.Data[ReleaseLabel] == "":
+		return osb.HTTPStatusCodeError{
+			StatusCode:  http.StatusNotFound,
+			Description: strPtr(fmt.Sprintf("service instance %q failed to provision", config.Name)),
+		}
+	case state == string(osb.StateInProgress):
+		return osb.HTTPStatusCodeError{
+			StatusCode:   http.StatusUnprocessableEntity,
+			ErrorMessage: strPtr(ConcurrencyErrorMessage),
+			Description:  strPtr(fmt.Sprintf("service instance %q is being deprovisioned", config.Name)),
+		}
+	}
+	return nil
+}
+
+// bindingFetchable returns the OSB 404 error for fetching a binding whose binding operation has not
+// succeeded, including one still in progress.
+func bindingFetchable(config *corev1.ConfigMap, bindingID string) error {
+	var state osb.LastOperationResponse
+	stateJSON, ok := config.Data[BindingStateKeyPrefix+bindingID]
+	if ok {
+		if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+			return errors.Wrapf(err, "Error unmarshalling binding state %s", stateJSON)
+		}
+	}
+	if state.State != osb.StateSucceeded {
+		return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	return nil
+}
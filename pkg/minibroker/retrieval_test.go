@@ -0,0 +1,131 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestFetchInstance(t *testing.T) {
+	newConfig := func(name string, data map[string]string) *corev1.ConfigMap {
+		config := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "minibroker"},
+			Data: map[string]string{
+				ServiceKey:         "redis",
+				PlanKey:            "redis-5-0-7",
+				ProvisionParamsKey: `{"Object":{"password":"hunter2","replicas":2}}`,
+			},
+		}
+		for key, value := range data {
+			config.Data[key] = value
+		}
+		return config
+	}
+	coreClient := fake.NewSimpleClientset(
+		newConfig("ready", map[string]string{
+			ReleaseLabel:      "ready-release",
+			OperationNameKey:  "provision-1",
+			OperationStateKey: string(osb.StateSucceeded),
+		}),
+		newConfig("sync", map[string]string{
+			ReleaseLabel: "sync-release",
+		}),
+		newConfig("installing", map[string]string{}),
+		newConfig("waiting", map[string]string{
+			ReleaseLabel:      "waiting-release",
+			OperationNameKey:  "provision-1",
+			OperationStateKey: string(osb.StateInProgress),
+		}),
+		newConfig("failed", map[string]string{
+			OperationNameKey:  "provision-1",
+			OperationStateKey: string(osb.StateFailed),
+		}),
+		newConfig("deleting", map[string]string{
+			ReleaseLabel:      "deleting-release",
+			OperationNameKey:  "deprovision-1",
+			OperationStateKey: string(osb.StateInProgress),
+		}),
+	)
+	c := &Client{namespace: "minibroker", coreClient: coreClient}
+
+	expected := &FetchedInstance{
+		ServiceID:  "redis",
+		PlanID:     "redis-5-0-7",
+		Parameters: map[string]interface{}{"password": "hunter2", "replicas": float64(2)},
+	}
+	for _, instanceID := range []string{"ready", "sync"} {
+		instance, err := c.FetchInstance(context.TODO(), instanceID)
+		if err != nil {
+			t.Fatalf("FetchInstance(%q): unexpected error %v", instanceID, err)
+		}
+		if !reflect.DeepEqual(instance, expected) {
+			t.Errorf("FetchInstance(%q): expected %+v, actual %+v", instanceID, expected, instance)
+		}
+	}
+
+	tests := map[string]int{
+		"missing":    http.StatusNotFound,
+		"installing": http.StatusNotFound,
+		"waiting":    http.StatusNotFound,
+		"failed":     http.StatusNotFound,
+		"deleting":   http.StatusUnprocessableEntity,
+	}
+	for instanceID, statusCode := range tests {
+		_, err := c.FetchInstance(context.TODO(), instanceID)
+		httpErr, ok := osb.IsHTTPError(err)
+		if !ok || httpErr.StatusCode != statusCode {
+			t.Errorf("FetchInstance(%q): expected status %d, actual error %v", instanceID, statusCode, err)
+		}
+	}
+}
+
+func TestGetBindingInProgress(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "minibroker"},
+		Data: map[string]string{
+			ReleaseLabel:                     "db-release",
+			BindingKeyPrefix + "bound":       `{"credentials":{"user":"app"}}`,
+			BindingStateKeyPrefix + "bound":  `{"state":"succeeded"}`,
+			BindingKeyPrefix + "binding":     `{"credentials":{"user":"app"}}`,
+			BindingKeyPrefix + "failed":      `{"credentials":{"user":"app"}}`,
+			BindingStateKeyPrefix + "failed": `{"state":"failed"}`,
+		},
+	}
+	c := &Client{namespace: "minibroker", coreClient: fake.NewSimpleClientset(config)}
+
+	binding, err := c.GetBinding(context.TODO(), "db", "bound")
+	if err != nil {
+		t.Fatalf("GetBinding: unexpected error %v", err)
+	}
+	if binding.Credentials["user"] != "app" {
+		t.Errorf("GetBinding: unexpected binding %+v", binding)
+	}
+	for _, bindingID := range []string{"binding", "failed", "missing"} {
+		_, err := c.GetBinding(context.TODO(), "db", bindingID)
+		if httpErr, ok := osb.IsHTTPError(err); !ok || httpErr.StatusCode != http.StatusNotFound {
+			t.Errorf("GetBinding(%q): expected status 404, actual error %v", bindingID, err)
+		}
+	}
+}
@@ -0,0 +1,394 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/pkg/errors"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+const (
+	// RotateCredentialsAnnotation requests the rotation of all the binding credentials of an
+	// instance when set on the instance configmap. The annotation is removed once the rotation was
+	// attempted, so the bindings already rotated are not rotated again; the failures are reported
+	// with a ReasonCredentialsRotationFailed Event.
+	RotateCredentialsAnnotation = "minibroker.rotate-credentials"
+	// RetiredUserKeyPrefix is the instance configmap key prefix for the binding users replaced by a
+	// credential rotation that are still valid.
+	RetiredUserKeyPrefix = "binding-retired-user-"
+	// LastCredentialsRotationKey is the instance configmap key recording the bindings affected by
+	// the last credential rotation.
+	LastCredentialsRotationKey = "last-credentials-rotation"
+
+	// DefaultCredentialRotationGracePeriod is the default time a rotated credential stays valid.
+	DefaultCredentialRotationGracePeriod = time.Hour
+
+	credentialRotationCheckPeriod = time.Minute
+)
+
+// ErrRotationUnsupported is the error returned when rotating the credentials of a binding without
+// a dedicated database user.
+var ErrRotationUnsupported = fmt.Errorf("credential rotation requires per-binding users")
+
+// RetiredBindingUser is a binding user replaced by a credential rotation. It is dropped once the
+// grace period expires.
+type RetiredBindingUser struct {
+	BindingUser
+	BindingID string    `json:"bindingID"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// CredentialsRotation records the bindings affected by a credential rotation, so platforms can
+// refresh the credentials they hold.
+type CredentialsRotation struct {
+	Time     time.Time `json:"time"`
+	Bindings []string  `json:"bindings"`
+}
+
+// RotateBindingCredentials replaces the database user of a binding with a new one and updates the
+// stored binding response. The previous user remains valid for the grace period.
+func (c *Client) RotateBindingCredentials(ctx context.Context, instanceID, bindingID string) error {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID, "bindingID", bindingID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("rotating binding credentials")
+
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+		}
+		return err
+	}
+	if err := c.rotateBindingCredentials(ctx, config, bindingID); err != nil {
+		return err
+	}
+	if err := c.recordCredentialsRotation(ctx, instanceID, []string{bindingID}); err != nil {
+		return err
+	}
+
+	logger.V(3).Info("rotated binding credentials")
+	return nil
+}
+
+// RotateInstanceCredentials rotates the credentials of all the bindings of an instance that have a
+// dedicated database user. It returns the IDs of the rotated bindings; a binding failing to rotate
+// does not prevent the rotation of the others.
+func (c *Client) RotateInstanceCredentials(ctx context.Context, instanceID string) ([]string, error) {
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues("instanceID", instanceID))
+	logger := contextLogger(ctx)
+	logger.V(3).Info("rotating instance credentials")
+
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+		}
+		return nil, err
+	}
+
+	rotated := make([]string, 0)
+	failures := make([]string, 0)
+	for _, bindingID := range boundUserBindings(config) {
+		if err := c.rotateBindingCredentials(ctx, config, bindingID); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", bindingID, err))
+			continue
+		}
+		rotated = append(rotated, bindingID)
+	}
+	if len(rotated) > 0 {
+		if err := c.recordCredentialsRotation(ctx, instanceID, rotated); err != nil {
+			return rotated, err
+		}
+	}
+	if len(failures) > 0 {
+		return rotated, errors.Errorf("failed to rotate the credentials of bindings %s", strings.Join(failures, "; "))
+	}
+
+	logger.V(3).Info("rotated instance credentials", "bindings", rotated)
+	return rotated, nil
+}
+
+func (c *Client) rotateBindingCredentials(ctx context.Context, config *corev1.ConfigMap, bindingID string) error {
+	instanceID := config.Name
+
+	user, ok, err := currentBindingUser(config, bindingID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRotationUnsupported
+	}
+	serviceID := config.Data[ServiceKey]
+	userProvider, ok := c.userProvider(serviceID)
+	if !ok {
+		return ErrRotationUnsupported
+	}
+
+	bindingJSON, ok := config.Data[BindingKeyPrefix+bindingID]
+	if !ok {
+		return osb.HTTPStatusCodeError{StatusCode: http.StatusNotFound}
+	}
+	var binding *osb.GetBindingResponse
+	if err := json.Unmarshal([]byte(bindingJSON), &binding); err != nil {
+		return errors.Wrapf(err, "could not decode binding %q", bindingID)
+	}
+
+	var provisionParams *ProvisionParams
+	if err := json.Unmarshal([]byte(config.Data[ProvisionParamsKey]), &provisionParams); err != nil {
+		return errors.Wrapf(err, "could not unmarshall provision parameters for instance %q", instanceID)
+	}
+
+	target, err := c.instanceCluster(ctx, config)
+	if err != nil {
+		return err
+	}
+	releaseNamespace := config.Data[ReleaseNamespaceKey]
+	chartSecrets, creds, err := c.resolveCredentials(
+		ctx,
+		target,
+		instanceID,
+		serviceID,
+		releaseNamespace,
+		NewBindParams(binding.Parameters),
+		provisionParams,
+	)
+	if err != nil {
+		return err
+	}
+
+	newUser, err := newBindingUser(bindingID, user.Role, user.Generation+1)
+	if err != nil {
+		return err
+	}
+	job, err := userProvider.CreateUserJob(creds, chartSecrets, newUser)
+	if err != nil {
+		return errors.Wrapf(err, "unable to rotate the credentials of binding %q", bindingID)
+	}
+	if err := c.runUserJob(ctx, target, releaseNamespace, config.Data[ReleaseLabel], userJobName("create", newUser), job); err != nil {
+		return errors.Wrapf(err, "unable to rotate the credentials of binding %q", bindingID)
+	}
+
+	binding.Credentials, err = withBindingUser(binding.Credentials, newUser)
+	if err != nil {
+		return err
+	}
+	bindingJSONBytes, err := json.Marshal(binding)
+	if err != nil {
+		return err
+	}
+	newUserJSON, err := json.Marshal(newUser)
+	if err != nil {
+		return err
+	}
+	retired := RetiredBindingUser{
+		BindingUser: *user,
+		BindingID:   bindingID,
+		ExpiresAt:   time.Now().UTC().Add(c.rotationGracePeriod),
+	}
+	retiredJSON, err := json.Marshal(retired)
+	if err != nil {
+		return err
+	}
+
+	err = c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+		(BindingKeyPrefix + bindingID):                  string(bindingJSONBytes),
+		(BindingUserKeyPrefix + bindingID):              string(newUserJSON),
+		retiredBindingUserKey(bindingID, user.Username): string(retiredJSON),
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.updateBindingSecret(ctx, config, bindingID, binding.Credentials)
+}
+
+func (c *Client) recordCredentialsRotation(ctx context.Context, instanceID string, bindingIDs []string) error {
+	rotation := CredentialsRotation{
+		Time:     time.Now().UTC(),
+		Bindings: bindingIDs,
+	}
+	rotationJSON, err := json.Marshal(rotation)
+	if err != nil {
+		return err
+	}
+	contextLogger(ctx).V(2).Info("credentials rotated", "instanceID", instanceID, "bindings", bindingIDs)
+	return c.updateConfigMap(ctx, instanceID, map[string]interface{}{
+		LastCredentialsRotationKey: string(rotationJSON),
+	})
+}
+
+// RunCredentialRotation periodically processes the rotation requests set with the
+// RotateCredentialsAnnotation, rotates the binding users older than maxAge (when maxAge is not
+// zero) and drops the retired users whose grace period expired. It blocks until the context is
+// done.
+func (c *Client) RunCredentialRotation(ctx context.Context, maxAge time.Duration) {
+	logger := contextLogger(ctx)
+	logger.V(3).Info("starting the credential rotation loop")
+	ticker := time.NewTicker(credentialRotationCheckPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			logger.V(3).Info("stopped the credential rotation loop")
+			return
+		case <-ticker.C:
+			if err := c.reconcileCredentials(ctx, maxAge); err != nil {
+				logger.V(2).Info("failed to reconcile credentials", "error", err)
+			}
+		}
+	}
+}
+
+func (c *Client) reconcileCredentials(ctx context.Context, maxAge time.Duration) error {
+	isInstance, err := labels.NewRequirement(ServiceKey, selection.Exists, nil)
+	if err != nil {
+		return err
+	}
+	configs, err := c.coreClient.CoreV1().
+		ConfigMaps(c.namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: labels.NewSelector().Add(*isInstance).String()})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for i := range configs.Items {
+		config := &configs.Items[i]
+		instanceID := config.Name
+		logger := contextLogger(ctx).WithValues("instanceID", instanceID)
+
+		if _, ok := config.Annotations[RotateCredentialsAnnotation]; ok {
+			if rotated, err := c.RotateInstanceCredentials(ctx, instanceID); err != nil {
+				logger.V(2).Info("failed to rotate instance credentials", "rotated", rotated, "error", err)
+				c.recordEvent(config, config.Data[ReleaseNamespaceKey], corev1.EventTypeWarning, ReasonCredentialsRotationFailed,
+					"Failed to rotate the credentials of instance %q: %v", instanceID, err)
+			}
+			if err := c.removeRotationAnnotation(ctx, instanceID); err != nil {
+				logger.V(2).Info("failed to remove the rotation annotation", "error", err)
+			}
+		} else if maxAge > 0 {
+			rotated := make([]string, 0)
+			for _, bindingID := range boundUserBindings(config) {
+				user, _, err := currentBindingUser(config, bindingID)
+				if err != nil || user.CreatedAt.IsZero() || now.Sub(user.CreatedAt) < maxAge {
+					continue
+				}
+				if err := c.rotateBindingCredentials(ctx, config, bindingID); err != nil {
+					logger.V(2).Info("failed to rotate binding credentials", "bindingID", bindingID, "error", err)
+					continue
+				}
+				rotated = append(rotated, bindingID)
+			}
+			if len(rotated) > 0 {
+				if err := c.recordCredentialsRotation(ctx, instanceID, rotated); err != nil {
+					logger.V(2).Info("failed to record the credential rotation", "error", err)
+				}
+			}
+		}
+
+		// The rotations above update the configmap, so it is fetched again before looking for
+		// expired users.
+		config, err := c.getConfigMap(ctx, instanceID)
+		if err != nil {
+			continue
+		}
+		if err := c.dropExpiredUsers(ctx, config, now); err != nil {
+			logger.V(2).Info("failed to drop expired users", "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) dropExpiredUsers(ctx context.Context, config *corev1.ConfigMap, now time.Time) error {
+	retiredUsers, err := retiredBindingUsers(config, "")
+	if err != nil {
+		return err
+	}
+	for key, retired := range retiredUsers {
+		if now.Before(retired.ExpiresAt) {
+			continue
+		}
+		if err := c.dropUser(ctx, config, retired.BindingID, &retired.BindingUser); err != nil {
+			return err
+		}
+		if err := c.updateConfigMap(ctx, config.Name, map[string]interface{}{key: nil}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) removeRotationAnnotation(ctx context.Context, instanceID string) error {
+	config, err := c.getConfigMap(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+	delete(config.Annotations, RotateCredentialsAnnotation)
+	_, err = c.coreClient.CoreV1().ConfigMaps(c.namespace).Update(ctx, config, metav1.UpdateOptions{})
+	return err
+}
+
+// boundUserBindings returns the sorted IDs of the bindings with a dedicated database user.
+func boundUserBindings(config *corev1.ConfigMap) []string {
+	bindingIDs := make([]string, 0)
+	for key := range config.Data {
+		if strings.HasPrefix(key, BindingUserKeyPrefix) {
+			bindingIDs = append(bindingIDs, strings.TrimPrefix(key, BindingUserKeyPrefix))
+		}
+	}
+	sort.Strings(bindingIDs)
+	return bindingIDs
+}
+
+func retiredBindingUserKey(bindingID, username string) string {
+	return fmt.Sprintf("%s%s.%s", RetiredUserKeyPrefix, bindingID, username)
+}
+
+// retiredBindingUsers returns the retired users of a binding, indexed by their configmap key. All
+// the retired users of the instance are returned when bindingID is empty.
+func retiredBindingUsers(config *corev1.ConfigMap, bindingID string) (map[string]*RetiredBindingUser, error) {
+	retiredUsers := make(map[string]*RetiredBindingUser)
+	for key, value := range config.Data {
+		if !strings.HasPrefix(key, RetiredUserKeyPrefix) {
+			continue
+		}
+		var retired *RetiredBindingUser
+		if err := json.Unmarshal([]byte(value), &retired); err != nil {
+			return nil, errors.Wrapf(err, "could not unmarshall retired user %q", key)
+		}
+		if bindingID != "" && retired.BindingID != bindingID {
+			continue
+		}
+		retiredUsers[key] = retired
+	}
+	return retiredUsers, nil
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/helm"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestBoundUserBindings(t *testing.T) {
+	config := &corev1.ConfigMap{
+		Data: map[string]string{
+			BindingKeyPrefix + "b1":               "{}",
+			BindingStateKeyPrefix + "b1":          "{}",
+			BindingUserKeyPrefix + "b2":           "{}",
+			BindingUserKeyPrefix + "b1":           "{}",
+			retiredBindingUserKey("b1", "mbuser"): "{}",
+		},
+	}
+
+	actual := boundUserBindings(config)
+	expected := []string{"b1", "b2"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("boundUserBindings: expected %v, actual %v", expected, actual)
+	}
+}
+
+func TestRetiredBindingUsers(t *testing.T) {
+	config := &corev1.ConfigMap{
+		Data: map[string]string{
+			BindingUserKeyPrefix + "b1":           `{"username":"mbcurrent"}`,
+			retiredBindingUserKey("b1", "mbold1"): `{"username":"mbold1","bindingID":"b1"}`,
+			retiredBindingUserKey("b2", "mbold2"): `{"username":"mbold2","bindingID":"b2"}`,
+		},
+	}
+
+	all, err := retiredBindingUsers(config, "")
+	if err != nil {
+		t.Fatalf("retiredBindingUsers: unexpected error %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("retiredBindingUsers: expected 2 retired users, actual %d", len(all))
+	}
+
+	filtered, err := retiredBindingUsers(config, "b2")
+	if err != nil {
+		t.Fatalf("retiredBindingUsers: unexpected error %v", err)
+	}
+	retired, ok := filtered[retiredBindingUserKey("b2", "mbold2")]
+	if len(filtered) != 1 || !ok || retired.Username != "mbold2" {
+		t.Errorf("retiredBindingUsers: expected only mbold2, actual %v", filtered)
+	}
+}
+
+func TestRotateBindingCredentialsUnsupported(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "instance",
+			Namespace: "minibroker",
+		},
+		Data: map[string]string{
+			ServiceKey:             "redis",
+			BindingKeyPrefix + "b": "{}",
+		},
+	}
+	c := &Client{
+		namespace:       "minibroker",
+		coreClient:      fake.NewSimpleClientset(config),
+		perBindingUsers: true,
+		providers: map[string]Provider{
+			"redis": RedisProvider{},
+		},
+	}
+
+	if err := c.RotateBindingCredentials(context.TODO(), "instance", "b"); err != ErrRotationUnsupported {
+		t.Errorf("RotateBindingCredentials: expected err %v, actual err %v", ErrRotationUnsupported, err)
+	}
+}
+
+func TestReconcileCredentialsRotationFailure(t *testing.T) {
+	config := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "instance",
+			Namespace:   "minibroker",
+			Labels:      map[string]string{ServiceKey: "mysql"},
+			Annotations: map[string]string{RotateCredentialsAnnotation: "now"},
+		},
+		Data: map[string]string{
+			ServiceKey:                  "mysql",
+			ProvisionParamsKey:          `{"Object":{}}`,
+			ReleaseLabel:                "instance-release",
+			ReleaseNamespaceKey:         "apps",
+			BindingKeyPrefix + "b1":     `{"credentials":{}}`,
+			BindingUserKeyPrefix + "b1": `{"username":"mbuser1","role":"readwrite"}`,
+			BindingKeyPrefix + "b2":     `{"credentials":{}}`,
+			BindingUserKeyPrefix + "b2": `{"username":"mbuser2","role":"readwrite"}`,
+		},
+	}
+	recorder := record.NewFakeRecorder(10)
+	c := &Client{
+		namespace:       "minibroker",
+		coreClient:      fake.NewSimpleClientset(config),
+		helm:            helm.NewDefaultClient(),
+		perBindingUsers: true,
+		providers:       map[string]Provider{"mysql": MySQLProvider{}},
+		recorder:        recorder,
+	}
+
+	// Without the release services, none of the bindings can be rotated.
+	if err := c.reconcileCredentials(context.Background(), 0); err != nil {
+		t.Fatalf("reconcileCredentials: unexpected error %v", err)
+	}
+
+	updated, err := c.getConfigMap(context.TODO(), "instance")
+	if err != nil {
+		t.Fatalf("getConfigMap: unexpected error %v", err)
+	}
+	if _, ok := updated.Annotations[RotateCredentialsAnnotation]; ok {
+		t.Errorf("reconcileCredentials: expected the rotation annotation to be removed")
+	}
+	if _, ok := updated.Data[LastCredentialsRotationKey]; ok {
+		t.Errorf("reconcileCredentials: expected no rotation to be recorded")
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning "+ReasonCredentialsRotationFailed) || !strings.Contains(event, "b1: ") || !strings.Contains(event, "b2: ") {
+			t.Errorf("reconcileCredentials: unexpected event %q", event)
+		}
+	default:
+		t.Errorf("reconcileCredentials: expected a %s event", ReasonCredentialsRotationFailed)
+	}
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package minibroker
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+)
+
+// BindingRole is the role granted to the dedicated database user of a binding.
+type BindingRole string
+
+// The roles accepted by the "role" binding parameter.
+const (
+	BindingRoleReadWrite BindingRole = "readwrite"
+	BindingRoleReadOnly  BindingRole = "readonly"
+	BindingRoleAdmin     BindingRole = "admin"
+)
+
+const (
+	// BindingRoleParam is the binding parameter used for selecting the role of the binding user.
+	BindingRoleParam = "role"
+	// BindingUserKeyPrefix is the instance configmap key prefix for the binding user records.
+	BindingUserKeyPrefix = "binding-user-"
+	// UserJobLabel labels the Jobs and Secrets used for managing binding users.
+	UserJobLabel = "minibroker.user-job"
+
+	defaultUserJobTimeout  = 2 * time.Minute
+	userJobPollInterval    = 2 * time.Second
+	userJobBackoffLimit    = 2
+	userJobNameSuffixLen   = 5
+	bindingUsernamePrefix  = "mb"
+	bindingUsernameHashLen = 12
+	bindingPasswordBytes   = 16
+)
+
+// Environment variables exposed to the user management Jobs.
+const (
+	userJobEnvHost          = "MB_HOST"
+	userJobEnvPort          = "MB_PORT"
+	userJobEnvDatabase      = "MB_DATABASE"
+	userJobEnvAdminUser     = "MB_ADMIN_USER"
+	userJobEnvAdminPassword = "MB_ADMIN_PASSWORD"
+	userJobEnvScript        = "MB_SCRIPT"
+)
+
+// BindingUser is a dedicated database user created for a single binding. Each credential rotation
+// creates a new user with the next generation.
+type BindingUser struct {
+	Username   string      `json:"username"`
+	Password   string      `json:"-"`
+	Role       BindingRole `json:"role"`
+	Generation int         `json:"generation,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt,omitempty"`
+}
+
+// UserJob describes the command a short-lived Job runs for creating or dropping a binding user.
+type UserJob struct {
+	Command []string
+	Env     map[string]string
+	// ClientImages are the names, without registry and tag, of the database images carrying the
+	// client run by the Command. The Job runs with the image of the release container with one of
+	// these names or images, rather than one of a sidecar.
+	ClientImages []string
+}
+
+// bindingRole extracts the binding role from the binding parameters. The returned bool is false
+// when the parameter is absent.
+func bindingRole(bindParams *BindParams) (BindingRole, bool, error) {
+	if bindParams == nil {
+		return "", false, nil
+	}
+	role, err := bindParams.DigString(BindingRoleParam)
+	if err == ErrDigNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("invalid %q parameter: %w", BindingRoleParam, err)
+	}
+	switch BindingRole(role) {
+	case BindingRoleReadWrite, BindingRoleReadOnly, BindingRoleAdmin:
+		return BindingRole(role), true, nil
+	default:
+		return "", false, fmt.Errorf(
+			"invalid %q parameter %q: must be one of %q, %q or %q",
+			BindingRoleParam, role, BindingRoleReadWrite, BindingRoleReadOnly, BindingRoleAdmin)
+	}
+}
+
+// newBindingUser creates a BindingUser with a name derived from the binding ID and the generation,
+// and a random password.
+func newBindingUser(bindingID string, role BindingRole, generation int) (*BindingUser, error) {
+	password, err := randomPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create binding user: %w", err)
+	}
+	return &BindingUser{
+		Username:   bindingUsername(bindingID, generation),
+		Password:   password,
+		Role:       role,
+		Generation: generation,
+		CreatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// bindingUsername derives a short, valid database username from the binding ID and the user
+// generation. Binding IDs are usually UUIDs, which are too long for some databases (e.g. MySQL 5.6
+// caps usernames at 16 characters).
+func bindingUsername(bindingID string, generation int) string {
+	seed := bindingID
+	if generation > 0 {
+		seed = fmt.Sprintf("%s/%d", bindingID, generation)
+	}
+	sum := sha256.Sum256([]byte(seed))
+	return bindingUsernamePrefix + hex.EncodeToString(sum[:])[:bindingUsernameHashLen]
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, bindingPasswordBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withBindingUser returns a copy of the credentials using the binding user instead of the
+// credentials read from the chart secrets.
+func withBindingUser(creds Object, user *BindingUser) (Object, error) {
+	result := make(Object, len(creds))
+	for k, v := range creds {
+		result[k] = v
+	}
+	result["username"] = user.Username
+	result["password"] = user.Password
+
+	if rawURI, ok := creds["uri"].(string); ok {
+		uri, err := url.Parse(rawURI)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set binding user: %w", err)
+		}
+		uri.User = url.UserPassword(user.Username, user.Password)
+		result["uri"] = uri.String()
+	}
+
+	return result, nil
+}
+
+// userJobEnv builds the common environment for the user management Jobs from the provider
+// credentials.
+func userJobEnv(creds Object, adminUser, adminPassword, script string) (map[string]string, error) {
+	host, err := creds.DigString("host")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get host: %w", err)
+	}
+	port, ok := creds.Dig("port")
+	if !ok {
+		return nil, fmt.Errorf("failed to get port: %w", ErrDigNotFound)
+	}
+	database, err := creds.DigStringOr("database", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get database name: %w", err)
+	}
+	return map[string]string{
+		userJobEnvHost:          host,
+		userJobEnvPort:          fmt.Sprintf("%v", port),
+		userJobEnvDatabase:      database,
+		userJobEnvAdminUser:     adminUser,
+		userJobEnvAdminPassword: adminPassword,
+		userJobEnvScript:        script,
+	}, nil
+}
+
+// quoteSQLString quotes a SQL string literal.
+func quoteSQLString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// quotePostgresIdent quotes a PostgreSQL identifier.
+func quotePostgresIdent(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// quoteMySQLIdent quotes a MySQL or MariaDB identifier.
+func quoteMySQLIdent(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
+
+// quoteJSString quotes a JavaScript string literal for the mongo shell.
+func quoteJSString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// userJobName builds the name of the Job and Secret used for managing the binding user. The random
+// suffix lets a retried bind run a new Job while the previous one is still being deleted.
+func userJobName(action string, user *BindingUser) string {
+	return fmt.Sprintf("minibroker-%s-%s-%s", action, user.Username, utilrand.String(userJobNameSuffixLen))
+}
+
+// runUserJob runs the user management Job in the release namespace of the target cluster and waits
+// for it to complete. The Job reuses the image of the release pods so the database client matches
+// the server.
+func (c *Client) runUserJob(ctx context.Context, target *cluster, namespace, release, name string, job *UserJob) error {
+	coreClient := target.coreClient
+
+	image, err := releaseImage(ctx, coreClient, namespace, release, job.ClientImages)
+	if err != nil {
+		return fmt.Errorf("failed to run user job %s/%s: %w", namespace, name, err)
+	}
+
+	objectLabels := map[string]string{
+		UserJobLabel: name,
+		ReleaseLabel: release,
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    objectLabels,
+		},
+		StringData: job.Env,
+	}
+	if _, err := coreClient.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to run user job %s/%s: %w", namespace, name, err)
+	}
+	defer func() {
+		err := coreClient.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil {
+			contextLogger(ctx).V(2).Info("failed to delete user job secret", "namespace", namespace, "secret", name, "error", err)
+		}
+	}()
+
+	backoffLimit := int32(userJobBackoffLimit)
+	batchJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels:    objectLabels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{UserJobLabel: name},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{{
+						Name:    "user",
+						Image:   image,
+						Command: job.Command,
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: name},
+							},
+						}},
+					}},
+				},
+			},
+		},
+	}
+	if _, err := coreClient.BatchV1().Jobs(namespace).Create(ctx, batchJob, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to run user job %s/%s: %w", namespace, name, err)
+	}
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		err := coreClient.BatchV1().
+			Jobs(namespace).
+			Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil {
+			contextLogger(ctx).V(2).Info("failed to delete user job", "namespace", namespace, "job", name, "error", err)
+		}
+	}()
+
+	contextLogger(ctx).V(4).Info("waiting for user job", "namespace", namespace, "job", name)
+	err = wait.PollImmediate(userJobPollInterval, c.userJobTimeout, func() (bool, error) {
+		current, err := coreClient.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range current.Status.Conditions {
+			if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+				return false, errors.Errorf("job failed: %s", condition.Message)
+			}
+		}
+		return current.Status.Succeeded > 0, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to run user job %s/%s: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+// releaseImage returns the image of the database container of a release pod: the first container
+// whose name or image name is one of the client images.
+func releaseImage(ctx context.Context, coreClient kubernetes.Interface, namespace, release string, clientImages []string) (string, error) {
+	for _, selector := range releaseSelectors(release) {
+		pods, err := coreClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return "", err
+		}
+		for _, pod := range pods.Items {
+			for _, container := range pod.Spec.Containers {
+				if containsString(clientImages, container.Name) || containsString(clientImages, imageName(container.Image)) {
+					return container.Image, nil
+				}
+			}
+		}
+	}
+	return "", errors.Errorf("no database container %v found in the pods of release %q", clientImages, release)
+}
+
+// imageName returns the name of an image reference without its registry, repository path, tag and
+// digest, e.g. "mysql" for "docker.io/bitnami/mysql:8.0.21".
+func imageName(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		image = image[i+1:]
+	}
+	if i := strings.Index(image, ":"); i >= 0 {
+		image = image[:i]
+	}
+	return image
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,606 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/log"
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// ClaimLabel labels the Secrets written for the ServiceClaims with the claim name.
+	ClaimLabel = "minibroker.io/claim"
+
+	defaultPollInterval = 10 * time.Second
+	resyncPeriod        = 10 * time.Minute
+)
+
+// Client is the subset of the Minibroker client driven by the controller.
+type Client interface {
+	Provision(ctx context.Context, request *minibroker.ProvisionRequest) (string, error)
+	Deprovision(ctx context.Context, instanceID string, acceptsIncomplete bool) (string, error)
+	LastOperationState(ctx context.Context, instanceID string, operationKey *osb.OperationKey) (*osb.LastOperationResponse, error)
+	Bind(ctx context.Context, request *minibroker.BindRequest) (string, error)
+	Unbind(ctx context.Context, instanceID, bindingID string) error
+	GetBinding(ctx context.Context, instanceID, bindingID string) (*osb.GetBindingResponse, error)
+	LastBindingOperationState(ctx context.Context, instanceID, bindingID string) (*osb.LastOperationResponse, error)
+}
+
+// Controller reconciles the ManagedService and ServiceClaim resources using the Minibroker client.
+// The instance and binding IDs are the UIDs of the resources.
+type Controller struct {
+	client        Client
+	dynamicClient dynamic.Interface
+	coreClient    kubernetes.Interface
+	queue         workqueue.RateLimitingInterface
+	pollInterval  time.Duration
+}
+
+// queueKey identifies a resource in the work queue.
+type queueKey struct {
+	resource  schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// NewController creates a new Controller.
+func NewController(client Client, dynamicClient dynamic.Interface, coreClient kubernetes.Interface) *Controller {
+	return &Controller{
+		client:        client,
+		dynamicClient: dynamicClient,
+		coreClient:    coreClient,
+		queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		pollInterval:  defaultPollInterval,
+	}
+}
+
+// Run watches the custom resources and reconciles them with the given number of workers until the
+// context is done.
+func (c *Controller) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	logger := log.FromContext(ctx).WithName("operator")
+	logger.V(1).Info("starting the controller")
+
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(c.dynamicClient, resyncPeriod)
+	for _, resource := range []schema.GroupVersionResource{ManagedServiceResource, ServiceClaimResource} {
+		resource := resource
+		enqueue := func(obj interface{}) {
+			key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			namespace, name, err := cache.SplitMetaNamespaceKey(key)
+			if err != nil {
+				utilruntime.HandleError(err)
+				return
+			}
+			c.queue.Add(queueKey{resource: resource, namespace: namespace, name: name})
+		}
+		factory.ForResource(resource).Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueue,
+			UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+			DeleteFunc: enqueue,
+		})
+	}
+	factory.Start(ctx.Done())
+	for resource, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("failed to start the controller: could not sync the %s cache", resource.Resource)
+		}
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(func() { c.runWorker(ctx) }, time.Second, ctx.Done())
+	}
+
+	<-ctx.Done()
+	logger.V(1).Info("stopping the controller")
+	return ctx.Err()
+}
+
+func (c *Controller) runWorker(ctx context.Context) {
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	item, shutdown := c.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer c.queue.Done(item)
+
+	key := item.(queueKey)
+	// Each reconciliation gets its own correlation ID, carried by the logs of the client calls.
+	ctx = log.WithCorrelationID(ctx, log.NewCorrelationID())
+	ctx = log.IntoContext(ctx, log.FromContext(ctx).WithValues(
+		"resource", key.resource.Resource, "namespace", key.namespace, "name", key.name))
+	requeueAfter, err := c.reconcile(ctx, key)
+	if err != nil {
+		log.FromContext(ctx).WithName("operator").V(2).Info("failed to reconcile", "error", err)
+		c.queue.AddRateLimited(item)
+		return true
+	}
+	c.queue.Forget(item)
+	if requeueAfter > 0 {
+		c.queue.AddAfter(item, requeueAfter)
+	}
+	return true
+}
+
+// reconcile reconciles a resource, returning when it should be reconciled again while an
+// operation is in progress.
+func (c *Controller) reconcile(ctx context.Context, key queueKey) (time.Duration, error) {
+	obj, err := c.dynamicClient.Resource(key.resource).Namespace(key.namespace).Get(ctx, key.name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	switch key.resource {
+	case ManagedServiceResource:
+		return c.reconcileManagedService(ctx, obj)
+	case ServiceClaimResource:
+		return c.reconcileServiceClaim(ctx, obj)
+	default:
+		return 0, fmt.Errorf("unknown resource %s", key.resource)
+	}
+}
+
+func (c *Controller) reconcileManagedService(ctx context.Context, obj *unstructured.Unstructured) (time.Duration, error) {
+	var ms ManagedService
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &ms); err != nil {
+		return 0, fmt.Errorf("failed to decode managed service %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	status := ms.Status
+
+	if ms.DeletionTimestamp != nil {
+		return c.deprovision(ctx, obj, &ms)
+	}
+
+	obj, err := c.ensureFinalizer(ctx, ManagedServiceResource, obj)
+	if err != nil {
+		return 0, err
+	}
+
+	if status.InstanceID == "" {
+		instanceID := string(ms.UID)
+		log.FromContext(ctx).WithName("operator").V(3).Info("provisioning managed service", "instanceID", instanceID)
+		operation, err := c.client.Provision(ctx, &minibroker.ProvisionRequest{
+			InstanceID: instanceID,
+			ServiceID:  ms.Spec.Service,
+			PlanID:     ms.Spec.Plan,
+			Namespace:  ms.Namespace,
+			Cluster:    ms.Spec.Cluster,
+			Context: map[string]interface{}{
+				"platform":  "kubernetes",
+				"namespace": ms.Namespace,
+			},
+			AcceptsIncomplete: true,
+			Params:            minibroker.NewProvisionParams(ms.Spec.Parameters),
+		})
+		// A conflict means the instance was created, but recording it in the status failed.
+		if err != nil && !osb.IsConflictError(err) {
+			status.Conditions = setCondition(status.Conditions, Condition{
+				Type:    ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ProvisionFailed",
+				Message: err.Error(),
+			})
+			return c.retryOnServerError(err, c.updateStatus(ctx, ManagedServiceResource, obj, &status))
+		}
+		status.InstanceID = instanceID
+		status.Operation = operation
+		status.Conditions = setCondition(status.Conditions, Condition{
+			Type:    ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Provisioning",
+			Message: fmt.Sprintf("provisioning service instance %q", instanceID),
+		})
+		return c.pollInterval, c.updateStatus(ctx, ManagedServiceResource, obj, &status)
+	}
+
+	var requeueAfter time.Duration
+	condition := Condition{Type: ConditionReady}
+	state, err := c.client.LastOperationState(ctx, status.InstanceID, nil)
+	switch {
+	case osb.IsGoneError(err):
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "InstanceGone"
+		condition.Message = fmt.Sprintf("service instance %q no longer exists", status.InstanceID)
+	case err != nil:
+		return 0, err
+	case state.State == osb.StateInProgress:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "Provisioning"
+		condition.Message = operationDescription(state)
+		requeueAfter = c.pollInterval
+	case state.State == osb.StateFailed:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "ProvisionFailed"
+		condition.Message = operationDescription(state)
+	default:
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "Provisioned"
+		condition.Message = operationDescription(state)
+	}
+	status.Conditions = setCondition(status.Conditions, condition)
+	return requeueAfter, c.updateStatus(ctx, ManagedServiceResource, obj, &status)
+}
+
+// deprovision deprovisions the instance of a deleted ManagedService, removing the finalizer once
+// the instance is gone.
+func (c *Controller) deprovision(ctx context.Context, obj *unstructured.Unstructured, ms *ManagedService) (time.Duration, error) {
+	if !hasFinalizer(obj) {
+		return 0, nil
+	}
+
+	status := ms.Status
+	if status.InstanceID != "" {
+		state, err := c.client.LastOperationState(ctx, status.InstanceID, nil)
+		switch {
+		case osb.IsGoneError(err):
+			// The instance was deprovisioned.
+		case err != nil:
+			return 0, err
+		case state.State == osb.StateInProgress:
+			// Wait for the provisioning or the deprovisioning to finish.
+			return c.pollInterval, nil
+		default:
+			log.FromContext(ctx).WithName("operator").V(3).Info("deprovisioning managed service", "instanceID", status.InstanceID)
+			operation, err := c.client.Deprovision(ctx, status.InstanceID, true)
+			if err != nil && !osb.IsGoneError(err) {
+				return 0, err
+			}
+			if err == nil {
+				status.Operation = operation
+				status.Conditions = setCondition(status.Conditions, Condition{
+					Type:    ConditionReady,
+					Status:  corev1.ConditionFalse,
+					Reason:  "Deprovisioning",
+					Message: fmt.Sprintf("deprovisioning service instance %q", status.InstanceID),
+				})
+				return c.pollInterval, c.updateStatus(ctx, ManagedServiceResource, obj, &status)
+			}
+		}
+	}
+
+	return 0, c.removeFinalizer(ctx, ManagedServiceResource, obj)
+}
+
+func (c *Controller) reconcileServiceClaim(ctx context.Context, obj *unstructured.Unstructured) (time.Duration, error) {
+	var claim ServiceClaim
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &claim); err != nil {
+		return 0, fmt.Errorf("failed to decode service claim %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	status := claim.Status
+
+	if claim.DeletionTimestamp != nil {
+		return 0, c.unbind(ctx, obj, &claim)
+	}
+
+	obj, err := c.ensureFinalizer(ctx, ServiceClaimResource, obj)
+	if err != nil {
+		return 0, err
+	}
+
+	if status.BindingID == "" {
+		ms, err := c.managedService(ctx, claim.Namespace, claim.Spec.ServiceName)
+		if err != nil {
+			return 0, err
+		}
+		if ms == nil || !isReady(ms.Status.Conditions) {
+			status.Conditions = setCondition(status.Conditions, Condition{
+				Type:    ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  "ServiceNotReady",
+				Message: fmt.Sprintf("managed service %q is not ready", claim.Spec.ServiceName),
+			})
+			return c.pollInterval, c.updateStatus(ctx, ServiceClaimResource, obj, &status)
+		}
+
+		bindingID := string(claim.UID)
+		log.FromContext(ctx).WithName("operator").V(3).Info("binding service claim", "bindingID", bindingID)
+		_, err = c.client.Bind(ctx, &minibroker.BindRequest{
+			InstanceID:        ms.Status.InstanceID,
+			ServiceID:         ms.Spec.Service,
+			BindingID:         bindingID,
+			Namespace:         claim.Namespace,
+			AcceptsIncomplete: true,
+			Params:            minibroker.NewBindParams(claim.Spec.Parameters),
+		})
+		if err != nil && !osb.IsConflictError(err) {
+			status.Conditions = setCondition(status.Conditions, Condition{
+				Type:    ConditionReady,
+				Status:  corev1.ConditionFalse,
+				Reason:  "BindFailed",
+				Message: err.Error(),
+			})
+			return c.retryOnServerError(err, c.updateStatus(ctx, ServiceClaimResource, obj, &status))
+		}
+		status.InstanceID = ms.Status.InstanceID
+		status.BindingID = bindingID
+		status.Conditions = setCondition(status.Conditions, Condition{
+			Type:    ConditionReady,
+			Status:  corev1.ConditionFalse,
+			Reason:  "Binding",
+			Message: fmt.Sprintf("binding service instance %q", ms.Status.InstanceID),
+		})
+		return c.pollInterval, c.updateStatus(ctx, ServiceClaimResource, obj, &status)
+	}
+
+	var requeueAfter time.Duration
+	condition := Condition{Type: ConditionReady}
+	state, err := c.client.LastBindingOperationState(ctx, status.InstanceID, status.BindingID)
+	switch {
+	case osb.IsGoneError(err):
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "BindingGone"
+		condition.Message = fmt.Sprintf("binding %q no longer exists", status.BindingID)
+	case err != nil:
+		return 0, err
+	case state.State == osb.StateInProgress:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "Binding"
+		condition.Message = operationDescription(state)
+		requeueAfter = c.pollInterval
+	case state.State == osb.StateFailed:
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "BindFailed"
+		condition.Message = operationDescription(state)
+	default:
+		// The Secret is rewritten on every reconciliation, so rotated credentials are picked up on
+		// the periodic resync.
+		binding, err := c.client.GetBinding(ctx, status.InstanceID, status.BindingID)
+		if err != nil {
+			return 0, err
+		}
+		secretName := claim.Spec.SecretName
+		if secretName == "" {
+			secretName = claim.Name
+		}
+		if err := c.writeSecret(ctx, &claim, secretName, binding.Credentials); err != nil {
+			condition.Status = corev1.ConditionFalse
+			condition.Reason = "SecretFailed"
+			condition.Message = err.Error()
+			requeueAfter = c.pollInterval
+			break
+		}
+		status.SecretName = secretName
+		condition.Status = corev1.ConditionTrue
+		condition.Reason = "Bound"
+		condition.Message = fmt.Sprintf("credentials written to secret %q", secretName)
+	}
+	status.Conditions = setCondition(status.Conditions, condition)
+	return requeueAfter, c.updateStatus(ctx, ServiceClaimResource, obj, &status)
+}
+
+// unbind unbinds a deleted ServiceClaim and deletes its Secret before removing the finalizer.
+func (c *Controller) unbind(ctx context.Context, obj *unstructured.Unstructured, claim *ServiceClaim) error {
+	if !hasFinalizer(obj) {
+		return nil
+	}
+
+	status := claim.Status
+	if status.BindingID != "" {
+		log.FromContext(ctx).WithName("operator").V(3).Info("unbinding service claim", "bindingID", status.BindingID)
+		if err := c.client.Unbind(ctx, status.InstanceID, status.BindingID); err != nil && !osb.IsGoneError(err) {
+			return err
+		}
+	}
+	if status.SecretName != "" {
+		err := c.coreClient.CoreV1().
+			Secrets(claim.Namespace).
+			Delete(ctx, status.SecretName, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete secret %s/%s: %w", claim.Namespace, status.SecretName, err)
+		}
+	}
+
+	return c.removeFinalizer(ctx, ServiceClaimResource, obj)
+}
+
+// managedService gets a ManagedService, returning nil if it does not exist.
+func (c *Controller) managedService(ctx context.Context, namespace, name string) (*ManagedService, error) {
+	obj, err := c.dynamicClient.Resource(ManagedServiceResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ms ManagedService
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &ms); err != nil {
+		return nil, fmt.Errorf("failed to decode managed service %s/%s: %w", namespace, name, err)
+	}
+	return &ms, nil
+}
+
+// writeSecret creates or updates the Secret holding the credentials of a claim. The Secret is owned
+// by the claim.
+func (c *Controller) writeSecret(ctx context.Context, claim *ServiceClaim, name string, creds map[string]interface{}) error {
+	data, err := secretData(creds)
+	if err != nil {
+		return err
+	}
+	owner := metav1.NewControllerRef(claim, schema.GroupVersionKind{Group: Group, Version: Version, Kind: "ServiceClaim"})
+
+	secretsInterface := c.coreClient.CoreV1().Secrets(claim.Namespace)
+	secret, err := secretsInterface.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       claim.Namespace,
+				Labels:          map[string]string{ClaimLabel: claim.Name},
+				OwnerReferences: []metav1.OwnerReference{*owner},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}
+		if _, err := secretsInterface.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create secret %s/%s: %w", claim.Namespace, name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get secret %s/%s: %w", claim.Namespace, name, err)
+	}
+
+	if !metav1.IsControlledBy(secret, claim) {
+		return fmt.Errorf("secret %s/%s exists and is not owned by the claim", claim.Namespace, name)
+	}
+	if reflect.DeepEqual(secret.Data, data) {
+		return nil
+	}
+	secret.Data = data
+	if _, err := secretsInterface.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update secret %s/%s: %w", claim.Namespace, name, err)
+	}
+	return nil
+}
+
+// secretData converts the binding credentials into Secret data. Non-string values are encoded as
+// JSON.
+func secretData(creds map[string]interface{}) (map[string][]byte, error) {
+	data := make(map[string][]byte, len(creds))
+	for k, v := range creds {
+		if str, ok := v.(string); ok {
+			data[k] = []byte(str)
+			continue
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode credential %q: %w", k, err)
+		}
+		data[k] = value
+	}
+	return data, nil
+}
+
+// updateStatus updates the status subresource, skipping the update when nothing changed so the
+// update events do not trigger endless reconciliations.
+func (c *Controller) updateStatus(
+	ctx context.Context,
+	resource schema.GroupVersionResource,
+	obj *unstructured.Unstructured,
+	status interface{},
+) error {
+	statusObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(obj.Object["status"], statusObj) {
+		return nil
+	}
+
+	obj = obj.DeepCopy()
+	obj.Object["status"] = statusObj
+	_, err = c.dynamicClient.Resource(resource).Namespace(obj.GetNamespace()).UpdateStatus(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update the status of %s %s/%s: %w", resource.Resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+// retryOnServerError returns the Minibroker client error for a rate-limited retry, unless it is a
+// client error such as invalid parameters, which are only reported in the status.
+func (c *Controller) retryOnServerError(err, statusErr error) (time.Duration, error) {
+	if statusErr != nil {
+		return 0, statusErr
+	}
+	if httpErr, ok := osb.IsHTTPError(err); ok && httpErr.StatusCode < http.StatusInternalServerError {
+		return 0, nil
+	}
+	return 0, err
+}
+
+func hasFinalizer(obj *unstructured.Unstructured) bool {
+	for _, finalizer := range obj.GetFinalizers() {
+		if finalizer == Finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) ensureFinalizer(
+	ctx context.Context,
+	resource schema.GroupVersionResource,
+	obj *unstructured.Unstructured,
+) (*unstructured.Unstructured, error) {
+	if hasFinalizer(obj) {
+		return obj, nil
+	}
+	obj = obj.DeepCopy()
+	obj.SetFinalizers(append(obj.GetFinalizers(), Finalizer))
+	updated, err := c.dynamicClient.Resource(resource).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add the finalizer to %s %s/%s: %w", resource.Resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return updated, nil
+}
+
+func (c *Controller) removeFinalizer(ctx context.Context, resource schema.GroupVersionResource, obj *unstructured.Unstructured) error {
+	finalizers := make([]string, 0)
+	for _, finalizer := range obj.GetFinalizers() {
+		if finalizer != Finalizer {
+			finalizers = append(finalizers, finalizer)
+		}
+	}
+	obj = obj.DeepCopy()
+	obj.SetFinalizers(finalizers)
+	_, err := c.dynamicClient.Resource(resource).Namespace(obj.GetNamespace()).Update(ctx, obj, metav1.UpdateOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to remove the finalizer from %s %s/%s: %w", resource.Resource, obj.GetNamespace(), obj.GetName(), err)
+	}
+	return nil
+}
+
+func operationDescription(state *osb.LastOperationResponse) string {
+	if state.Description == nil {
+		return ""
+	}
+	return strings.TrimSpace(*state.Description)
+}
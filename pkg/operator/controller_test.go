@@ -0,0 +1,182 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kubernetes-sigs/minibroker/pkg/minibroker"
+	osb "github.com/pmorie/go-open-service-broker-client/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeClient is a Minibroker client completing every operation immediately.
+type fakeClient struct {
+	instances map[string]string
+	bindings  map[string]map[string]interface{}
+}
+
+func (f *fakeClient) Provision(_ context.Context, request *minibroker.ProvisionRequest) (string, error) {
+	f.instances[request.InstanceID] = request.ServiceID
+	return "provision-operation", nil
+}
+
+func (f *fakeClient) Deprovision(_ context.Context, instanceID string, acceptsIncomplete bool) (string, error) {
+	delete(f.instances, instanceID)
+	return "deprovision-operation", nil
+}
+
+func (f *fakeClient) LastOperationState(_ context.Context, instanceID string, operationKey *osb.OperationKey) (*osb.LastOperationResponse, error) {
+	if _, ok := f.instances[instanceID]; !ok {
+		return nil, osb.HTTPStatusCodeError{StatusCode: 410}
+	}
+	return &osb.LastOperationResponse{State: osb.StateSucceeded}, nil
+}
+
+func (f *fakeClient) Bind(_ context.Context, request *minibroker.BindRequest) (string, error) {
+	f.bindings[request.BindingID] = map[string]interface{}{"host": "db", "port": 5432}
+	return "bind-operation", nil
+}
+
+func (f *fakeClient) Unbind(_ context.Context, instanceID, bindingID string) error {
+	delete(f.bindings, bindingID)
+	return nil
+}
+
+func (f *fakeClient) GetBinding(_ context.Context, instanceID, bindingID string) (*osb.GetBindingResponse, error) {
+	return &osb.GetBindingResponse{Credentials: f.bindings[bindingID]}, nil
+}
+
+func (f *fakeClient) LastBindingOperationState(_ context.Context, instanceID, bindingID string) (*osb.LastOperationResponse, error) {
+	if _, ok := f.bindings[bindingID]; !ok {
+		return nil, osb.HTTPStatusCodeError{StatusCode: 410}
+	}
+	return &osb.LastOperationResponse{State: osb.StateSucceeded}, nil
+}
+
+func newResource(kind, name, uid string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": Group + "/" + Version,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "apps",
+			"uid":       uid,
+		},
+		"spec": spec,
+	}}
+}
+
+func TestController(t *testing.T) {
+	ctx := context.TODO()
+	client := &fakeClient{instances: map[string]string{}, bindings: map[string]map[string]interface{}{}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		newResource("ManagedService", "db", "instance", map[string]interface{}{
+			"service": "postgresql",
+			"plan":    "postgresql-11-7-0",
+		}),
+		newResource("ServiceClaim", "app", "binding", map[string]interface{}{
+			"serviceName": "db",
+		}),
+	)
+	coreClient := fake.NewSimpleClientset()
+	c := NewController(client, dynamicClient, coreClient)
+
+	msKey := queueKey{resource: ManagedServiceResource, namespace: "apps", name: "db"}
+	claimKey := queueKey{resource: ServiceClaimResource, namespace: "apps", name: "app"}
+	reconcile := func(key queueKey) {
+		t.Helper()
+		if _, err := c.reconcile(ctx, key); err != nil {
+			t.Fatalf("reconcile %s: unexpected error %v", key.resource.Resource, err)
+		}
+	}
+	ready := func(key queueKey) (bool, []string) {
+		t.Helper()
+		obj, err := dynamicClient.Resource(key.resource).Namespace(key.namespace).Get(ctx, key.name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		var status ServiceClaimStatus
+		statusObj, _, _ := unstructured.NestedMap(obj.Object, "status")
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(statusObj, &status); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return isReady(status.Conditions), obj.GetFinalizers()
+	}
+
+	// The claim waits for the service to be ready.
+	reconcile(claimKey)
+	if isReady, _ := ready(claimKey); isReady {
+		t.Fatalf("expected the claim not to be ready before the service")
+	}
+
+	reconcile(msKey)
+	if _, ok := client.instances["instance"]; !ok {
+		t.Fatalf("expected the instance to be provisioned")
+	}
+	reconcile(msKey)
+	if isReady, finalizers := ready(msKey); !isReady || len(finalizers) != 1 {
+		t.Fatalf("expected the service to be ready with a finalizer, actual ready %v, finalizers %v", isReady, finalizers)
+	}
+
+	reconcile(claimKey)
+	reconcile(claimKey)
+	if isReady, _ := ready(claimKey); !isReady {
+		t.Fatalf("expected the claim to be ready")
+	}
+	secret, err := coreClient.CoreV1().Secrets("apps").Get(ctx, "app", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("expected the claim secret, actual error %v", err)
+	}
+	if string(secret.Data["host"]) != "db" || string(secret.Data["port"]) != "5432" {
+		t.Errorf("unexpected secret data %v", secret.Data)
+	}
+	if secret.Type != corev1.SecretTypeOpaque || len(secret.OwnerReferences) != 1 {
+		t.Errorf("expected an opaque secret owned by the claim, actual %+v", secret.ObjectMeta)
+	}
+
+	// Deleting the resources releases the binding and the instance.
+	for _, key := range []queueKey{claimKey, msKey} {
+		obj, _ := dynamicClient.Resource(key.resource).Namespace("apps").Get(ctx, key.name, metav1.GetOptions{})
+		now := metav1.Now()
+		obj.SetDeletionTimestamp(&now)
+		if _, err := dynamicClient.Resource(key.resource).Namespace("apps").Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		reconcile(key)
+	}
+	if len(client.bindings) != 0 {
+		t.Errorf("expected the binding to be removed, actual %v", client.bindings)
+	}
+	reconcile(msKey)
+	if len(client.instances) != 0 {
+		t.Errorf("expected the instance to be deprovisioned, actual %v", client.instances)
+	}
+	if _, finalizers := ready(msKey); len(finalizers) != 0 {
+		t.Errorf("expected the finalizer to be removed, actual %v", finalizers)
+	}
+	if _, err := coreClient.CoreV1().Secrets("apps").Get(ctx, "app", metav1.GetOptions{}); err == nil {
+		t.Errorf("expected the claim secret to be deleted")
+	}
+}
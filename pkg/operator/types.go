@@ -0,0 +1,143 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package operator
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// Group is the API group of the Minibroker custom resources.
+	Group = "minibroker.io"
+	// Version is the API version of the Minibroker custom resources.
+	Version = "v1alpha1"
+	// Finalizer is set on the custom resources so the instances and bindings are released before
+	// the resources are deleted.
+	Finalizer = "minibroker.io/finalizer"
+)
+
+var (
+	// ManagedServiceResource is the resource of the ManagedService kind.
+	ManagedServiceResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "managedservices"}
+	// ServiceClaimResource is the resource of the ServiceClaim kind.
+	ServiceClaimResource = schema.GroupVersionResource{Group: Group, Version: Version, Resource: "serviceclaims"}
+)
+
+// ManagedService is a service instance provisioned by Minibroker in the namespace of the resource.
+type ManagedService struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ManagedServiceSpec   `json:"spec"`
+	Status ManagedServiceStatus `json:"status,omitempty"`
+}
+
+// ManagedServiceSpec is the desired state of a ManagedService.
+type ManagedServiceSpec struct {
+	// Service is the ID of the catalog service, e.g. postgresql.
+	Service string `json:"service"`
+	// Plan is the ID of the catalog plan, e.g. postgresql-11-7-0.
+	Plan string `json:"plan"`
+	// Parameters are the provisioning parameters passed to the chart.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// Cluster is the name of the target cluster. Defaults to the cluster configured for the plan, if
+	// any, or the cluster Minibroker runs in.
+	Cluster string `json:"cluster,omitempty"`
+}
+
+// ManagedServiceStatus is the observed state of a ManagedService.
+type ManagedServiceStatus struct {
+	// InstanceID is the ID of the service instance, set once provisioning started.
+	InstanceID string `json:"instanceID,omitempty"`
+	// Operation is the key of the last asynchronous operation on the instance.
+	Operation  string      `json:"operation,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// ServiceClaim is a binding to a ManagedService in the same namespace, whose credentials are
+// written to a Secret.
+type ServiceClaim struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ServiceClaimSpec   `json:"spec"`
+	Status ServiceClaimStatus `json:"status,omitempty"`
+}
+
+// ServiceClaimSpec is the desired state of a ServiceClaim.
+type ServiceClaimSpec struct {
+	// ServiceName is the name of the ManagedService to bind.
+	ServiceName string `json:"serviceName"`
+	// Parameters are the binding parameters, e.g. the role of the binding user.
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	// SecretName is the name of the Secret holding the credentials. Defaults to the claim name.
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// ServiceClaimStatus is the observed state of a ServiceClaim.
+type ServiceClaimStatus struct {
+	// InstanceID is the ID of the bound service instance.
+	InstanceID string `json:"instanceID,omitempty"`
+	// BindingID is the ID of the binding, set once binding started.
+	BindingID string `json:"bindingID,omitempty"`
+	// SecretName is the name of the Secret holding the credentials, set once written.
+	SecretName string      `json:"secretName,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// ConditionReady is the condition type reporting whether a resource is ready for use.
+const ConditionReady = "Ready"
+
+// Condition is a status condition of the Minibroker custom resources.
+type Condition struct {
+	Type               string                 `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	Reason             string                 `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+}
+
+// setCondition sets a condition in the list, only updating the transition time when the status
+// changes.
+func setCondition(conditions []Condition, condition Condition) []Condition {
+	for i, current := range conditions {
+		if current.Type != condition.Type {
+			continue
+		}
+		if current.Status == condition.Status {
+			condition.LastTransitionTime = current.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	condition.LastTransitionTime = metav1.Now()
+	return append(conditions, condition)
+}
+
+// isReady returns whether the Ready condition is true.
+func isReady(conditions []Condition) bool {
+	for _, condition := range conditions {
+		if condition.Type == ConditionReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("span %q not found in %d spans", name, len(spans))
+	return tracetest.SpanStub{}
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	shutdown, err := Setup(context.Background(), Options{})
+	if err != nil {
+		t.Fatalf("Setup: unexpected error %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown: unexpected error %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	exporter := SetupInMemory()
+
+	var requestSpan trace.SpanContext
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	const parent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	req := httptest.NewRequest(http.MethodPut, "/v2/service_instances/db", nil)
+	req.Header.Set("traceparent", parent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	span := spanNamed(t, exporter.GetSpans(), "HTTP PUT")
+	if !span.SpanContext.Equal(requestSpan) {
+		t.Errorf("the request context does not carry the server span")
+	}
+	if span.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the span to continue the caller trace, got parent %v", span.Parent)
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("expected a server span, got %v", span.SpanKind)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("expected an error status, got %v", span.Status)
+	}
+
+	exporter.Reset()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Errorf("expected the probes not to be traced, got %d spans", len(spans))
+	}
+}
+
+func TestStartAsync(t *testing.T) {
+	exporter := SetupInMemory()
+
+	ctx, requestSpan := Start(context.Background(), "request")
+	requestCtx, cancel := context.WithCancel(ctx)
+	asyncCtx, asyncSpan := StartAsync(requestCtx, "async")
+	cancel()
+	requestSpan.End()
+	if asyncCtx.Err() != nil {
+		t.Errorf("expected the async context to outlive the request")
+	}
+	End(asyncSpan, errors.New("failed"))
+
+	spans := exporter.GetSpans()
+	request := spanNamed(t, spans, "request")
+	async := spanNamed(t, spans, "async")
+	if async.Parent.IsValid() {
+		t.Errorf("expected the async span to be a root span, got parent %v", async.Parent)
+	}
+	if len(async.Links) != 1 || async.Links[0].SpanContext.SpanID() != request.SpanContext.SpanID() {
+		t.Errorf("expected the async span to link the request span, got %+v", async.Links)
+	}
+	if async.Status.Code != codes.Error || len(async.Events) != 1 {
+		t.Errorf("expected the error to be recorded, got status %v and events %v", async.Status, async.Events)
+	}
+}
+
+func TestWrapTransport(t *testing.T) {
+	exporter := SetupInMemory()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx, parent := Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/namespaces/default/configmaps/db", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: WrapTransport(http.DefaultTransport)}).Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	span := spanNamed(t, exporter.GetSpans(), "kubernetes get")
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected the client span to be a child of the parent span")
+	}
+	if traceparent == "" || traceparent[36:52] != span.SpanContext.SpanID().String() {
+		t.Errorf("expected the client span to be propagated, got traceparent %q", traceparent)
+	}
+	if span.Status.Code != codes.Error {
+		t.Errorf("expected an error status for a 404, got %v", span.Status)
+	}
+}